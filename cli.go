@@ -0,0 +1,724 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tttfrfr2/ECHONETTester/echonetlite"
+	"github.com/tttfrfr2/ECHONETTester/util"
+)
+
+// Exit codes of subcommands
+const (
+	exitPass  = 0 // no finding of the severity designated by -fail-on or more serious
+	exitFail  = 1 // findings, negative replies or nodes not found
+	exitError = 2 // invalid arguments or the tool failed
+)
+
+// Flags added by addOptions besides -config and -release
+const (
+	withTarget = 1 << iota // -target
+	withOut                // -out
+	withSeed               // -seed
+	withFailOn             // -fail-on
+)
+
+// options are flags common to subcommands testing nodes. Flags not added are nil
+type options struct {
+	config  *string
+	targets *string
+	release *string
+	out     *string
+	seed    *int64
+	failOn  *string
+
+	conf     *util.Config         // config read by start
+	prompter echonetlite.Prompter // user of interactive flows. If nil, stdin and stdout
+	stop     func()               // stop handling Ctrl-C
+}
+
+// addOptions add -config, -release and the flags of with to fs
+func addOptions(fs *flag.FlagSet, with int) *options {
+	o := &options{
+		config:  addConfig(fs),
+		release: fs.String("release", "", "Appendix release of target nodes like M. If empty, release in config file or detected from Version information (0x82)"),
+	}
+	if with&withTarget != 0 {
+		o.targets = fs.String("target", "", "IP addresses of target nodes separated by ','. If empty, ip and nodes in config file")
+	}
+	if with&withOut != 0 {
+		o.out = addOut(fs)
+	}
+	if with&withSeed != 0 {
+		o.seed = fs.Int64("seed", 0, "Seed of random values in fuzzing. Seed recorded in a result reproduces the same cases")
+	}
+	if with&withFailOn != 0 {
+		o.failOn = addFailOn(fs)
+	}
+	return o
+}
+
+func addConfig(fs *flag.FlagSet) *string {
+	return fs.String("config", "config.tml", "Path of config file")
+}
+
+func addOut(fs *flag.FlagSet) *string {
+	return fs.String("out", "", "Directory reports are output. If empty, resultDir in config file or result")
+}
+
+func addFailOn(fs *flag.FlagSet) *string {
+	return fs.String("fail-on", "medium", "Exit status is 1 if there are findings of this severity or more serious (info, low, medium, high)")
+}
+
+// hasTargets return whether target nodes are designated by -target
+func (o *options) hasTargets() bool {
+	return o.targets != nil && *o.targets != ""
+}
+
+// severity return the severity of -fail-on. Without -fail-on, it is medium
+func (o *options) severity() (echonetlite.Severity, bool) {
+	if o.failOn == nil {
+		return echonetlite.SeverityMedium, true
+	}
+	return echonetlite.ParseSeverity(*o.failOn)
+}
+
+// start read config and discover target nodes.
+// If it fails, Auditor is nil and the exit code is returned
+func (o *options) start() (*echonetlite.Auditor, int) {
+	if code := o.load(!o.hasTargets()); code != exitPass {
+		return nil, code
+	}
+	targets, code := o.targetIPs()
+	if targets == nil {
+		return nil, code
+	}
+	return o.discover(targets)
+}
+
+// load check flags and read config into o.conf. If required is false, a missing config file is skipped.
+// Return the exit code if it fails
+func (o *options) load(required bool) int {
+	if _, ok := o.severity(); !ok {
+		fmt.Printf("ECHONET Lite ERROR: invalid severity %s\n", *o.failOn)
+		return exitError
+	}
+	config := &util.Config{}
+	if _, err := os.Stat(*o.config); err == nil || required {
+		config, err = util.LoadConfig(*o.config)
+		if err != nil {
+			fmt.Printf("ECHONET Lite ERROR: %s\n", err)
+			return exitError
+		}
+	}
+	if _, err := echonetlite.ParsePlan(strings.Join(config.EchonetLite.Plan, ",")); err != nil {
+		fmt.Printf("ECHONET Lite ERROR: Invalid config %s: plan: %s\n", *o.config, err)
+		return exitError
+	}
+	o.conf = config
+	return exitPass
+}
+
+// targetIPs return target nodes of -target, or of config if it is empty.
+// If there are none or invalid ones, nil and the exit code are returned
+func (o *options) targetIPs() ([]net.IP, int) {
+	ips := o.conf.EchonetLite.Targets()
+	if o.hasTargets() {
+		ips = strings.Split(*o.targets, ",")
+	}
+	if len(ips) == 0 {
+		fmt.Printf("ECHONET Lite ERROR: no target nodes. Set ip or [[echonetLite.node]] in %s, or -target\n", *o.config)
+		return nil, exitError
+	}
+	var targets []net.IP
+	for _, s := range ips {
+		ip := net.ParseIP(strings.TrimSpace(s))
+		if ip == nil {
+			fmt.Printf("ECHONET Lite ERROR: invalid IP address %s\n", s)
+			return nil, exitError
+		}
+		targets = append(targets, ip)
+	}
+	return targets, exitPass
+}
+
+// resultDir return the directory reports are output
+func (o *options) resultDir() string {
+	if o.out != nil && *o.out != "" {
+		return *o.out
+	}
+	if o.conf != nil && o.conf.EchonetLite.ResultDir != "" {
+		return o.conf.EchonetLite.ResultDir
+	}
+	return "result"
+}
+
+// discover create Auditor by config read by load and discover targets.
+// If no node is found, Auditor is nil and the exit code is returned
+func (o *options) discover(targets []net.IP) (*echonetlite.Auditor, int) {
+	config := o.conf
+	fmt.Println("---Tool Start---")
+
+	var seed int64
+	if o.seed != nil {
+		seed = *o.seed
+	}
+	a := &echonetlite.Auditor{Seed: seed, Release: *o.release, ResultDir: o.resultDir(), Config: config.EchonetLite, Prompter: o.prompter}
+	// the first Ctrl-C stops the test and results so far are output by finish
+	o.stop = a.HandleInterrupt()
+	err := a.NewAuditor(targets)
+	if err != nil {
+		fmt.Printf("ECHONET Lite testing ERROR: %+v\n", err)
+	}
+	if a.Result != nil {
+		a.Result.Config = *config
+	}
+	if len(a.DistNodes) < 1 {
+		o.stop()
+		a.Close()
+		fmt.Printf("There are no ECHONET Lite node\nEXIT\n")
+		return nil, exitFail
+	}
+	return a, exitPass
+}
+
+// finish output reports, close a and return the exit code by findings.
+// If the test was interrupted, the reports have results so far and echonetlite.ExitInterrupted is returned
+func (o *options) finish(a *echonetlite.Auditor) int {
+	o.stop()
+	defer a.Close()
+	err := a.Flush()
+	if err != nil {
+		fmt.Printf("ECHONET Lite ERROR: %+v\n", err)
+		return exitError
+	}
+	if a.Interrupted() {
+		fmt.Printf("Interrupted. Reports have results so far\n")
+		return echonetlite.ExitInterrupted
+	}
+	severity, _ := o.severity()
+	fmt.Printf("Findings: %d (%s or more serious: %d)\n", a.Result.Count(echonetlite.SeverityInfo), severity, a.Result.Count(severity))
+	return a.Result.ExitCode(severity)
+}
+
+// ips return IP addresses of nodes discovered
+func ips(a *echonetlite.Auditor) []net.IP {
+	var retIPs []net.IP
+	for _, node := range a.Result.Nodes {
+		retIPs = append(retIPs, net.ParseIP(node.IP))
+	}
+	return retIPs
+}
+
+// runDiscover discover target nodes and print their instances and property maps
+func runDiscover(args []string) int {
+	fs := flag.NewFlagSet("discover", flag.ContinueOnError)
+	opts := addOptions(fs, withTarget|withOut|withFailOn)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s discover [flags]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	a, code := opts.start()
+	if a == nil {
+		return code
+	}
+	for _, node := range a.Result.Nodes {
+		fmt.Printf("%s (release %s)\n", node.IP, node.Release)
+		for _, inst := range node.Instances {
+			var get, set, inf []string
+			for _, prop := range inst.Props {
+				if prop.ImplementGet {
+					get = append(get, prop.EPC)
+				}
+				if prop.ImplementSet {
+					set = append(set, prop.EPC)
+				}
+				if prop.ImplementInf {
+					inf = append(inf, prop.EPC)
+				}
+			}
+			fmt.Printf("  %s %s\n    Get: %s\n    Set: %s\n    Inf: %s\n", inst.Code, inst.ClassName, strings.Join(get, " "), strings.Join(set, " "), strings.Join(inf, " "))
+		}
+	}
+	return opts.finish(a)
+}
+
+// parseEPCs change EPCs separated by ',' like "80,B0" into bytes
+func parseEPCs(s string) ([]uint8, error) {
+	var retEPCs []uint8
+	for _, field := range strings.Split(s, ",") {
+		epc, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(field), "0x"), 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EPC %s", field)
+		}
+		retEPCs = append(retEPCs, uint8(epc))
+	}
+	return retEPCs, nil
+}
+
+// parseObject change object code like "013001" into [3]uint8
+func parseObject(s string) ([3]uint8, error) {
+	var retEOJ [3]uint8
+	code, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil || len(code) != 3 {
+		return retEOJ, fmt.Errorf("invalid object code %s", s)
+	}
+	copy(retEOJ[:], code)
+	return retEOJ, nil
+}
+
+// printFrame print decoded frame
+func printFrame(frame echonetlite.FrameFormat) {
+	fmt.Printf("TID:%04X SEOJ:%X DEOJ:%X ESV:%02X OPC:%d\n", frame.TID, frame.SEOJ, frame.DEOJ, frame.ESV, frame.OPC)
+	for _, group := range frame.VarGroups {
+		fmt.Printf("  EPC:%02X PDC:%d EDT:%X\n", group.EPC, group.PDC, group.EDT)
+	}
+}
+
+// request send a request to a node and print the reply.
+// Return 1 if the reply is SNA, there is no reply to a request other than SetI or there are findings
+func request(name string, args []string, esvs map[string]uint8, withEDT bool) int {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	opts := addOptions(fs, withTarget|withOut|withFailOn)
+	eoj := fs.String("eoj", "", "Object code like 013001 (required)")
+	epcs := fs.String("epc", "", "EPCs separated by ',' like 80,B0 (required)")
+	var edts *string
+	var esvName *string
+	if withEDT {
+		edts = fs.String("edt", "", "EDTs in HEX separated by ',' in the order of -epc (required)")
+		esvName = fs.String("esv", "setc", "Service, setc or seti")
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s -target IP -eoj EOJ -epc EPC[,EPC...] [flags]\n", os.Args[0], name)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	dstCode, err := parseObject(*eoj)
+	if err != nil {
+		fmt.Printf("ECHONET Lite ERROR: %s\n", err)
+		return exitError
+	}
+	epcList, err := parseEPCs(*epcs)
+	if err != nil {
+		fmt.Printf("ECHONET Lite ERROR: %s\n", err)
+		return exitError
+	}
+	esv := esvs[name]
+	var groups []echonetlite.VarByteGroup
+	if withEDT {
+		var ok bool
+		esv, ok = esvs[*esvName]
+		if !ok {
+			fmt.Printf("ECHONET Lite ERROR: invalid service %s\n", *esvName)
+			return exitError
+		}
+		fields := strings.Split(*edts, ",")
+		if len(fields) != len(epcList) {
+			fmt.Printf("ECHONET Lite ERROR: %d EDTs for %d EPCs\n", len(fields), len(epcList))
+			return exitError
+		}
+		for i, field := range fields {
+			edt, err := hex.DecodeString(strings.TrimSpace(field))
+			if err != nil || len(edt) > 0xFF {
+				fmt.Printf("ECHONET Lite ERROR: invalid EDT %s\n", field)
+				return exitError
+			}
+			groups = append(groups, echonetlite.VarByteGroup{EPC: epcList[i], PDC: uint8(len(edt)), EDT: edt})
+		}
+	} else {
+		for _, epc := range epcList {
+			groups = append(groups, echonetlite.VarByteGroup{EPC: epc})
+		}
+	}
+
+	a, code := opts.start()
+	if a == nil {
+		return code
+	}
+	verdict := exitPass
+	for _, ip := range ips(a) {
+		fmt.Printf("%s\n", ip)
+		recv, err := a.Request(ip, dstCode, esv, groups)
+		if err != nil {
+			fmt.Printf("ECHONET Lite ERROR: %+v\n", err)
+			verdict = exitFail
+			continue
+		}
+		if recv.EHD1 == 0 {
+			fmt.Printf("  no reply\n")
+			// SetI is replied only when it is not accepted
+			if esv != 0x60 {
+				verdict = exitFail
+			}
+			continue
+		}
+		printFrame(recv)
+		if recv.ESV&0xF0 == 0x50 {
+			verdict = exitFail
+		}
+	}
+	if code := opts.finish(a); code != exitPass {
+		return code
+	}
+	return verdict
+}
+
+// runGet send Get to target nodes and print the replies
+func runGet(args []string) int {
+	return request("get", args, map[string]uint8{"get": 0x62}, false)
+}
+
+// runSet send SetC or SetI to target nodes and print the replies
+func runSet(args []string) int {
+	return request("set", args, map[string]uint8{"setc": 0x61, "seti": 0x60}, true)
+}
+
+// runPlan run steps against all target nodes in parallel. flags add flags of the subcommand if not nil.
+// If fuzzing, -seed is added. If steps return nil, plan in config file is run
+func runPlan(name string, usage string, args []string, fuzzing bool, steps func(fs *flag.FlagSet) ([]string, error), flags func(fs *flag.FlagSet)) int {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	with := withTarget | withOut | withFailOn
+	if fuzzing {
+		with |= withSeed
+	}
+	opts := addOptions(fs, with)
+	pace := fs.Duration("pace", 0, "Interval between packets sent per node like 100ms")
+	if flags != nil {
+		flags(fs)
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s [flags]\n", os.Args[0], usage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	planSteps, err := steps(fs)
+	if err != nil {
+		fmt.Printf("ECHONET Lite ERROR: %s\n", err)
+		fs.Usage()
+		return exitError
+	}
+	a, code := opts.start()
+	if a == nil {
+		return code
+	}
+	if planSteps == nil {
+		// validated by start
+		planSteps, _ = echonetlite.ParsePlan(strings.Join(opts.conf.EchonetLite.Plan, ","))
+	}
+	_, err = a.RunPlan(planSteps, nil, *pace, os.Stdout)
+	if err != nil {
+		fmt.Printf("ECHONET Lite ERROR: %+v\n", err)
+		if code := opts.finish(a); code == echonetlite.ExitInterrupted {
+			return code
+		}
+		return exitError
+	}
+	return opts.finish(a)
+}
+
+// runTestPlan run steps of -steps, or plan in config file, against all target nodes in parallel
+func runTestPlan(args []string) int {
+	var names *string
+	return runPlan("plan", "plan [-steps STEP[,STEP...]]", args, true, func(fs *flag.FlagSet) ([]string, error) {
+		if *names == "" {
+			return nil, nil
+		}
+		return echonetlite.ParsePlan(*names)
+	}, func(fs *flag.FlagSet) {
+		names = fs.String("steps", "", fmt.Sprintf("Steps separated by ',' (%s). If empty, plan in config file or all steps", strings.Join(echonetlite.PlanSteps, ", ")))
+	})
+}
+
+// runSweep get all properties in Get property maps of target nodes and check them
+func runSweep(args []string) int {
+	return runPlan("sweep", "sweep", args, false, func(fs *flag.FlagSet) ([]string, error) {
+		return []string{"Sweep"}, nil
+	}, nil)
+}
+
+// runConformance run checks which don't send invalid frames against target nodes
+func runConformance(args []string) int {
+	return runPlan("conformance", "conformance", args, false, func(fs *flag.FlagSet) ([]string, error) {
+		return []string{"Sweep", "Profile Check", "Address Check"}, nil
+	}, nil)
+}
+
+// fuzzStrategies are strategies fuzz subcommand accepts
+var fuzzStrategies = map[string][]string{
+	"opc":      {"OPC Fuzz"},
+	"frame":    {"Frame Fuzz"},
+	"boundary": {"Boundary Fuzz"},
+	"profile":  {"Profile Fuzz"},
+	"all":      {"OPC Fuzz", "Frame Fuzz", "Boundary Fuzz", "Profile Fuzz"},
+}
+
+// runFuzz run a fuzzing strategy against target nodes in parallel
+func runFuzz(args []string) int {
+	strategy := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		strategy = args[0]
+		args = args[1:]
+	}
+	return runPlan("fuzz", "fuzz opc|frame|boundary|profile|all", args, true, func(fs *flag.FlagSet) ([]string, error) {
+		if strategy == "" {
+			strategy = fs.Arg(0)
+		}
+		steps, ok := fuzzStrategies[strategy]
+		if !ok {
+			return nil, fmt.Errorf("unknown strategy %q", strategy)
+		}
+		return steps, nil
+	}, nil)
+}
+
+// runReport output JUnit XML and HTML reports from a result file again and print the count of findings
+func runReport(args []string) int {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	opts := &options{
+		config: addConfig(fs),
+		out:    addOut(fs),
+		failOn: addFailOn(fs),
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s report [-config FILE] [-out DIR] [-fail-on severity] RESULT.json\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitError
+	}
+	if code := opts.load(false); code != exitPass {
+		return code
+	}
+	result, err := echonetlite.LoadResult(fs.Arg(0))
+	if err != nil {
+		fmt.Printf("ECHONET Lite report ERROR: %+v\n", err)
+		return exitError
+	}
+	paths, err := result.WriteReports(opts.resultDir())
+	for _, path := range paths {
+		fmt.Printf("Output %s\n", path)
+	}
+	if err != nil {
+		fmt.Printf("ECHONET Lite report ERROR: %+v\n", err)
+		return exitError
+	}
+	counts := make(map[echonetlite.Severity]int)
+	for _, f := range result.Findings {
+		counts[f.Severity]++
+	}
+	for _, s := range []echonetlite.Severity{echonetlite.SeverityHigh, echonetlite.SeverityMedium, echonetlite.SeverityLow, echonetlite.SeverityInfo} {
+		fmt.Printf("%-6s %d\n", s, counts[s])
+	}
+	// validated by load
+	severity, _ := opts.severity()
+	return result.ExitCode(severity)
+}
+
+// runDiff compare two result files and print the difference.
+// Return 1 if there are new findings whose severity is designated by -fail-on or more serious
+func runDiff(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	failOn := addFailOn(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s diff [-fail-on severity] OLD_RESULT.json NEW_RESULT.json\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	severity, ok := echonetlite.ParseSeverity(*failOn)
+	if fs.NArg() != 2 || !ok {
+		fs.Usage()
+		return exitError
+	}
+	oldResult, err := echonetlite.LoadResult(fs.Arg(0))
+	if err != nil {
+		fmt.Printf("ECHONET Lite diff ERROR: %+v\n", err)
+		return exitError
+	}
+	newResult, err := echonetlite.LoadResult(fs.Arg(1))
+	if err != nil {
+		fmt.Printf("ECHONET Lite diff ERROR: %+v\n", err)
+		return exitError
+	}
+
+	diff := echonetlite.DiffResults(oldResult, newResult)
+	diff.WriteText(os.Stdout)
+	if diff.CountNew(severity) > 0 {
+		return exitFail
+	}
+	return exitPass
+}
+
+// runReplay send cases stored in a result or corpus file to a node again and compare the replies.
+// Return 1 if ESV of some replies differ from the original ones
+func runReplay(args []string) int {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	opts := addOptions(fs, withTarget)
+	strategy := fs.String("strategy", "", "Replay only cases of this strategy, like \"OPC Fuzz\" (result file only)")
+	instance := fs.String("instance", "", "Replay only cases sent to this instance, like 013001 (result file only)")
+	from := fs.Int("from", 1, "Index of the first case replayed")
+	to := fs.Int("to", 0, "Index of the last case replayed. 0 means the last case")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s replay -target IP [-strategy S] [-instance EOJ] [-from N] [-to N] [flags] RESULT.json|CORPUS.hex\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitError
+	}
+	if code := opts.load(!opts.hasTargets()); code != exitPass {
+		return code
+	}
+	targets, code := opts.targetIPs()
+	if targets == nil {
+		return code
+	}
+	if len(targets) != 1 {
+		fmt.Printf("ECHONET Lite replay ERROR: cases are replayed to one node, but there are %d target nodes. Set -target\n", len(targets))
+		return exitError
+	}
+	ip := targets[0]
+
+	var cases []echonetlite.FuzzCase
+	if strings.HasSuffix(fs.Arg(0), ".json") {
+		result, err := echonetlite.LoadResult(fs.Arg(0))
+		if err != nil {
+			fmt.Printf("ECHONET Lite replay ERROR: %+v\n", err)
+			return exitError
+		}
+		for _, c := range result.FuzzCases {
+			if c.Node == ip.String() && (*strategy == "" || c.Strategy == *strategy) && (*instance == "" || c.Instance == *instance) {
+				cases = append(cases, c)
+			}
+		}
+	} else {
+		var err error
+		cases, err = echonetlite.LoadCorpus(fs.Arg(0))
+		if err != nil {
+			fmt.Printf("ECHONET Lite replay ERROR: %+v\n", err)
+			return exitError
+		}
+	}
+	var selected []echonetlite.FuzzCase
+	for _, c := range cases {
+		if c.Index >= *from && (*to == 0 || c.Index <= *to) {
+			selected = append(selected, c)
+		}
+	}
+	if len(selected) == 0 {
+		fmt.Printf("There are no cases to replay\n")
+		return exitError
+	}
+
+	a, code := opts.discover(targets)
+	if a == nil {
+		return code
+	}
+	defer a.Close()
+	defer opts.stop()
+	results, err := a.Replay(ip, selected)
+	echonetlite.WriteReplay(os.Stdout, results)
+	if a.Interrupted() {
+		return echonetlite.ExitInterrupted
+	} else if err != nil {
+		fmt.Printf("ECHONET Lite replay ERROR: %+v\n", err)
+		return exitError
+	}
+	for _, r := range results {
+		if (r.Case.Recv != "" || r.Case.Timeout) && !r.SameESV {
+			return exitFail
+		}
+	}
+	return exitPass
+}
+
+// runResume continue the fuzzing campaigns which were not done from a checkpoint with the seed of the checkpoint.
+// Without argument, the latest checkpoint under the result directory is used
+func runResume(args []string) int {
+	fs := flag.NewFlagSet("resume", flag.ContinueOnError)
+	opts := addOptions(fs, withOut|withFailOn)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s resume [flags] [CHECKPOINT.json]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if code := opts.load(false); code != exitPass {
+		return code
+	}
+	path := fs.Arg(0)
+	if path == "" {
+		dir := opts.resultDir()
+		paths, _ := filepath.Glob(filepath.Join(dir, "*-checkpoint.json"))
+		var latest time.Time
+		for _, p := range paths {
+			info, err := os.Stat(p)
+			if err == nil && info.ModTime().After(latest) {
+				path = p
+				latest = info.ModTime()
+			}
+		}
+		if path == "" {
+			fmt.Printf("There are no checkpoints under %s\n", dir)
+			return exitError
+		}
+	}
+	checkpoint, err := echonetlite.LoadCheckpoint(path)
+	if err != nil {
+		fmt.Printf("ECHONET Lite resume ERROR: %+v\n", err)
+		return exitError
+	}
+	var targets []net.IP
+	seen := make(map[string]bool)
+	for _, c := range checkpoint.Campaigns {
+		if !c.Done && !seen[c.Node] {
+			seen[c.Node] = true
+			targets = append(targets, net.ParseIP(c.Node))
+		}
+	}
+	if targets == nil {
+		fmt.Printf("Nothing to resume, all campaigns of %s are done\n", checkpoint.RunID)
+		return exitPass
+	}
+	fmt.Printf("---Resume %s---\n", checkpoint.RunID)
+	// the remaining cases are generated from the seed of the checkpoint
+	opts.seed = &checkpoint.Seed
+	a, code := opts.discover(targets)
+	if a == nil {
+		return code
+	}
+	err = a.Resume(checkpoint)
+	if err != nil && !a.Interrupted() {
+		fmt.Printf("ECHONET Lite resume ERROR: %+v\n", err)
+		opts.finish(a)
+		return exitError
+	}
+	code = opts.finish(a)
+	if code == echonetlite.ExitInterrupted {
+		fmt.Printf("Resume again to continue\n")
+	}
+	return code
+}
@@ -0,0 +1,223 @@
+package echonetlite
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+// bogusSEOJs are source objects of requests which the device should reply to as they are
+var bogusSEOJs = [][3]uint8{
+	{0x0F, 0x00, 0x01}, // user-defined class group
+	{0x01, 0xFF, 0x01}, // undefined class
+	{0x05, 0xFF, 0x01}, // controller group, undefined class
+	{0x05, 0xFF, 0x00}, // instance code 0x00
+}
+
+// eojMatches return whether the object eoj is addressed by deoj. Instance code 0x00 addresses all instances of the class
+func eojMatches(deoj [3]uint8, eoj [3]uint8) bool {
+	if deoj[2] == 0x00 {
+		return deoj[0] == eoj[0] && deoj[1] == eoj[1]
+	}
+	return deoj == eoj
+}
+
+// collect send payload and receive all replies whose TID is the same until timeout
+func (node *Node) collect(payload FrameFormat, timeout time.Duration) ([]FrameFormat, error) {
+	var retFrames []FrameFormat
+	err := SendEchonet(payload, node.client.conn)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		data, err := node.recvRaw(time.Until(deadline))
+		if err != nil {
+			break
+		}
+		recv, err := parser(data)
+		if err != nil || recv.TID != payload.TID || recv.ESV&0xF0 == 0x60 || recv.ESV == 0x73 {
+			continue
+		}
+		retFrames = append(retFrames, *recv)
+	}
+	// replies missing because of interruption must not be checked
+	return retFrames, node.canceled()
+}
+
+// unknownDEOJs return objects which do not exist in node: unused instance codes of classes node has,
+// an undefined class and objects whose class group is undefined
+func unknownDEOJs(node *Node) [][3]uint8 {
+	exist := make(map[[3]uint8]bool)
+	maxInstance := make(map[[2]uint8]uint8)
+	for _, inst := range node.Instances {
+		exist[inst.ClassCode] = true
+		class := [2]uint8{inst.ClassCode[0], inst.ClassCode[1]}
+		if inst.ClassCode[2] > maxInstance[class] {
+			maxInstance[class] = inst.ClassCode[2]
+		}
+	}
+	var retEOJs [][3]uint8
+	for _, inst := range node.Instances {
+		class := [2]uint8{inst.ClassCode[0], inst.ClassCode[1]}
+		if max, ok := maxInstance[class]; ok && max < 0x7F {
+			retEOJs = append(retEOJs, [3]uint8{class[0], class[1], max + 1})
+			delete(maxInstance, class)
+		}
+	}
+	for _, eoj := range [][3]uint8{{0x01, 0xFF, 0x01}, {0x0F, 0x00, 0x01}, {0x07, 0x00, 0x01}, {0x00, 0x00, 0x00}} {
+		if !exist[eoj] {
+			retEOJs = append(retEOJs, eoj)
+		}
+	}
+	return retEOJs
+}
+
+// AddressCheck send requests with invalid addressing to the node designated by dstIP.
+// Requests to nonexistent objects should be ignored or replied with SNA, every instance of a class should reply
+// to requests whose instance code is 0x00 with its own SEOJ, and requests from any SEOJ should be replied to it
+func (a *Auditor) AddressCheck(dstIP net.IP) error {
+	var node *Node
+	for i := range a.DistNodes {
+		if a.DistNodes[i].ip.Equal(dstIP) {
+			n := a.DistNodes[i]
+			node = &n
+			break
+		}
+	}
+	if node == nil {
+		return xerrors.Errorf("Node %s is not found", dstIP)
+	}
+	node.logger.Info("Start address check")
+	start := time.Now()
+	timeout := node.replyTimeout()
+	get := func(seoj [3]uint8, deoj [3]uint8) FrameFormat {
+		return FrameFormat{
+			EHD1:      0x10,
+			EHD2:      0x81,
+			TID:       node.client.nextTID(),
+			SEOJ:      seoj,
+			DEOJ:      deoj,
+			ESV:       0x62,
+			OPC:       0x01,
+			VarGroups: []VarByteGroup{{EPC: 0x80, PDC: 0x00}},
+		}
+	}
+
+	// nonexistent objects
+	for _, deoj := range unknownDEOJs(node) {
+		sent := get(node.client.SEOJ, deoj)
+		node.logger.Info("sent packet", zap.String("check", "unknown DEOJ"), zap.String("payload", frameHex(&sent)))
+		recvs, err := node.collect(sent, timeout)
+		if err != nil {
+			return xerrors.Errorf("Failed to send request to unknown DEOJ %s: %w", eojString(deoj), err)
+		}
+		node.checkUnknownDEOJ(&sent, recvs)
+	}
+
+	// instance code 0x00 addresses all instances of the class
+	classes := make(map[[2]uint8][][3]uint8)
+	var order [][2]uint8
+	for _, inst := range node.Instances {
+		class := [2]uint8{inst.ClassCode[0], inst.ClassCode[1]}
+		if _, ok := classes[class]; !ok {
+			order = append(order, class)
+		}
+		classes[class] = append(classes[class], inst.ClassCode)
+	}
+	for _, class := range order {
+		sent := get(node.client.SEOJ, [3]uint8{class[0], class[1], 0x00})
+		node.logger.Info("sent packet", zap.String("check", "instance code 0x00"), zap.String("payload", frameHex(&sent)))
+		recvs, err := node.collect(sent, timeout)
+		if err != nil {
+			return xerrors.Errorf("Failed to send request to %s: %w", eojString(sent.DEOJ), err)
+		}
+		node.checkBroadcastReplies(&sent, classes[class], recvs)
+	}
+
+	// bogus source objects
+	deoj := [3]uint8{0x0E, 0xF0, 0x01}
+	for _, inst := range node.Instances {
+		if !isNodeProfile(inst.ClassCode) {
+			deoj = inst.ClassCode
+			break
+		}
+	}
+	for _, seoj := range bogusSEOJs {
+		sent := get(seoj, deoj)
+		node.logger.Info("sent packet", zap.String("check", "bogus SEOJ"), zap.String("payload", frameHex(&sent)))
+		recvs, err := node.collect(sent, timeout)
+		if err != nil {
+			return xerrors.Errorf("Failed to send request from %s: %w", eojString(seoj), err)
+		}
+		node.checkBogusSEOJ(&sent, recvs)
+	}
+
+	if node.result != nil {
+		node.result.AddTiming("Address Check", node.ip.String(), start)
+	}
+	node.logger.Info("Finished address check")
+	return nil
+}
+
+// checkUnknownDEOJ check replies to a request to a nonexistent object. It should be ignored or replied with SNA
+func (node *Node) checkUnknownDEOJ(sent *FrameFormat, recvs []FrameFormat) {
+	var recv *FrameFormat
+	if len(recvs) > 0 {
+		recv = &recvs[0]
+	}
+	defer node.endCheck(node.beginCheck("AddressCheck", sent.DEOJ, frameHex(sent), frameHex(recv)))
+	for i := range recvs {
+		if recvs[i].ESV&0xF0 == 0x70 {
+			node.report(Finding{RuleID: "ADDR-UNKNOWN-DEOJ", Message: fmt.Sprintf("Device replied ESV %02X from %s to request to nonexistent object %s", recvs[i].ESV, eojString(recvs[i].SEOJ), eojString(sent.DEOJ)), Recv: frameHex(&recvs[i])})
+		}
+	}
+}
+
+// checkBroadcastReplies check replies to a request whose instance code is 0x00.
+// Each instance of the class in instances should reply once with its own SEOJ
+func (node *Node) checkBroadcastReplies(sent *FrameFormat, instances [][3]uint8, recvs []FrameFormat) {
+	var recv *FrameFormat
+	if len(recvs) > 0 {
+		recv = &recvs[0]
+	}
+	defer node.endCheck(node.beginCheck("AddressCheck", sent.DEOJ, frameHex(sent), frameHex(recv)))
+	replied := make(map[[3]uint8]int)
+	for i := range recvs {
+		seoj := recvs[i].SEOJ
+		known := false
+		for _, eoj := range instances {
+			if seoj == eoj {
+				known = true
+			}
+		}
+		if !known {
+			node.report(Finding{RuleID: "ADDR-BROADCAST-SEOJ", Message: fmt.Sprintf("Reply to request to %s has SEOJ %s, not an instance of the class", eojString(sent.DEOJ), eojString(seoj)), Recv: frameHex(&recvs[i])})
+			continue
+		}
+		replied[seoj]++
+		if replied[seoj] == 2 {
+			node.report(Finding{RuleID: "ADDR-BROADCAST-DUPLICATE", Instance: eojString(seoj), Message: fmt.Sprintf("Instance %s replied more than once to request to %s", eojString(seoj), eojString(sent.DEOJ)), Recv: frameHex(&recvs[i])})
+		}
+	}
+	for _, eoj := range instances {
+		if replied[eoj] == 0 {
+			node.report(Finding{RuleID: "ADDR-BROADCAST-MISSING", Instance: eojString(eoj), Message: fmt.Sprintf("Instance %s didn't reply to request to %s", eojString(eoj), eojString(sent.DEOJ))})
+		}
+	}
+}
+
+// checkBogusSEOJ check replies to a request from a source object the device doesn't know.
+// The reply should be addressed to the source object as it is
+func (node *Node) checkBogusSEOJ(sent *FrameFormat, recvs []FrameFormat) {
+	if len(recvs) == 0 {
+		defer node.endCheck(node.beginCheck("AddressCheck", sent.DEOJ, frameHex(sent), ""))
+		node.report(Finding{RuleID: "ADDR-SEOJ-IGNORED", Message: fmt.Sprintf("No reply to request from SEOJ %s", eojString(sent.SEOJ))})
+		return
+	}
+	defer node.endCheck(node.beginCheck("AddressCheck", sent.DEOJ, frameHex(sent), frameHex(&recvs[0])))
+	node.CheckFlowValidation(*sent, recvs[0])
+}
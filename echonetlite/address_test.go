@@ -0,0 +1,48 @@
+package echonetlite
+
+import (
+	"testing"
+)
+
+func Test_unknownDEOJs(t *testing.T) {
+	node := newTestNode()
+	node.Instances = []Instance{
+		{ClassCode: [3]uint8{0x0E, 0xF0, 0x01}},
+		{ClassCode: [3]uint8{0x01, 0x30, 0x01}},
+		{ClassCode: [3]uint8{0x01, 0x30, 0x02}},
+	}
+	got := unknownDEOJs(node)
+	want := [][3]uint8{{0x0E, 0xF0, 0x02}, {0x01, 0x30, 0x03}, {0x01, 0xFF, 0x01}, {0x0F, 0x00, 0x01}, {0x07, 0x00, 0x01}, {0x00, 0x00, 0x00}}
+	if len(got) != len(want) {
+		t.Fatalf("unknownDEOJs => %X, want %X", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("unknownDEOJs[%d] => %X, want %X", i, got[i], want[i])
+		}
+	}
+}
+
+func Test_checkBroadcastReplies(t *testing.T) {
+	node := newTestNode()
+	sent := FrameFormat{EHD1: 0x10, EHD2: 0x81, TID: 0x0001, SEOJ: [3]uint8{0x0E, 0xF0, 0x01}, DEOJ: [3]uint8{0x01, 0x30, 0x00}, ESV: 0x62, OPC: 0x01, VarGroups: []VarByteGroup{{EPC: 0x80}}}
+	reply := func(seoj [3]uint8) FrameFormat {
+		return FrameFormat{EHD1: 0x10, EHD2: 0x81, TID: 0x0001, SEOJ: seoj, DEOJ: sent.SEOJ, ESV: 0x72, OPC: 0x01, VarGroups: []VarByteGroup{{EPC: 0x80, PDC: 0x01, EDT: []uint8{0x30}}}}
+	}
+	instances := [][3]uint8{{0x01, 0x30, 0x01}, {0x01, 0x30, 0x02}, {0x01, 0x30, 0x03}}
+	recvs := []FrameFormat{reply(instances[0]), reply(instances[0]), reply([3]uint8{0x01, 0x30, 0x00}), reply(instances[2])}
+	node.checkBroadcastReplies(&sent, instances, recvs)
+
+	rules := make(map[string]int)
+	for _, f := range node.result.Findings {
+		rules[f.RuleID]++
+	}
+	for rule, n := range map[string]int{"ADDR-BROADCAST-DUPLICATE": 1, "ADDR-BROADCAST-SEOJ": 1, "ADDR-BROADCAST-MISSING": 1} {
+		if rules[rule] != n {
+			t.Errorf("%s => %d, want %d (%v)", rule, rules[rule], n, rules)
+		}
+	}
+	if !eojMatches(sent.DEOJ, instances[1]) || eojMatches(instances[0], instances[1]) {
+		t.Errorf("eojMatches with instance code 0x00 is wrong")
+	}
+}
@@ -0,0 +1,502 @@
+package echonetlite
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+// boundaryCase is an invalid EDT generated from the data type of property
+type boundaryCase struct {
+	name string
+	edt  []uint8
+}
+
+// encodeInt change v into big endian bytes whose length is size.
+// Negative v is two's complement
+func encodeInt(v int64, size int) []uint8 {
+	retNum := make([]uint8, size)
+	for i := size - 1; i >= 0; i-- {
+		retNum[i] = uint8(v & 0xFF)
+		v >>= 8
+	}
+	return retNum
+}
+
+// representable return whether v can be encoded in size bytes
+func representable(v int64, size int, signed bool) bool {
+	if size >= 8 {
+		return true
+	}
+	bits := uint(size * 8)
+	if signed {
+		return v >= -(1<<(bits-1)) && v <= (1<<(bits-1))-1
+	}
+	return v >= 0 && v <= (1<<bits)-1
+}
+
+// wrongSizes return EDTs whose length is different from size
+func wrongSizes(size int) []boundaryCase {
+	retCases := []boundaryCase{{fmt.Sprintf("size %d (want %d)", size+1, size), make([]uint8, size+1)}}
+	if size > 1 {
+		retCases = append(retCases, boundaryCase{fmt.Sprintf("size %d (want %d)", size-1, size), make([]uint8, size-1)})
+	}
+	return retCases
+}
+
+// notInEnum return values near enum which are not members of enum
+func notInEnum(enum []int64, size int, signed bool) []int64 {
+	if len(enum) == 0 {
+		return nil
+	}
+	member := make(map[int64]bool)
+	min, max := enum[0], enum[0]
+	for _, e := range enum {
+		member[e] = true
+		if e < min {
+			min = e
+		}
+		if e > max {
+			max = e
+		}
+	}
+	candidates := []int64{min - 1, max + 1, 0}
+	// a gap between members
+	for v := min + 1; v < max; v++ {
+		if !member[v] {
+			candidates = append(candidates, v)
+			break
+		}
+	}
+	if signed {
+		candidates = append(candidates, -1)
+	} else if size < 8 {
+		candidates = append(candidates, (1<<uint(size*8))-1)
+	}
+	var retValues []int64
+	seen := make(map[int64]bool)
+	for _, v := range candidates {
+		if member[v] || seen[v] || !representable(v, size, signed) {
+			continue
+		}
+		seen[v] = true
+		retValues = append(retValues, v)
+	}
+	return retValues
+}
+
+// boundaryValues generate invalid EDTs of the data type.
+// min-1, max+1, invalid enum members, wrong sizes and invalid dates
+// r generates valid values of the other items or elements
+func boundaryValues(data interface{}, r *rand.Rand) ([]boundaryCase, error) {
+	var retCases []boundaryCase
+
+	if value, ok := data.([]interface{}); ok { // data is []interface
+		for _, d := range value {
+			cases, err := boundaryValues(d, r)
+			if err != nil {
+				return nil, err
+			}
+			retCases = append(retCases, cases...)
+		}
+		return retCases, nil
+
+	} else if value, ok := data.(Number); ok { // data is Number struct
+		sizeU, err := getDataSize(value)
+		if err != nil {
+			return nil, xerrors.Errorf("Invalid format of Number %s: %w", value.format, err)
+		}
+		size := int(sizeU)
+		signed := !strings.HasPrefix(value.format, "uint")
+		if value.enum != nil {
+			for _, v := range notInEnum(value.enum, size, signed) {
+				retCases = append(retCases, boundaryCase{fmt.Sprintf("not in enum %d", v), encodeInt(v, size)})
+			}
+		} else {
+			if representable(value.minimum-1, size, signed) {
+				retCases = append(retCases, boundaryCase{fmt.Sprintf("minimum-1 (%d)", value.minimum-1), encodeInt(value.minimum-1, size)})
+			}
+			if representable(value.maximum+1, size, signed) {
+				retCases = append(retCases, boundaryCase{fmt.Sprintf("maximum+1 (%d)", value.maximum+1), encodeInt(value.maximum+1, size)})
+			}
+		}
+		return append(retCases, wrongSizes(size)...), nil
+
+	} else if value, ok := data.(State); ok { // data is State struct
+		size := int(value.size)
+		if size == 0 {
+			size = 1
+		}
+		var enum []int64
+		for _, e := range value.enum {
+			enum = append(enum, e.edt)
+		}
+		for _, v := range notInEnum(enum, size, false) {
+			retCases = append(retCases, boundaryCase{fmt.Sprintf("invalid state 0x%X", v), encodeInt(v, size)})
+		}
+		return append(retCases, wrongSizes(size)...), nil
+
+	} else if value, ok := data.(Level); ok { // data is Level struct
+		size := (len(value.base) - 2) / 2
+		if size < 1 {
+			return nil, xerrors.Errorf("Invalid size of Level")
+		}
+		base, err := strconv.ParseInt(value.base, 0, 64)
+		if err != nil {
+			return nil, xerrors.Errorf("Invalid format of number: %w", err)
+		}
+		if representable(base-1, size, false) {
+			retCases = append(retCases, boundaryCase{fmt.Sprintf("base-1 (0x%X)", base-1), encodeInt(base-1, size)})
+		}
+		over := base + int64(value.maximum) + 1
+		if representable(over, size, false) {
+			retCases = append(retCases, boundaryCase{fmt.Sprintf("maximum+1 (0x%X)", over), encodeInt(over, size)})
+		}
+		return append(retCases, wrongSizes(size)...), nil
+
+	} else if value, ok := data.(Raw); ok { // data is Raw struct
+		if value.minSize > 1 {
+			retCases = append(retCases, boundaryCase{fmt.Sprintf("size %d (minimum %d)", value.minSize-1, value.minSize), make([]uint8, value.minSize-1)})
+		}
+		if value.maxSize+1 <= 0xFF {
+			retCases = append(retCases, boundaryCase{fmt.Sprintf("size %d (maximum %d)", value.maxSize+1, value.maxSize), make([]uint8, value.maxSize+1)})
+		}
+		return retCases, nil
+
+	} else if value, ok := data.(Bitmap); ok { // data is Bitmap struct
+		size := int(value.size)
+		mask, _ := genBitmask(value)
+		all := uint64(1)<<uint(size*8) - 1
+		if invalid := all &^ mask; size < 8 && invalid != 0 {
+			retCases = append(retCases, boundaryCase{fmt.Sprintf("undefined bits 0x%X", invalid), encodeInt(int64(invalid), size)})
+		}
+		return append(retCases, wrongSizes(size)...), nil
+
+	} else if value, ok := data.(NumericValues); ok { // data is NumericValues struct
+		size := int(value.size)
+		if size == 0 {
+			size = 1
+		}
+		var enum []int64
+		for _, e := range value.enum {
+			enum = append(enum, e.edt)
+		}
+		for _, v := range notInEnum(enum, size, false) {
+			retCases = append(retCases, boundaryCase{fmt.Sprintf("undefined numeric value 0x%X", v), encodeInt(v, size)})
+		}
+		return append(retCases, wrongSizes(size)...), nil
+
+	} else if value, ok := data.(DateTime); ok { // data is DateTime struct
+		// year 2020 in big endian
+		year := []uint8{0x07, 0xE4}
+		invalidDates := []boundaryCase{
+			{"month 13", []uint8{13, 1}},
+			{"month 0", []uint8{0, 1}},
+			{"day 32", []uint8{1, 32}},
+			{"day 0", []uint8{1, 0}},
+			{"February 30", []uint8{2, 30}},
+		}
+		invalidTimes := []boundaryCase{
+			{"hour 24", []uint8{24, 0, 0}},
+			{"minute 60", []uint8{0, 60, 0}},
+			{"second 60", []uint8{0, 0, 60}},
+		}
+		switch value.size {
+		case 2:
+			retCases = append(retCases, invalidDates...)
+		case 3:
+			retCases = append(retCases, invalidTimes...)
+		case 4, 6, 7:
+			for _, d := range invalidDates {
+				edt := append(append([]uint8(nil), year...), d.edt...)
+				edt = append(edt, make([]uint8, value.size-4)...)
+				retCases = append(retCases, boundaryCase{d.name, edt})
+			}
+			if value.size > 4 {
+				for _, t := range invalidTimes[:value.size-4] {
+					edt := append(append([]uint8(nil), year...), 1, 1)
+					edt = append(edt, t.edt[:value.size-4]...)
+					retCases = append(retCases, boundaryCase{t.name, edt})
+				}
+			}
+		default:
+			return nil, xerrors.Errorf("Invalid data of DateTime")
+		}
+		return append(retCases, wrongSizes(int(value.size))...), nil
+
+	} else if value, ok := data.(Array); ok { // data is Array struct
+		items := func(n int64) ([]uint8, error) {
+			var edt []uint8
+			for i := int64(0); i < n; i++ {
+				item, err := RandProp(value.data, r)
+				if err != nil {
+					return nil, err
+				}
+				edt = append(edt, item...)
+			}
+			return edt, nil
+		}
+		if value.minItems > 0 {
+			edt, err := items(value.minItems - 1)
+			if err != nil {
+				return nil, xerrors.Errorf("Failed to generate items of Array: %w", err)
+			}
+			retCases = append(retCases, boundaryCase{fmt.Sprintf("%d items (minimum %d)", value.minItems-1, value.minItems), edt})
+		}
+		edt, err := items(value.maxItems + 1)
+		if err != nil {
+			return nil, xerrors.Errorf("Failed to generate items of Array: %w", err)
+		}
+		if len(edt) < 0xFF {
+			retCases = append(retCases, boundaryCase{fmt.Sprintf("%d items (maximum %d)", value.maxItems+1, value.maxItems), edt})
+		}
+		edt, err = items(value.minItems)
+		if err != nil {
+			return nil, xerrors.Errorf("Failed to generate items of Array: %w", err)
+		}
+		retCases = append(retCases, boundaryCase{"partial item", append(edt, 0x00)})
+		return retCases, nil
+
+	} else if value, ok := data.(Object); ok { // data is Object struct
+		valid := make([][]uint8, len(value.element))
+		for i, el := range value.element {
+			edt, err := RandProp(el.data, r)
+			if err != nil {
+				return nil, xerrors.Errorf("Failed to generate element %s of Object: %w", el.name, err)
+			}
+			valid[i] = edt
+		}
+		for i, el := range value.element {
+			if len(el.data) == 0 {
+				continue
+			}
+			cases, err := boundaryValues(el.data[0], r)
+			if err != nil {
+				return nil, xerrors.Errorf("Failed to generate element %s of Object: %w", el.name, err)
+			}
+			for _, c := range cases {
+				var edt []uint8
+				for j := range value.element {
+					if i == j {
+						edt = append(edt, c.edt...)
+					} else {
+						edt = append(edt, valid[j]...)
+					}
+				}
+				retCases = append(retCases, boundaryCase{el.name + ": " + c.name, edt})
+			}
+		}
+		whole := bytes.Join(valid, nil)
+		if len(whole) > 0 {
+			retCases = append(retCases, boundaryCase{"object lacks last byte", whole[:len(whole)-1]})
+		}
+		return retCases, nil
+	}
+	return nil, xerrors.Errorf("Invalid data")
+}
+
+// propertyBoundaryValues generate invalid EDTs of the property.
+// If the property has multiple types of data, EDTs valid as the other types are excluded
+func propertyBoundaryValues(prop Property, r *rand.Rand) ([]boundaryCase, error) {
+	var retCases []boundaryCase
+	seen := make(map[string]bool)
+	for i, data := range prop.Data {
+		cases, err := boundaryValues(data, r)
+		if err != nil {
+			return nil, xerrors.Errorf("Failed to generate boundary values of EPC:0x%02X: %w", prop.EPC, err)
+		}
+		for _, c := range cases {
+			key := hex.EncodeToString(c.edt)
+			if seen[key] {
+				continue
+			}
+			validAsOther := false
+			for j, other := range prop.Data {
+				if i == j {
+					continue
+				}
+				if rslt, err := elementCorrectRange(other, c.edt); err == nil && rslt {
+					validAsOther = true
+					break
+				}
+			}
+			if validAsOther {
+				continue
+			}
+			seen[key] = true
+			retCases = append(retCases, c)
+		}
+	}
+	return retCases, nil
+}
+
+// BoundaryFuzz send out-of-range EDTs generated from class.json to Set properties of the instance
+// designated by dstIP and dstCode with SetC.
+// The device should reject them with SetC_SNA (0x51) and the property value should be unchanged
+func (a *Auditor) BoundaryFuzz(dstIP net.IP, dstCode [3]uint8) error {
+	a.printf("---Start Boundary fuzzy---\n")
+	node, instIndex, err := a.searchInstane(dstIP, dstCode)
+	if err != nil {
+		a.logger.Error("Invalid Class Code", zap.String("IPaddr", dstIP.String()), zap.String("CLASSCODE", eojString(dstCode)))
+		return xerrors.Errorf("Invalid Class Code")
+	}
+	inst := node.Instances[instIndex]
+	node.logger.Info("Start Boundary fuzzy", zap.String("instance", inst.ClassName))
+
+	strategy := "Boundary Fuzz"
+	campaign := a.beginCampaign(strategy, dstIP.String(), eojString(dstCode), a.campaignSeed(strategy, dstIP.String(), eojString(dstCode)))
+	stats := campaign.statsOf(strategy)
+	if a.Result != nil {
+		defer func() {
+			stats.Duration = time.Since(stats.Start)
+			a.Result.AddFuzz(stats)
+			a.Result.AddTiming(stats.Strategy, stats.Node, stats.Start)
+		}()
+	}
+	r := rand.New(rand.NewSource(stats.Seed))
+	timeout := node.replyTimeout()
+	live := newLiveness(&node, a.LivenessEvery, a.LivenessRecovery)
+	minimized := make(minimizedCases)
+
+	index := 0 // cases generated
+	for _, prop := range inst.Props {
+		if !prop.ImplementSet || len(prop.Data) == 0 {
+			continue
+		}
+		cases, err := propertyBoundaryValues(prop, r)
+		if err != nil {
+			node.logger.Error("Generate boundary values Failed", zap.String("EPC", fmt.Sprintf("0x%02X", prop.EPC)), zap.String("message", err.Error()))
+			continue
+		}
+		if index+len(cases) <= campaign.Next {
+			// sent before the checkpoint resumed
+			index += len(cases)
+			continue
+		}
+		get := FrameFormat{
+			EHD1:      0x10,
+			EHD2:      0x81,
+			SEOJ:      node.client.SEOJ,
+			DEOJ:      dstCode,
+			ESV:       0x62,
+			OPC:       0x01,
+			VarGroups: []VarByteGroup{{EPC: prop.EPC, PDC: 0x00}},
+		}
+		var before []uint8
+		if prop.ImplementGet {
+			get.TID = node.client.nextTID()
+			recv, err := node.exchange(get, timeout)
+			if err == nil && recv.ESV == 0x72 && len(recv.VarGroups) > 0 {
+				before = recv.VarGroups[0].EDT
+			}
+		}
+
+		for _, c := range cases {
+			index++
+			if index <= campaign.Next {
+				continue
+			}
+			if err := node.canceled(); err != nil {
+				return xerrors.Errorf("Boundary fuzzy stopped: %w", err)
+			}
+			caseFindings := a.findingCount(&node)
+			setC := FrameFormat{
+				EHD1:      0x10,
+				EHD2:      0x81,
+				TID:       node.client.nextTID(),
+				SEOJ:      node.client.SEOJ,
+				DEOJ:      dstCode,
+				ESV:       0x61,
+				OPC:       0x01,
+				VarGroups: []VarByteGroup{{EPC: prop.EPC, PDC: uint8(len(c.edt)), EDT: c.edt}},
+			}
+			node.logger.Info("sent packet", zap.String("EPC", fmt.Sprintf("0x%02X", prop.EPC)), zap.String("case", c.name), zap.String("payload", frameHex(&setC)))
+			fuzzCase := FuzzCase{
+				Strategy: stats.Strategy,
+				Node:     stats.Node,
+				Instance: stats.Instance,
+				Index:    stats.Sent + 1,
+				Sent:     frameHex(&setC),
+				SentAt:   time.Now(),
+			}
+			stats.Sent++
+			recv, err := node.exchange(setC, timeout)
+			fuzzCase.RTT = time.Since(fuzzCase.SentAt)
+			truncated := err != nil && isTruncated(err)
+			if err != nil && !xerrors.Is(err, ErrTimeout) && !truncated {
+				node.logger.Error("Receive packet Failed", zap.String("message", err.Error()))
+				return xerrors.Errorf("Failed to recieve ECHONET Lite packet at Boundary fuzzy: %w", err)
+			}
+			fuzzCase.Timeout = err != nil && !truncated
+			fuzzCase.Recv = frameHex(&recv)
+			a.recordCase(fuzzCase)
+			if truncated {
+				stats.Replied++
+			} else if fuzzCase.Timeout {
+				stats.Timeouts++
+			} else {
+				stats.Replied++
+			}
+
+			var after []uint8
+			if prop.ImplementGet && before != nil {
+				get.TID = node.client.nextTID()
+				recvGet, err := node.exchange(get, timeout)
+				if err == nil && recvGet.ESV == 0x72 && len(recvGet.VarGroups) > 0 {
+					after = recvGet.VarGroups[0].EDT
+				}
+			}
+			if !truncated {
+				node.checkBoundaryReply(inst, prop, c, &setC, &recv, before, after)
+			}
+			if after != nil {
+				before = after
+			}
+			err = live.after(fuzzCase, &stats)
+			if err != nil {
+				return xerrors.Errorf("Boundary fuzzy stopped: %w", err)
+			}
+			a.minimizeFindings(&node, live, caseFindings, minimized)
+			stats.Findings += a.findingCount(&node) - caseFindings
+			a.progress(campaign, index, stats)
+		}
+	}
+	caseFindings := a.findingCount(&node)
+	err = live.flush(&stats)
+	if err != nil {
+		return xerrors.Errorf("Boundary fuzzy stopped: %w", err)
+	}
+	a.minimizeFindings(&node, live, caseFindings, minimized)
+	stats.Findings += a.findingCount(&node) - caseFindings
+	a.endCampaign(campaign)
+	node.logger.Info("Finished Boundary fuzzy", zap.String("instance", inst.ClassName))
+	return nil
+}
+
+// checkBoundaryReply check the reply to SetC with invalid EDT and the property value before and after it
+func (node *Node) checkBoundaryReply(inst Instance, prop Property, c boundaryCase, sent *FrameFormat, recv *FrameFormat, before []uint8, after []uint8) {
+	defer node.endCheck(node.beginCheck("BoundaryFuzz", inst.ClassCode, frameHex(sent), frameHex(recv)))
+	epc := fmt.Sprintf("%02X", prop.EPC)
+
+	if recv.EHD1 == 0 {
+		node.report(Finding{RuleID: "RECV-NONE", EPC: epc, Message: fmt.Sprintf("No reply to SetC of %s with invalid value (%s)", prop.PropertyName, c.name)})
+		return
+	}
+	node.CheckFlowValidation(*sent, *recv)
+	if recv.ESV == 0x71 {
+		node.report(Finding{RuleID: "BOUNDARY-ACCEPTED", EPC: epc, Message: fmt.Sprintf("Device accepted invalid value of %s (%s, EDT:%X)", prop.PropertyName, c.name, c.edt)})
+	} else if recv.ESV != 0x51 {
+		node.report(Finding{RuleID: "BOUNDARY-NOT-REJECTED", EPC: epc, Message: fmt.Sprintf("Device replied ESV %02X to invalid value of %s (%s), want 51", recv.ESV, prop.PropertyName, c.name)})
+	}
+	if before != nil && after != nil && !bytes.Equal(before, after) {
+		node.report(Finding{RuleID: "BOUNDARY-VALUE-CHANGED", EPC: epc, Message: fmt.Sprintf("Value of %s changed from %X to %X by invalid value (%s)", prop.PropertyName, before, after, c.name)})
+	}
+}
@@ -0,0 +1,65 @@
+package echonetlite
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func Test_propertyBoundaryValues(t *testing.T) {
+	cases := []struct {
+		name string
+		prop Property
+		want int
+	}{
+		{
+			name: "Number",
+			prop: Property{EPC: 0xB3, Data: []interface{}{Number{format: "uint8", minimum: 0, maximum: 50}}},
+			// maximum+1, size 2
+			want: 2,
+		},
+		{
+			name: "State",
+			prop: Property{EPC: 0x80, Data: []interface{}{State{size: 1, enum: []enumber{{edt: 0x30}, {edt: 0x31}}}}},
+			// 0x2F, 0x32, 0x00, 0xFF, size 2
+			want: 5,
+		},
+		{
+			name: "Number or State",
+			prop: Property{EPC: 0xB3, Data: []interface{}{
+				Number{format: "uint8", minimum: 0, maximum: 0xFC},
+				State{size: 1, enum: []enumber{{edt: 0xFD}}},
+			}},
+			// maximum+1 (0xFD) is valid as State and 0x00 is valid as Number. size 2, 0xFE and 0xFF are not
+			want: 3,
+		},
+		{
+			name: "DateTime",
+			prop: Property{EPC: 0x98, Data: []interface{}{DateTime{size: 4}}},
+			// 5 invalid dates, size 5, size 3
+			want: 7,
+		},
+		{
+			name: "Raw of 254 bytes",
+			prop: Property{EPC: 0xB3, Data: []interface{}{Raw{minSize: 2, maxSize: 254}}},
+			// size 1, size 255 which is the largest PDC
+			want: 2,
+		},
+	}
+	for _, c := range cases {
+		got, err := propertyBoundaryValues(c.prop, rand.New(rand.NewSource(1)))
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if len(got) != c.want {
+			t.Errorf("%s: cases => %d, want %d (%v)", c.name, len(got), c.want, got)
+		}
+		for _, bc := range got {
+			for _, data := range c.prop.Data {
+				if ok, err := elementCorrectRange(data, bc.edt); err == nil && ok {
+					t.Errorf("%s: %s (%X) is valid", c.name, bc.name, bc.edt)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package echonetlite
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/tttfrfr2/ECHONETTester/util"
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+// checkpointEvery is the number of cases sent between checkpoints
+const checkpointEvery = 20
+
+// CampaignProgress is the progress of a fuzzing campaign, a strategy against an instance
+type CampaignProgress struct {
+	Strategy string `json:"strategy"`
+	Node     string `json:"node"`
+	Instance string `json:"instance"`
+	Seed     int64  `json:"seed"`
+	// Next is the index of the case sent next. Cases before it are done
+	Next int  `json:"next"`
+	Done bool `json:"done"`
+	// Stats are the statistics of the campaign so far
+	Stats []FuzzStats `json:"stats"`
+}
+
+// Checkpoint is the progress of a run persisted periodically to resume it.
+// Checks and fuzzing cases are appended to the journal file instead of being rewritten every checkpoint
+type Checkpoint struct {
+	SchemaVersion int                 `json:"schemaVersion"`
+	RunID         string              `json:"runId"`
+	Seed          int64               `json:"seed"`
+	Saved         time.Time           `json:"saved"`
+	Campaigns     []*CampaignProgress `json:"campaigns"`
+	// Result is the result so far without checks and fuzzing cases
+	Result *Result `json:"result"`
+	// Journal is the file name of the journal next to the checkpoint.
+	// Checks and Cases are the numbers of checks and cases in it at this checkpoint
+	Journal string `json:"journal"`
+	Checks  int    `json:"checks"`
+	Cases   int    `json:"cases"`
+	// Suspects are the indexes of cases marked as suspects
+	Suspects []int `json:"suspects,omitempty"`
+}
+
+// journalEntry is a line of the journal
+type journalEntry struct {
+	Check *CheckRecord `json:"check,omitempty"`
+	Case  *FuzzCase    `json:"case,omitempty"`
+}
+
+// checkpointer keeps the progress of campaigns in a run
+type checkpointer struct {
+	mu        sync.Mutex
+	campaigns []*CampaignProgress
+	sent      int  // cases sent since the last checkpoint
+	journaled bool // the journal was written in this run
+	checks    int  // checks in the journal
+	cases     int  // cases in the journal
+}
+
+// LoadCheckpoint read the checkpoint file written during fuzzing and the checks and cases in its journal
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to read checkpoint %s: %w", path, err)
+	}
+	cp := &Checkpoint{}
+	err = json.Unmarshal(data, cp)
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to parse checkpoint %s: %w", path, err)
+	}
+	if cp.SchemaVersion > ResultSchemaVersion || cp.Result == nil {
+		return nil, xerrors.Errorf("Unsupported checkpoint %s", path)
+	}
+	if cp.Checks == 0 && cp.Cases == 0 {
+		return cp, nil
+	}
+
+	journal := filepath.Join(filepath.Dir(path), cp.Journal)
+	f, err := os.Open(journal)
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to read journal %s: %w", journal, err)
+	}
+	defer f.Close()
+	// entries appended after the checkpoint are ignored
+	decoder := json.NewDecoder(f)
+	for len(cp.Result.Checks) < cp.Checks || len(cp.Result.FuzzCases) < cp.Cases {
+		var entry journalEntry
+		err = decoder.Decode(&entry)
+		if err != nil {
+			return nil, xerrors.Errorf("Failed to parse journal %s: %w", journal, err)
+		}
+		if entry.Check != nil && len(cp.Result.Checks) < cp.Checks {
+			cp.Result.Checks = append(cp.Result.Checks, *entry.Check)
+		}
+		if entry.Case != nil && len(cp.Result.FuzzCases) < cp.Cases {
+			cp.Result.FuzzCases = append(cp.Result.FuzzCases, *entry.Case)
+		}
+	}
+	for _, i := range cp.Suspects {
+		if i < len(cp.Result.FuzzCases) {
+			cp.Result.FuzzCases[i].Suspect = true
+		}
+	}
+	return cp, nil
+}
+
+// SaveCheckpoint output the progress of campaigns and Result without checks and cases as JSON under the result directory,
+// and append checks finished and cases since the last checkpoint to the journal.
+// File names are stable per run, (RunID)-checkpoint.json and (RunID)-journal.jsonl
+func (a *Auditor) SaveCheckpoint() error {
+	if a.Result == nil {
+		return nil
+	}
+	cp := a.checkpoint()
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	journal := a.Result.RunID + "-journal.jsonl"
+	doc, checks, cases, suspects := a.Result.journal(cp.checks, cp.cases)
+	var entries []interface{}
+	for i := range checks {
+		entries = append(entries, journalEntry{Check: &checks[i]})
+	}
+	for i := range cases {
+		entries = append(entries, journalEntry{Case: &cases[i]})
+	}
+	// the journal of the checkpoint resumed is written again from the beginning
+	err := util.AppendJsonLines(filepath.Join(a.resultDir(), journal), !cp.journaled, entries...)
+	if err != nil {
+		return xerrors.Errorf("Failed to write journal: %w", err)
+	}
+	cp.journaled = true
+	cp.checks += len(checks)
+	cp.cases += len(cases)
+
+	checkpoint := Checkpoint{
+		SchemaVersion: ResultSchemaVersion,
+		RunID:         a.Result.RunID,
+		Seed:          a.Seed,
+		Saved:         time.Now(),
+		Campaigns:     cp.campaigns,
+		Result:        &Result{ResultDocument: doc},
+		Journal:       journal,
+		Checks:        cp.checks,
+		Cases:         cp.cases,
+		Suspects:      suspects,
+	}
+	cp.sent = 0
+	return util.OutJson(checkpoint, filepath.Join(a.resultDir(), a.Result.RunID+"-checkpoint"))
+}
+
+// journal return the copy of ResultDocument without checks and cases, the checks and cases to append to the journal
+// holding checks checks and cases cases, and the indexes of suspect cases. Only checks finished in order are appended
+func (r *Result) journal(checks int, cases int) (ResultDocument, []CheckRecord, []FuzzCase, []int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var retChecks []CheckRecord
+	for _, check := range r.Checks[checks:] {
+		if check.running {
+			break
+		}
+		retChecks = append(retChecks, check)
+	}
+	var retSuspects []int
+	for i, c := range r.FuzzCases {
+		if c.Suspect {
+			retSuspects = append(retSuspects, i)
+		}
+	}
+	return r.document(), retChecks, append([]FuzzCase(nil), r.FuzzCases[cases:]...), retSuspects
+}
+
+func (a *Auditor) checkpoint() *checkpointer {
+	if a.checkpoints == nil {
+		a.checkpoints = &checkpointer{}
+	}
+	return a.checkpoints
+}
+
+// beginCampaign return the progress of the campaign.
+// If the campaign was in progress in the checkpoint resumed, its progress is returned
+func (a *Auditor) beginCampaign(strategy string, node string, instance string, seed int64) *CampaignProgress {
+	cp := a.checkpoint()
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	for _, c := range cp.campaigns {
+		if c.Strategy == strategy && c.Node == node && c.Instance == instance && !c.Done {
+			return c
+		}
+	}
+	c := &CampaignProgress{Strategy: strategy, Node: node, Instance: instance, Seed: seed}
+	cp.campaigns = append(cp.campaigns, c)
+	return c
+}
+
+// progress record that the cases before next are done and save checkpoint every checkpointEvery cases
+func (a *Auditor) progress(c *CampaignProgress, next int, stats ...FuzzStats) {
+	cp := a.checkpoint()
+	cp.mu.Lock()
+	c.Next = next
+	c.Stats = stats
+	cp.sent++
+	save := cp.sent >= checkpointEvery
+	cp.mu.Unlock()
+	if save {
+		a.saveCheckpointLogged()
+	}
+}
+
+// endCampaign record that the campaign is done and save checkpoint
+func (a *Auditor) endCampaign(c *CampaignProgress) {
+	cp := a.checkpoint()
+	cp.mu.Lock()
+	c.Done = true
+	cp.mu.Unlock()
+	a.saveCheckpointLogged()
+}
+
+func (a *Auditor) saveCheckpointLogged() {
+	err := a.SaveCheckpoint()
+	if err != nil && a.logger != nil {
+		a.logger.Error("Save checkpoint Failed", zap.String("message", err.Error()))
+	}
+}
+
+// statsOf return the statistics of strategy in c, or a new one if c has no statistics of strategy
+func (c *CampaignProgress) statsOf(strategy string) FuzzStats {
+	for _, s := range c.Stats {
+		if s.Strategy == strategy {
+			return s
+		}
+	}
+	return FuzzStats{
+		Strategy: strategy,
+		Node:     c.Node,
+		Instance: c.Instance,
+		Seed:     c.Seed,
+		Start:    time.Now(),
+	}
+}
+
+// Resume continue the campaigns in cp which were not done.
+// Nodes must be added by NewAuditor before Resume. Result and seed of the run are restored from cp
+func (a *Auditor) Resume(cp *Checkpoint) error {
+	a.Seed = cp.Seed
+	a.Result = cp.Result
+	for i := range a.DistNodes {
+		a.DistNodes[i].result = a.Result
+	}
+	a.checkpoints = &checkpointer{campaigns: cp.Campaigns}
+	a.logger.Info("Resume", zap.String("runId", cp.RunID), zap.Time("saved", cp.Saved))
+
+	for _, c := range cp.Campaigns {
+		if c.Done {
+			continue
+		}
+		dstIP := net.ParseIP(c.Node)
+		dstCode, err := parseEOJ(c.Instance)
+		if dstIP == nil || err != nil {
+			return xerrors.Errorf("Invalid campaign %s %s %s in checkpoint", c.Strategy, c.Node, c.Instance)
+		}
+		a.logger.Info("Resume campaign", zap.String("strategy", c.Strategy), zap.String("IPaddr", c.Node), zap.String("instance", c.Instance), zap.Int("next", c.Next))
+		switch c.Strategy {
+		case "OPC Fuzz":
+			_, err = a.OpcFuzz(dstIP, dstCode)
+		case "Frame Fuzz":
+			err = a.FrameFuzz(dstIP, dstCode)
+		case "Boundary Fuzz":
+			err = a.BoundaryFuzz(dstIP, dstCode)
+		case "Profile Fuzz":
+			err = a.ProfileFuzz(dstIP)
+		default:
+			err = xerrors.Errorf("Unknown strategy %s", c.Strategy)
+		}
+		if err != nil {
+			return xerrors.Errorf("Failed to resume %s against %s %s: %w", c.Strategy, c.Node, c.Instance, err)
+		}
+	}
+	return nil
+}
+
+// parseEOJ change object code like "013001" into [3]uint8
+func parseEOJ(s string) ([3]uint8, error) {
+	var retEOJ [3]uint8
+	code, err := hex.DecodeString(s)
+	if err != nil || len(code) != 3 {
+		return retEOJ, xerrors.Errorf("Invalid object code %s", s)
+	}
+	copy(retEOJ[:], code)
+	return retEOJ, nil
+}
@@ -0,0 +1,97 @@
+package echonetlite
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func Test_Checkpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(dir)
+
+	a := &Auditor{Seed: 42, Result: NewResult("run"), logger: zap.NewNop()}
+	campaign := a.beginCampaign("OPC Fuzz", "192.0.2.1", "013001", a.campaignSeed("OPC Fuzz", "192.0.2.1", "013001"))
+	stats := campaign.statsOf("OPC Fuzz")
+	stats.Sent = 5
+	stats.Findings = 1
+	id := a.Result.beginCheck("Check", "192.0.2.1", "013001", "", "")
+	a.Result.Add(Finding{RuleID: "FLOW-ESV", Node: "192.0.2.1", CheckID: id})
+	a.Result.endCheck(id)
+	a.Result.AddFuzzCase(FuzzCase{Strategy: "OPC Fuzz", Index: 1, Sent: "01"})
+	running := a.Result.beginCheck("Check", "192.0.2.1", "013001", "", "")
+	a.progress(campaign, 5, stats)
+	// only checks and cases since the last checkpoint are appended
+	a.Result.endCheck(running)
+	a.Result.AddFuzzCase(FuzzCase{Strategy: "OPC Fuzz", Index: 2, Sent: "02"})
+	a.Result.MarkSuspects(a.Result.FuzzCases[:1])
+	done := a.beginCampaign("Frame Fuzz", "192.0.2.1", "013001", 1)
+	a.endCampaign(done)
+
+	data, _ := ioutil.ReadFile("result/run-checkpoint.json")
+	if strings.Contains(string(data), `"sent": "01"`) {
+		t.Errorf("checkpoint has cases: %s", data)
+	}
+	cp, err := LoadCheckpoint("result/run-checkpoint.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.RunID != "run" || cp.Seed != 42 || len(cp.Campaigns) != 2 {
+		t.Fatalf("checkpoint => %+v", cp)
+	}
+	if len(cp.Result.Findings) != 1 {
+		t.Errorf("findings => %d, want 1", len(cp.Result.Findings))
+	}
+	if len(cp.Result.Checks) != 2 || cp.Result.Checks[0].Passed || !cp.Result.Checks[1].Passed {
+		t.Errorf("checks => %+v, want failed and passed", cp.Result.Checks)
+	}
+	if len(cp.Result.FuzzCases) != 2 || !cp.Result.FuzzCases[0].Suspect || cp.Result.FuzzCases[1].Sent != "02" {
+		t.Errorf("cases => %+v, want suspect 01 and 02", cp.Result.FuzzCases)
+	}
+
+	// the campaign in progress is continued
+	b := &Auditor{checkpoints: &checkpointer{campaigns: cp.Campaigns}}
+	resumed := b.beginCampaign("OPC Fuzz", "192.0.2.1", "013001", 0)
+	if resumed.Next != 5 || resumed.Seed != campaign.Seed {
+		t.Errorf("resumed campaign => next %d seed %d, want 5 %d", resumed.Next, resumed.Seed, campaign.Seed)
+	}
+	if s := resumed.statsOf("OPC Fuzz"); s.Sent != 5 || s.Findings != 1 || s.Seed != campaign.Seed {
+		t.Errorf("resumed stats => %+v", s)
+	}
+	// the campaign done is executed again from the beginning
+	if again := b.beginCampaign("Frame Fuzz", "192.0.2.1", "013001", 1); again.Next != 0 || again.Done {
+		t.Errorf("campaign done is resumed: %+v", again)
+	}
+
+	// the journal is written again by the run resumed
+	b.Seed = cp.Seed
+	b.Result = cp.Result
+	b.logger = zap.NewNop()
+	b.Result.AddFuzzCase(FuzzCase{Strategy: "OPC Fuzz", Index: 6, Sent: "06"})
+	if err := b.SaveCheckpoint(); err != nil {
+		t.Fatal(err)
+	}
+	cp, err = LoadCheckpoint("result/run-checkpoint.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cp.Result.Checks) != 2 || len(cp.Result.FuzzCases) != 3 || !cp.Result.FuzzCases[0].Suspect {
+		t.Errorf("resumed checkpoint => checks %+v, cases %+v", cp.Result.Checks, cp.Result.FuzzCases)
+	}
+
+	if _, err := parseEOJ("0130"); err == nil {
+		t.Errorf("parseEOJ accepted 0130")
+	}
+	if eoj, err := parseEOJ("0EF001"); err != nil || eoj != [3]uint8{0x0E, 0xF0, 0x01} {
+		t.Errorf("parseEOJ(0EF001) => %X, %v", eoj, err)
+	}
+}
@@ -0,0 +1,423 @@
+package echonetlite
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+// defaultClientTimeout is the time Client waits a reply if neither Timeout nor ctx decides it
+const defaultClientTimeout = 3 * time.Second
+
+// subscribePoll is the time a subscription receives packets at a time, so that requests can interleave
+const subscribePoll = 100 * time.Millisecond
+
+// ErrTimeout is returned when no reply is received from a node within the timeout
+var ErrTimeout = xerrors.New("no reply from the node within the timeout")
+
+// SNAError is returned when a node replies that a request is not possible (ESV 0x5X).
+// Properties whose PDC is 0 in the reply of Set were accepted
+type SNAError struct {
+	Response *Response
+}
+
+func (e *SNAError) Error() string {
+	return fmt.Sprintf("%s replied SNA (ESV %02X)", eojString(e.Response.Frame.SEOJ), e.Response.Frame.ESV)
+}
+
+// MalformedError is returned when a packet received from a node can't be parsed as ECHONET Lite frame
+type MalformedError struct {
+	Data    []byte       // packet received
+	Err     error        // error of parser
+	Request *FrameFormat // request the packet is received for, nil if unknown
+}
+
+func (e *MalformedError) Error() string {
+	return fmt.Sprintf("Malformed packet %s: %s", hex.EncodeToString(e.Data), e.Err)
+}
+
+func (e *MalformedError) Unwrap() error {
+	return e.Err
+}
+
+// Response is a reply to a request of Client
+type Response struct {
+	Frame      FrameFormat    // reply as received
+	Properties []VarByteGroup // properties replied. For SetGet, properties set
+	Got        []VarByteGroup // properties got by SetGet
+}
+
+// Value return EDT of epc in the properties replied
+func (r *Response) Value(epc uint8) ([]uint8, bool) {
+	for _, group := range append(r.Properties, r.Got...) {
+		if group.EPC == epc && group.PDC > 0 {
+			return group.EDT, true
+		}
+	}
+	return nil, false
+}
+
+// Notification is INF or INFC received from a node
+type Notification struct {
+	Time       time.Time
+	SEOJ       [3]uint8       // object notifying
+	ESV        uint8          // INF 0x73 or INFC 0x74
+	Properties []VarByteGroup // properties notified
+}
+
+// Client is an ECHONET Lite client of a node.
+// Requests are sent one at a time, and INF and INFC received meanwhile are delivered to subscriptions
+type Client struct {
+	IP      net.IP
+	SEOJ    [3]uint8      // object requests are sent from. NewClient set the node profile 0EF001
+	Timeout time.Duration // time waiting a reply if ctx has no earlier deadline
+
+	conn     net.Conn     // send socket to the node
+	connRecv *net.UDPConn // receive socket read if inbox is nil
+	inbox    chan []byte  // packets from the node dispatched by receiver
+	owned    bool         // sockets are closed by Close
+	tid      uint32
+	mu       sync.Mutex // held while a request or a subscription receives
+	subsMu   sync.Mutex
+	subs     map[chan Notification]struct{}
+	logger   *zap.Logger
+}
+
+// NewClient create Client of the node ip sending through conn and receiving from connRecv
+func NewClient(ip net.IP, conn net.Conn, connRecv *net.UDPConn) *Client {
+	return &Client{
+		IP:       ip,
+		SEOJ:     [3]uint8{0x0E, 0xF0, 0x01},
+		Timeout:  defaultClientTimeout,
+		conn:     conn,
+		connRecv: connRecv,
+		tid:      uint32(time.Now().UnixNano()),
+		subs:     make(map[chan Notification]struct{}),
+		logger:   zap.NewNop(),
+	}
+}
+
+// Dial create Client of the node ip with sockets of its own. Replies are received on UDP port 3610,
+// so only one Client created by Dial can exist in a process
+func Dial(ip net.IP) (*Client, error) {
+	connRecv, err := net.ListenUDP("udp4", &net.UDPAddr{Port: 3610})
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to listen ECHONET Lite port: %w", err)
+	}
+	conn, err := net.Dial("udp4", fmt.Sprintf("%s:3610", ip))
+	if err != nil {
+		connRecv.Close()
+		return nil, xerrors.Errorf("Couldn't connect to %s: %w", ip, err)
+	}
+	c := NewClient(ip, conn, connRecv)
+	c.owned = true
+	return c, nil
+}
+
+// Close close the sockets created by Dial
+func (c *Client) Close() error {
+	if !c.owned {
+		return nil
+	}
+	err := c.conn.Close()
+	if recvErr := c.connRecv.Close(); err == nil {
+		err = recvErr
+	}
+	return err
+}
+
+// nextTID return a new transaction ID
+func (c *Client) nextTID() uint16 {
+	return uint16(atomic.AddUint32(&c.tid, 1))
+}
+
+// withTimeout return ctx whose deadline is Timeout later unless ctx has one
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = defaultClientTimeout
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// send send payload to the node
+func (c *Client) send(payload FrameFormat) error {
+	c.logger.Info("sent packet", zap.String("payload", frameHex(&payload)))
+	return SendEchonet(payload, c.conn)
+}
+
+// receive receive a packet from the node until ctx is done. ErrTimeout is returned when the deadline of ctx is exceeded
+func (c *Client) receive(ctx context.Context) ([]byte, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(c.Timeout)
+	}
+	if c.inbox != nil {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		select {
+		case data := <-c.inbox:
+			return data, nil
+		case <-timer.C:
+			return nil, xerrors.Errorf("Failed to recieve ECHONET Lite packet: %w", ErrTimeout)
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return nil, xerrors.Errorf("Failed to recieve ECHONET Lite packet: %w", ErrTimeout)
+			}
+			return nil, ctx.Err()
+		}
+	}
+
+	// the socket is read in short slices to notice cancellation
+	buffer := make([]byte, maxDatagram)
+	for {
+		if err := ctx.Err(); err != nil && err != context.DeadlineExceeded {
+			return nil, err
+		}
+		if !time.Now().Before(deadline) {
+			return nil, xerrors.Errorf("Failed to recieve ECHONET Lite packet: %w", ErrTimeout)
+		}
+		err := c.connRecv.SetDeadline(minTime(deadline, time.Now().Add(subscribePoll)))
+		if err != nil {
+			return nil, xerrors.Errorf("Setting Timeout Error: %w", err)
+		}
+		length, _, err := c.connRecv.ReadFromUDP(buffer)
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			continue
+		} else if err != nil {
+			return nil, xerrors.Errorf("Failed to recieve ECHONET Lite packet: %w", err)
+		}
+		// callers keep only the datagram, not the whole buffer
+		return append([]byte(nil), buffer[:length]...), nil
+	}
+}
+
+// minTime return the earlier of a and b
+func minTime(a time.Time, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// Exchange send payload and receive the reply whose TID is the same until ctx is done.
+// Requests from the node meanwhile are skipped, and notifications are delivered to subscriptions
+func (c *Client) Exchange(ctx context.Context, payload FrameFormat) (FrameFormat, error) {
+	var retFrame FrameFormat
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err := c.send(payload)
+	if err != nil {
+		return retFrame, err
+	}
+	for {
+		data, err := c.receive(ctx)
+		if err != nil {
+			return retFrame, err
+		}
+		recv, err := parser(data)
+		if err != nil {
+			return retFrame, &MalformedError{Data: data, Err: err, Request: &payload}
+		}
+		if recv.TID == payload.TID && (recv.ESV&0xF0 == 0x50 || recv.ESV&0xF0 == 0x70) && recv.ESV != 0x73 {
+			c.logger.Info("received packet", zap.String("payload", frameHex(recv)))
+			return *recv, nil
+		}
+		if !c.notify(recv) {
+			c.logger.Info("Skip packet", zap.String("payload", frameHex(recv)))
+		}
+	}
+}
+
+// request send a request of esv to the object eoj and return the reply.
+// If the reply is SNA, SNAError is returned with the reply
+func (c *Client) request(ctx context.Context, eoj [3]uint8, esv uint8, groups []VarByteGroup, gets []VarByteGroup) (*Response, error) {
+	payload := FrameFormat{
+		EHD1:      0x10,
+		EHD2:      0x81,
+		TID:       c.nextTID(),
+		SEOJ:      c.SEOJ,
+		DEOJ:      eoj,
+		ESV:       esv,
+		OPC:       uint8(len(groups)),
+		VarGroups: groups,
+	}
+	if esv == 0x6E {
+		payload.OPCG = uint8(len(gets))
+		payload.VarGroupsG = gets
+	}
+	recv, err := c.Exchange(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	retResponse := &Response{Frame: recv, Properties: recv.VarGroups, Got: recv.VarGroupsG}
+	if recv.ESV&0xF0 == 0x50 {
+		return retResponse, &SNAError{Response: retResponse}
+	}
+	return retResponse, nil
+}
+
+// getGroups return groups of epcs without EDT
+func getGroups(epcs []uint8) []VarByteGroup {
+	var retGroups []VarByteGroup
+	for _, epc := range epcs {
+		retGroups = append(retGroups, VarByteGroup{EPC: epc, PDC: 0x00})
+	}
+	return retGroups
+}
+
+// Get get properties epcs of the object eoj
+func (c *Client) Get(ctx context.Context, eoj [3]uint8, epcs ...uint8) (*Response, error) {
+	return c.request(ctx, eoj, 0x62, getGroups(epcs), nil)
+}
+
+// SetC set properties of the object eoj and return the reply
+func (c *Client) SetC(ctx context.Context, eoj [3]uint8, props ...VarByteGroup) (*Response, error) {
+	return c.request(ctx, eoj, 0x61, props, nil)
+}
+
+// SetI set properties of the object eoj without reply. The node replies only SNA,
+// so it waits the timeout and returns nil Response and nil error if the request is accepted
+func (c *Client) SetI(ctx context.Context, eoj [3]uint8, props ...VarByteGroup) (*Response, error) {
+	retResponse, err := c.request(ctx, eoj, 0x60, props, nil)
+	if xerrors.Is(err, ErrTimeout) {
+		return nil, nil
+	}
+	return retResponse, err
+}
+
+// SetGet set properties set and get properties gets of the object eoj in a request
+func (c *Client) SetGet(ctx context.Context, eoj [3]uint8, set []VarByteGroup, gets ...uint8) (*Response, error) {
+	return c.request(ctx, eoj, 0x6E, set, getGroups(gets))
+}
+
+// InfReq request the object eoj to notify properties epcs, and return the notification.
+// If INF_SNA is replied, SNAError is returned
+func (c *Client) InfReq(ctx context.Context, eoj [3]uint8, epcs ...uint8) (*Response, error) {
+	if len(epcs) == 0 {
+		return nil, xerrors.Errorf("No EPC to request notification")
+	}
+	payload := FrameFormat{
+		EHD1:      0x10,
+		EHD2:      0x81,
+		TID:       c.nextTID(),
+		SEOJ:      c.SEOJ,
+		DEOJ:      eoj,
+		ESV:       0x63,
+		OPC:       uint8(len(epcs)),
+		VarGroups: getGroups(epcs),
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err := c.send(payload)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		data, err := c.receive(ctx)
+		if err != nil {
+			return nil, err
+		}
+		recv, err := parser(data)
+		if err != nil {
+			return nil, &MalformedError{Data: data, Err: err, Request: &payload}
+		}
+		c.logger.Info("received packet", zap.String("payload", frameHex(recv)))
+		if recv.ESV == 0x53 && recv.TID == payload.TID {
+			retResponse := &Response{Frame: *recv, Properties: recv.VarGroups}
+			return retResponse, &SNAError{Response: retResponse}
+		}
+		// the notification may be announced with another TID
+		notified := recv.ESV == 0x73 && recv.SEOJ == eoj && len(recv.VarGroups) > 0 && recv.VarGroups[0].EPC == epcs[0]
+		c.notify(recv)
+		if notified {
+			return &Response{Frame: *recv, Properties: recv.VarGroups}, nil
+		}
+	}
+}
+
+// Subscribe receive INF and INFC from the node until ctx is done, and return the channel they are delivered.
+// INFC is responded. Notifications received by requests meanwhile are delivered as well.
+// Packets read by a subscription are not seen by raw receives of the node, so subscribe only while requesting through Client
+func (c *Client) Subscribe(ctx context.Context) <-chan Notification {
+	ch, stop := c.listen()
+	go func() {
+		defer func() {
+			stop()
+			close(ch)
+		}()
+		for ctx.Err() == nil {
+			c.mu.Lock()
+			pollCtx, cancel := context.WithTimeout(ctx, subscribePoll)
+			data, err := c.receive(pollCtx)
+			cancel()
+			c.mu.Unlock()
+			if err != nil {
+				continue
+			}
+			recv, err := parser(data)
+			if err != nil {
+				c.logger.Error("Malformed packet at subscription", zap.String("payload", hex.EncodeToString(data)))
+				continue
+			}
+			if !c.notify(recv) {
+				c.logger.Info("Skip packet", zap.String("payload", frameHex(recv)))
+			}
+		}
+	}()
+	return ch
+}
+
+// listen return the channel INF and INFC received by requests are delivered to until stop is called.
+// Unlike Subscribe, no packet is received by listen itself
+func (c *Client) listen() (ch chan Notification, stop func()) {
+	ch = make(chan Notification, inboxSize)
+	c.subsMu.Lock()
+	c.subs[ch] = struct{}{}
+	c.subsMu.Unlock()
+	return ch, func() {
+		c.subsMu.Lock()
+		delete(c.subs, ch)
+		c.subsMu.Unlock()
+	}
+}
+
+// notify deliver frame to subscriptions if it is INF or INFC, and respond INFC. Return whether frame is a notification
+func (c *Client) notify(frame *FrameFormat) bool {
+	if frame.ESV != 0x73 && frame.ESV != 0x74 {
+		return false
+	}
+	if frame.ESV == 0x74 {
+		res := FrameFormat{EHD1: 0x10, EHD2: 0x81, TID: frame.TID, SEOJ: frame.DEOJ, DEOJ: frame.SEOJ, ESV: 0x7A, OPC: frame.OPC}
+		for _, group := range frame.VarGroups {
+			res.VarGroups = append(res.VarGroups, VarByteGroup{EPC: group.EPC})
+		}
+		if err := c.send(res); err != nil {
+			c.logger.Error("Send INFC_Res Failed", zap.String("message", err.Error()))
+		}
+	}
+	n := Notification{Time: time.Now(), SEOJ: frame.SEOJ, ESV: frame.ESV, Properties: frame.VarGroups}
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+	for ch := range c.subs {
+		select {
+		case ch <- n:
+		default:
+		}
+	}
+	return true
+}
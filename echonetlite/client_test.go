@@ -0,0 +1,127 @@
+package echonetlite
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+func Test_ClientGet(t *testing.T) {
+	eoj := [3]uint8{0x01, 0x30, 0x01}
+	c, _ := newFakeClient(t, func(req *FrameFormat) []byte {
+		res := FrameFormat{EHD1: 0x10, EHD2: 0x81, TID: req.TID, SEOJ: req.DEOJ, DEOJ: req.SEOJ, ESV: 0x72, OPC: 1,
+			VarGroups: []VarByteGroup{{EPC: 0x80, PDC: 1, EDT: []uint8{0x30}}}}
+		switch req.VarGroups[0].EPC {
+		case 0x81:
+			res.ESV = 0x52
+			res.VarGroups = []VarByteGroup{{EPC: 0x81}}
+		case 0x82:
+			return nil
+		case 0x83:
+			return []byte{0x10, 0x81, uint8(req.TID >> 8), uint8(req.TID), 0x01, 0x30, 0x01, 0x0E, 0xF0, 0x01, 0x72, 0x01, 0x80, 0x02, 0x30}
+		}
+		return echonetToByte(res)
+	})
+	ctx := context.Background()
+
+	res, err := c.Get(ctx, eoj, 0x80)
+	if edt, ok := res.Value(0x80); err != nil || !ok || edt[0] != 0x30 {
+		t.Errorf("Get => %+v, %v", res, err)
+	}
+	var sna *SNAError
+	if res, err := c.Get(ctx, eoj, 0x81); !xerrors.As(err, &sna) || res == nil || res.Frame.ESV != 0x52 {
+		t.Errorf("Get of SNA => %+v, %v", res, err)
+	}
+	if _, err := c.Get(ctx, eoj, 0x82); !xerrors.Is(err, ErrTimeout) {
+		t.Errorf("Get without reply => %v, want timeout", err)
+	}
+	var malformed *MalformedError
+	if _, err := c.Get(ctx, eoj, 0x83); !xerrors.As(err, &malformed) || !isTruncated(err) {
+		t.Errorf("Get of truncated reply => %v", err)
+	}
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := c.Get(canceled, eoj, 0x82); err != context.Canceled {
+		t.Errorf("Get with canceled context => %v", err)
+	}
+}
+
+func Test_exchange_truncated(t *testing.T) {
+	c, _ := newFakeClient(t, func(req *FrameFormat) []byte {
+		return []byte{0x10, 0x81, uint8(req.TID >> 8), uint8(req.TID), 0x01, 0x30, 0x01, 0x0E, 0xF0, 0x01, 0x72, 0x01, 0x80, 0x02, 0x30}
+	})
+	node := newTestNode()
+	node.client = c
+	sent := FrameFormat{EHD1: 0x10, EHD2: 0x81, TID: c.nextTID(), SEOJ: c.SEOJ, DEOJ: [3]uint8{0x01, 0x30, 0x01}, ESV: 0x62, OPC: 1, VarGroups: []VarByteGroup{{EPC: 0x80}}}
+	if _, err := node.exchange(sent, time.Second); !isTruncated(err) {
+		t.Fatalf("exchange of truncated reply => %v", err)
+	}
+	if len(node.result.Findings) != 1 || len(node.result.Checks) != 1 {
+		t.Fatalf("findings => %+v, checks => %+v, want 1 each", node.result.Findings, node.result.Checks)
+	}
+	f := node.result.Findings[0]
+	if f.RuleID != "RECV-TRUNCATED" || f.CheckID != node.result.Checks[0].ID || f.Instance != "013001" || f.Sent != frameHex(&sent) || f.Recv == "" {
+		t.Errorf("truncated reply is reported as %+v", f)
+	}
+}
+
+func Test_ClientSetGet(t *testing.T) {
+	eoj := [3]uint8{0x01, 0x30, 0x01}
+	wire := make(chan []byte, 1)
+	c, _ := newRawFakeClient(t, func(data []byte) []byte {
+		wire <- data
+		// Set 0x80 accepted, Get 0xB3 replied 0x19
+		return []byte{0x10, 0x81, data[2], data[3], 0x01, 0x30, 0x01, 0x05, 0xFF, 0x01, 0x7E, 0x01, 0x80, 0x00, 0x01, 0xB3, 0x01, 0x19}
+	})
+	res, err := c.SetGet(context.Background(), eoj, []VarByteGroup{{EPC: 0x80, PDC: 1, EDT: []uint8{0x30}}}, 0xB3)
+	if err != nil {
+		t.Fatalf("SetGet => %v", err)
+	}
+	sent := <-wire
+	// ESV, OPCSet, EPC, PDC, EDT, OPCGet, EPC, PDC
+	if want := []byte{0x6E, 0x01, 0x80, 0x01, 0x30, 0x01, 0xB3, 0x00}; len(sent) != 10+len(want) || !bytes.Equal(sent[10:], want) {
+		t.Errorf("SetGet sent %X, want ...%X after header", sent, want)
+	}
+	if len(res.Got) != 1 || res.Got[0].EPC != 0xB3 || !bytes.Equal(res.Got[0].EDT, []uint8{0x19}) {
+		t.Errorf("SetGet got => %+v", res.Got)
+	}
+}
+
+func Test_ClientSubscribe(t *testing.T) {
+	eoj := [3]uint8{0x01, 0x30, 0x01}
+	responded := make(chan *FrameFormat, 1)
+	c, device := newFakeClient(t, func(req *FrameFormat) []byte {
+		responded <- req
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	notifications := c.Subscribe(ctx)
+
+	infc := FrameFormat{EHD1: 0x10, EHD2: 0x81, TID: 7, SEOJ: eoj, DEOJ: c.SEOJ, ESV: 0x74, OPC: 1,
+		VarGroups: []VarByteGroup{{EPC: 0x80, PDC: 1, EDT: []uint8{0x31}}}}
+	device.WriteToUDP(echonetToByte(infc), c.connRecv.LocalAddr().(*net.UDPAddr))
+
+	select {
+	case n := <-notifications:
+		if n.SEOJ != eoj || n.ESV != 0x74 || n.Properties[0].EDT[0] != 0x31 {
+			t.Errorf("Subscribe => %+v", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Subscribe => no notification")
+	}
+	select {
+	case res := <-responded:
+		if res.ESV != 0x7A || res.TID != 7 || res.DEOJ != eoj {
+			t.Errorf("INFC_Res => %+v", res)
+		}
+	case <-time.After(time.Second):
+		t.Error("INFC => no response")
+	}
+	cancel()
+	for range notifications {
+	}
+}
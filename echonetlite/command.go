@@ -0,0 +1,99 @@
+package echonetlite
+
+import (
+	"net"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+// findNode return the node designated by dstIP
+func (a *Auditor) findNode(dstIP net.IP) (Node, error) {
+	for _, node := range a.DistNodes {
+		if node.ip.Equal(dstIP) {
+			return node, nil
+		}
+	}
+	return Node{}, xerrors.Errorf("Node %s is not found", dstIP)
+}
+
+// Request send a request whose ESV is esv with groups to the object designated by dstIP and dstCode,
+// and check the reply. For SetI (0x60), only SNA is replied, so no reply is not an error
+func (a *Auditor) Request(dstIP net.IP, dstCode [3]uint8, esv uint8, groups []VarByteGroup) (FrameFormat, error) {
+	var retFrame FrameFormat
+	node, err := a.findNode(dstIP)
+	if err != nil {
+		return retFrame, err
+	}
+	payload := FrameFormat{
+		EHD1:      0x10,
+		EHD2:      0x81,
+		TID:       node.client.nextTID(),
+		SEOJ:      node.client.SEOJ,
+		DEOJ:      dstCode,
+		ESV:       esv,
+		OPC:       uint8(len(groups)),
+		VarGroups: groups,
+	}
+	node.logger.Info("sent packet", zap.String("payload", frameHex(&payload)))
+	retFrame, err = node.exchange(payload, node.replyTimeout())
+	if err != nil && xerrors.Is(err, ErrTimeout) && esv == 0x60 {
+		return retFrame, nil
+	} else if err != nil && !xerrors.Is(err, ErrTimeout) {
+		return retFrame, xerrors.Errorf("Failed to recieve reply from %s: %w", dstIP, err)
+	}
+	node.logger.Info("received packet", zap.String("payload", frameHex(&retFrame)))
+	node.Check(&payload, &retFrame)
+	if retFrame.EHD1 == 0 {
+		return retFrame, xerrors.Errorf("No reply from %s", dstIP)
+	}
+	return retFrame, nil
+}
+
+// Sweep get properties in Get property map of the instance designated by dstIP and dstCode one by one
+// and check the replies and the values
+func (a *Auditor) Sweep(dstIP net.IP, dstCode [3]uint8) error {
+	node, instIndex, err := a.searchInstane(dstIP, dstCode)
+	if err != nil {
+		a.logger.Error("Invalid Class Code", zap.String("IPaddr", dstIP.String()), zap.String("CLASSCODE", eojString(dstCode)))
+		return xerrors.Errorf("Invalid Class Code")
+	}
+	inst := node.Instances[instIndex]
+	node.logger.Info("Start sweep", zap.String("instance", inst.ClassName))
+	start := time.Now()
+	timeout := node.replyTimeout()
+
+	for _, prop := range inst.Props {
+		if !prop.ImplementGet {
+			continue
+		}
+		if err := node.canceled(); err != nil {
+			return xerrors.Errorf("Sweep stopped: %w", err)
+		}
+		get := FrameFormat{
+			EHD1:      0x10,
+			EHD2:      0x81,
+			TID:       node.client.nextTID(),
+			SEOJ:      node.client.SEOJ,
+			DEOJ:      dstCode,
+			ESV:       0x62,
+			OPC:       0x01,
+			VarGroups: []VarByteGroup{{EPC: prop.EPC, PDC: 0x00}},
+		}
+		node.logger.Info("sent packet", zap.String("payload", frameHex(&get)))
+		recv, err := node.exchange(get, timeout)
+		if err != nil && isTruncated(err) {
+			// reported by exchange
+			continue
+		} else if err != nil && !xerrors.Is(err, ErrTimeout) {
+			return xerrors.Errorf("Failed to recieve reply at sweep: %w", err)
+		}
+		node.Check(&get, &recv)
+	}
+	if node.result != nil {
+		node.result.AddTiming("Sweep", node.ip.String(), start)
+	}
+	node.logger.Info("Finished sweep", zap.String("instance", inst.ClassName))
+	return nil
+}
@@ -1,9 +1,9 @@
 package echonetlite
 
-var definition []byte
-var latest string
-var nodeProfileEOJ [3]uint8
-var timeoutFuzz int
-var rel string
-var jsonAll []byte
-var TimeStr string
+import "time"
+
+const defaultReplyTimeout = 3 * time.Second       // waiting reply if timeout isn't configured
+const defaultLivenessEvery = 10                   // fuzz cases sent between heartbeat probes
+const defaultLivenessRecovery = 120 * time.Second // waiting a device recovers from hang or reboot
+const defaultMinimizeTests = 100                  // cases sent at most to minimize a case of finding
+const ExitInterrupted = 130                       // exit status when interrupted by Ctrl-C (128 + SIGINT)
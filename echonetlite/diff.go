@@ -0,0 +1,234 @@
+package echonetlite
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// RTT regression is reported when median of new RTT is rttRegressionRatio times as long as old one
+// and the difference is longer than rttRegressionMin
+const (
+	rttRegressionRatio = 1.5
+	rttRegressionMin   = 20 * time.Millisecond
+)
+
+// ResultDiff is the difference between two results of the same devices
+type ResultDiff struct {
+	Old string // run ID of old result
+	New string // run ID of new result
+
+	PropsAppeared    []PropertyChange
+	PropsDisappeared []PropertyChange
+	NewFindings      []Finding
+	FixedFindings    []Finding
+	RangeChanges     []RangeChange
+	RTTRegressions   []RTTRegression
+}
+
+// PropertyChange is a property which appeared in or disappeared from a property map
+type PropertyChange struct {
+	Node     string
+	Instance string
+	Map      string // "Get", "Set" or "Inf"
+	EPC      string
+	Name     string
+}
+
+// RangeChange is the change of value range observed of a property
+type RangeChange struct {
+	Node     string
+	Instance string
+	EPC      string
+	Old      Observation
+	New      Observation
+}
+
+// RTTRegression is the response time which became longer
+type RTTRegression struct {
+	Node     string
+	Instance string
+	Strategy string
+	Old      time.Duration
+	New      time.Duration
+}
+
+// DiffResults compare old and new Result
+func DiffResults(oldResult *Result, newResult *Result) *ResultDiff {
+	diff := &ResultDiff{Old: oldResult.RunID, New: newResult.RunID}
+
+	// property maps
+	oldMaps := propertyMaps(oldResult)
+	newMaps := propertyMaps(newResult)
+	for key, change := range newMaps {
+		if _, ok := oldMaps[key]; !ok {
+			diff.PropsAppeared = append(diff.PropsAppeared, change)
+		}
+	}
+	for key, change := range oldMaps {
+		if _, ok := newMaps[key]; !ok {
+			diff.PropsDisappeared = append(diff.PropsDisappeared, change)
+		}
+	}
+	sortPropertyChanges(diff.PropsAppeared)
+	sortPropertyChanges(diff.PropsDisappeared)
+
+	// findings
+	oldFindings := findingKeys(oldResult)
+	newFindings := findingKeys(newResult)
+	for key, f := range newFindings {
+		if _, ok := oldFindings[key]; !ok {
+			diff.NewFindings = append(diff.NewFindings, f)
+		}
+	}
+	for key, f := range oldFindings {
+		if _, ok := newFindings[key]; !ok {
+			diff.FixedFindings = append(diff.FixedFindings, f)
+		}
+	}
+	sortFindings(diff.NewFindings)
+	sortFindings(diff.FixedFindings)
+
+	// value ranges
+	oldObservations := make(map[string]Observation)
+	for _, o := range oldResult.Observations {
+		oldObservations[o.Node+"/"+o.Instance+"/"+o.EPC] = o
+	}
+	for _, o := range newResult.Observations {
+		old, ok := oldObservations[o.Node+"/"+o.Instance+"/"+o.EPC]
+		if ok && (compareEDT(old.Min, o.Min, o.Signed) != 0 || compareEDT(old.Max, o.Max, o.Signed) != 0) {
+			diff.RangeChanges = append(diff.RangeChanges, RangeChange{Node: o.Node, Instance: o.Instance, EPC: o.EPC, Old: old, New: o})
+		}
+	}
+	sort.Slice(diff.RangeChanges, func(i, j int) bool {
+		a, b := diff.RangeChanges[i], diff.RangeChanges[j]
+		return a.Node+a.Instance+a.EPC < b.Node+b.Instance+b.EPC
+	})
+
+	// response time
+	oldRTT := medianRTT(oldResult)
+	newRTT := medianRTT(newResult)
+	for key, rtt := range newRTT {
+		old, ok := oldRTT[key]
+		if ok && float64(rtt) > float64(old)*rttRegressionRatio && rtt-old > rttRegressionMin {
+			diff.RTTRegressions = append(diff.RTTRegressions, RTTRegression{Node: key[0], Instance: key[1], Strategy: key[2], Old: old, New: rtt})
+		}
+	}
+	sort.Slice(diff.RTTRegressions, func(i, j int) bool {
+		a, b := diff.RTTRegressions[i], diff.RTTRegressions[j]
+		return a.Node+a.Instance+a.Strategy < b.Node+b.Instance+b.Strategy
+	})
+	return diff
+}
+
+// CountNew return the number of new findings whose severity is min or more serious
+func (d *ResultDiff) CountNew(min Severity) int {
+	count := 0
+	for _, f := range d.NewFindings {
+		if f.Severity.Rank() >= min.Rank() {
+			count++
+		}
+	}
+	return count
+}
+
+// WriteText write ResultDiff as text into w
+func (d *ResultDiff) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", d.Old, d.New)
+	fmt.Fprintf(w, "\n## Properties appeared (%d)\n", len(d.PropsAppeared))
+	for _, p := range d.PropsAppeared {
+		fmt.Fprintf(w, "+ %s %s %s EPC:%s %s\n", p.Node, p.Instance, p.Map, p.EPC, p.Name)
+	}
+	fmt.Fprintf(w, "\n## Properties disappeared (%d)\n", len(d.PropsDisappeared))
+	for _, p := range d.PropsDisappeared {
+		fmt.Fprintf(w, "- %s %s %s EPC:%s %s\n", p.Node, p.Instance, p.Map, p.EPC, p.Name)
+	}
+	fmt.Fprintf(w, "\n## New findings (%d)\n", len(d.NewFindings))
+	for _, f := range d.NewFindings {
+		fmt.Fprintf(w, "+ [%s] %s %s %s EPC:%s %s\n", f.Severity, f.RuleID, f.Node, f.Instance, f.EPC, f.Message)
+	}
+	fmt.Fprintf(w, "\n## Fixed findings (%d)\n", len(d.FixedFindings))
+	for _, f := range d.FixedFindings {
+		fmt.Fprintf(w, "- [%s] %s %s %s EPC:%s %s\n", f.Severity, f.RuleID, f.Node, f.Instance, f.EPC, f.Message)
+	}
+	fmt.Fprintf(w, "\n## Value ranges changed (%d)\n", len(d.RangeChanges))
+	for _, r := range d.RangeChanges {
+		fmt.Fprintf(w, "~ %s %s EPC:%s [%s, %s] -> [%s, %s]\n", r.Node, r.Instance, r.EPC, r.Old.Min, r.Old.Max, r.New.Min, r.New.Max)
+	}
+	fmt.Fprintf(w, "\n## Response time regressions (%d)\n", len(d.RTTRegressions))
+	for _, r := range d.RTTRegressions {
+		fmt.Fprintf(w, "~ %s %s %s %s -> %s\n", r.Node, r.Instance, r.Strategy, r.Old, r.New)
+	}
+}
+
+// propertyMaps return the properties in property maps of result keyed by node, instance, map and EPC
+func propertyMaps(r *Result) map[string]PropertyChange {
+	retMaps := make(map[string]PropertyChange)
+	for _, node := range r.Nodes {
+		for _, inst := range node.Instances {
+			for _, prop := range inst.Props {
+				implements := map[string]bool{"Get": prop.ImplementGet, "Set": prop.ImplementSet, "Inf": prop.ImplementInf}
+				for mapName, implemented := range implements {
+					if implemented {
+						retMaps[node.IP+"/"+inst.Code+"/"+mapName+"/"+prop.EPC] = PropertyChange{
+							Node:     node.IP,
+							Instance: inst.Code,
+							Map:      mapName,
+							EPC:      prop.EPC,
+							Name:     prop.Name,
+						}
+					}
+				}
+			}
+		}
+	}
+	return retMaps
+}
+
+// findingKeys return the findings of result keyed by rule, node, instance and EPC
+func findingKeys(r *Result) map[string]Finding {
+	retFindings := make(map[string]Finding)
+	for _, f := range r.Findings {
+		key := f.RuleID + "/" + f.Node + "/" + f.Instance + "/" + f.EPC
+		if _, ok := retFindings[key]; !ok {
+			retFindings[key] = f
+		}
+	}
+	return retFindings
+}
+
+// medianRTT return median RTT of replied fuzz cases keyed by node, instance and strategy
+func medianRTT(r *Result) map[[3]string]time.Duration {
+	rtts := make(map[[3]string][]time.Duration)
+	for _, c := range r.FuzzCases {
+		if c.Timeout || c.Recv == "" {
+			continue
+		}
+		key := [3]string{c.Node, c.Instance, c.Strategy}
+		rtts[key] = append(rtts[key], c.RTT)
+	}
+	retRTT := make(map[[3]string]time.Duration)
+	for key, values := range rtts {
+		sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+		retRTT[key] = values[len(values)/2]
+	}
+	return retRTT
+}
+
+func sortPropertyChanges(changes []PropertyChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		return a.Node+a.Instance+a.Map+a.EPC < b.Node+b.Instance+b.Map+b.EPC
+	})
+}
+
+func sortFindings(findings []Finding) {
+	sort.Slice(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Severity.Rank() != b.Severity.Rank() {
+			return a.Severity.Rank() > b.Severity.Rank()
+		}
+		return a.RuleID+a.Node+a.Instance+a.EPC < b.RuleID+b.Node+b.Instance+b.EPC
+	})
+}
@@ -0,0 +1,56 @@
+package echonetlite
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_DiffResults(t *testing.T) {
+	oldResult := NewResult("old")
+	oldResult.Nodes = []NodeRecord{{IP: "192.0.2.1", Instances: []InstanceRecord{{Code: "013001", Props: []PropertyRecord{
+		{EPC: "80", ImplementGet: true, ImplementSet: true},
+		{EPC: "B0", ImplementGet: true},
+	}}}}}
+	oldResult.Findings = []Finding{
+		{RuleID: "FLOW-TID", Severity: SeverityMedium, Node: "192.0.2.1", Instance: "013001"},
+	}
+	oldResult.Observe("192.0.2.1", "013001", 0xB3, []uint8{0x10}, false)
+	oldResult.Observe("192.0.2.1", "013001", 0xB3, []uint8{0x20}, false)
+	oldResult.FuzzCases = []FuzzCase{{Node: "192.0.2.1", Instance: "013001", Strategy: "OPC Fuzz", Recv: "10", RTT: 10 * time.Millisecond}}
+
+	newResult := NewResult("new")
+	newResult.Nodes = []NodeRecord{{IP: "192.0.2.1", Instances: []InstanceRecord{{Code: "013001", Props: []PropertyRecord{
+		{EPC: "80", ImplementGet: true},
+		{EPC: "B0", ImplementGet: true},
+		{EPC: "B3", ImplementGet: true},
+	}}}}}
+	newResult.Findings = []Finding{
+		{RuleID: "FLOW-ESV", Severity: SeverityHigh, Node: "192.0.2.1", Instance: "013001"},
+	}
+	newResult.Observe("192.0.2.1", "013001", 0xB3, []uint8{0x10}, false)
+	newResult.Observe("192.0.2.1", "013001", 0xB3, []uint8{0x32}, false)
+	newResult.FuzzCases = []FuzzCase{{Node: "192.0.2.1", Instance: "013001", Strategy: "OPC Fuzz", Recv: "10", RTT: 200 * time.Millisecond}}
+
+	diff := DiffResults(oldResult, newResult)
+	if len(diff.PropsAppeared) != 1 || diff.PropsAppeared[0].EPC != "B3" {
+		t.Errorf("PropsAppeared => %+v, want B3 Get", diff.PropsAppeared)
+	}
+	if len(diff.PropsDisappeared) != 1 || diff.PropsDisappeared[0].EPC != "80" || diff.PropsDisappeared[0].Map != "Set" {
+		t.Errorf("PropsDisappeared => %+v, want 80 Set", diff.PropsDisappeared)
+	}
+	if len(diff.NewFindings) != 1 || diff.NewFindings[0].RuleID != "FLOW-ESV" {
+		t.Errorf("NewFindings => %+v, want FLOW-ESV", diff.NewFindings)
+	}
+	if len(diff.FixedFindings) != 1 || diff.FixedFindings[0].RuleID != "FLOW-TID" {
+		t.Errorf("FixedFindings => %+v, want FLOW-TID", diff.FixedFindings)
+	}
+	if len(diff.RangeChanges) != 1 || diff.RangeChanges[0].New.Max != "32" {
+		t.Errorf("RangeChanges => %+v, want max 32", diff.RangeChanges)
+	}
+	if len(diff.RTTRegressions) != 1 {
+		t.Errorf("RTTRegressions => %+v, want 1", diff.RTTRegressions)
+	}
+	if diff.CountNew(SeverityHigh) != 1 || DiffResults(newResult, newResult).CountNew(SeverityInfo) != 0 {
+		t.Errorf("CountNew is wrong")
+	}
+}
@@ -42,11 +42,22 @@ func (a *Auditor) searchInstane(dstIP net.IP, dstCode [3]uint8) (Node, int, erro
 
 // OpcFuzz send a number of ECHONET Lite packet whose EPCs is designated by argument "epc"
 // and whose destnation object is designated by a.dst and dstCode.
+// Each pair of packets is checked and the statistics are stored in a.Result.
 // This function return 2 value, [2][]FrameFormat and error type.
 // [0][i]FrameFormat means the packet sent and corresponds [1][i]FrameFormat that is recieve packet
 func (a *Auditor) OpcFuzz(dstIP net.IP, dstCode [3]uint8) ([2][]FrameFormat, error) {
-	fmt.Println("---Start OPC fuzzy---")
+	a.printf("---Start OPC fuzzy---\n")
 	var retFrames [2][]FrameFormat
+	strategy := "OPC Fuzz"
+	campaign := a.beginCampaign(strategy, dstIP.String(), eojString(dstCode), a.campaignSeed(strategy, dstIP.String(), eojString(dstCode)))
+	stats := campaign.statsOf(strategy)
+	if a.Result != nil {
+		defer func() {
+			stats.Duration = time.Since(stats.Start)
+			a.Result.AddFuzz(stats)
+			a.Result.AddTiming(stats.Strategy, stats.Node, stats.Start)
+		}()
+	}
 
 	node, instIndex, exist := a.searchInstane(dstIP, dstCode)
 	node.logger.Info("Start OPC fuzzy")
@@ -60,13 +71,13 @@ func (a *Auditor) OpcFuzz(dstIP net.IP, dstCode [3]uint8) ([2][]FrameFormat, err
 	payload := FrameFormat{
 		EHD1: 0x10,
 		EHD2: 0x81,
-		SEOJ: nodeProfileEOJ,
+		SEOJ: node.client.SEOJ,
 		DEOJ: dstCode,
 		ESV:  0x61,
 		OPC:  0x00,
 	}
 
-	rand.Seed(time.Now().UnixNano())
+	r := rand.New(rand.NewSource(stats.Seed))
 
 	var sends []uint8
 	for _, prop := range inst.Props {
@@ -78,14 +89,20 @@ func (a *Auditor) OpcFuzz(dstIP net.IP, dstCode [3]uint8) ([2][]FrameFormat, err
 		node.logger.Error("There are no SET property")
 		return retFrames, xerrors.Errorf("Cannot OPC Fuzzy: There are no Property whose Set access rule")
 	}
+	live := newLiveness(&node, a.LivenessEvery, a.LivenessRecovery)
+	minimized := make(minimizedCases)
 	// OPC [1:255]
 	for i := 1; i < 256; i++ {
+		if err := node.canceled(); err != nil {
+			return retFrames, xerrors.Errorf("OPC fuzzy stopped: %w", err)
+		}
+		caseFindings := a.findingCount(&node)
 		var payloadData VarByteGroup
 		var err error
 		var propRand Property
 
 		// choose property is generated random value
-		randIndex := rand.Intn(len(sends))
+		randIndex := r.Intn(len(sends))
 		epc := sends[randIndex]
 		for _, prop := range inst.Props {
 			if prop.EPC == epc {
@@ -96,34 +113,70 @@ func (a *Auditor) OpcFuzz(dstIP net.IP, dstCode [3]uint8) ([2][]FrameFormat, err
 		payloadData.EPC = epc
 
 		// property data (EDT) generated random
-		payloadData.EDT, err = RandProp(propRand)
+		payloadData.EDT, err = RandProp(propRand, r)
 		if err != nil {
 			node.logger.Error("Generate random value Failed")
 			return retFrames, xerrors.Errorf("Failed to generate random property: %w", err)
 		}
 		payloadData.PDC = uint8(len(payloadData.EDT))
 		payload.OPC++
-		payload.TID = uint16(rand.Intn(0xFFFF))
 		payload.VarGroups = append(payload.VarGroups, payloadData)
+		if i <= campaign.Next {
+			// sent before the checkpoint resumed
+			continue
+		}
+		payload.TID = node.client.nextTID()
 		node.logger.Info("sent packet", zap.String("payload", fmt.Sprintf("%+v", payload)))
 		retFrames[0] = append(retFrames[0], payload)
-		err = SendEchonet(payload, node.connSend)
-		if err != nil {
-			node.logger.Error("Send packet Failed", zap.String("payload", fmt.Sprintf("%+v", payload)))
-			return retFrames, xerrors.Errorf("Failed to send ECHONET Lite packet at OPC fuzzy: %w", err)
-		}
-		recv, err := node.RecvEchonet()
+		stats.Sent++
+		fuzzCase := FuzzCase{
+			Strategy: stats.Strategy,
+			Node:     stats.Node,
+			Instance: stats.Instance,
+			Index:    i,
+			Sent:     frameHex(&payload),
+			SentAt:   time.Now(),
+		}
+		recv, err := node.exchange(payload, node.replyTimeout())
+		fuzzCase.RTT = time.Since(fuzzCase.SentAt)
+		fuzzCase.Recv = frameHex(&recv)
+		fuzzCase.Timeout = err != nil && xerrors.Is(err, ErrTimeout)
+		a.recordCase(fuzzCase)
 		if err != nil {
-			if strings.Contains(err.Error(), "timeout") {
+			if xerrors.Is(err, ErrTimeout) {
+				stats.Timeouts++
 				node.logger.Error("Receive packet Timeout", zap.String("payload", fmt.Sprintf("%+v", recv)))
+			} else if isTruncated(err) {
+				// reported by exchange
+				stats.Replied++
 			} else {
 				node.logger.Error("Receive packet Failed", zap.String("payload", fmt.Sprintf("%+v", recv)), zap.String("message", err.Error()))
 				return retFrames, xerrors.Errorf("Failed to recieve ECHONET Lite packet at OPC fuzzy: %w", err)
 			}
+		} else {
+			stats.Replied++
 		}
 		node.logger.Info("received packet", zap.String("payload", fmt.Sprintf("%+v", recv)))
 		retFrames[1] = append(retFrames[1], recv)
+		if err == nil || fuzzCase.Timeout {
+			node.Check(&retFrames[0][len(retFrames[0])-1], &retFrames[1][len(retFrames[1])-1])
+		}
+		err = live.after(fuzzCase, &stats)
+		if err != nil {
+			return retFrames, xerrors.Errorf("OPC fuzzy stopped: %w", err)
+		}
+		a.minimizeFindings(&node, live, caseFindings, minimized)
+		stats.Findings += a.findingCount(&node) - caseFindings
+		a.progress(campaign, i, stats)
+	}
+	caseFindings := a.findingCount(&node)
+	err := live.flush(&stats)
+	if err != nil {
+		return retFrames, xerrors.Errorf("OPC fuzzy stopped: %w", err)
 	}
+	a.minimizeFindings(&node, live, caseFindings, minimized)
+	stats.Findings += a.findingCount(&node) - caseFindings
+	a.endCampaign(campaign)
 	return retFrames, nil
 }
 
@@ -131,14 +184,12 @@ func (a *Auditor) OpcFuzz(dstIP net.IP, dstCode [3]uint8) ([2][]FrameFormat, err
 // Designate Property by argument 'anlyzData'
 // This function return 2 value, []uint8, error type.
 // []uint8 is EDT randomly desided
-func RandProp(anlyzData interface{}) ([]uint8, error) {
+func RandProp(anlyzData interface{}, r *rand.Rand) ([]uint8, error) {
 	var retNum []uint8
 
-	rand.Seed(time.Now().UnixNano())
-
 	if value, ok := anlyzData.([]interface{}); ok { // argument 'anlyzData' is []interface
-		randIndex := rand.Intn(len(value))
-		recNum, err := RandProp(value[randIndex])
+		randIndex := r.Intn(len(value))
+		recNum, err := RandProp(value[randIndex], r)
 		if err != nil {
 			return retNum, err
 		}
@@ -150,12 +201,12 @@ func RandProp(anlyzData interface{}) ([]uint8, error) {
 	} else if value, ok := anlyzData.(Property); ok { // argument 'anlyzData' is Property struct
 		var anlyz interface{}
 		if len(value.Data) > 1 {
-			randIndex := rand.Intn(len(value.Data))
+			randIndex := r.Intn(len(value.Data))
 			anlyz = value.Data[randIndex]
 		} else {
 			anlyz = value.Data[0]
 		}
-		recNum, err := RandProp(anlyz)
+		recNum, err := RandProp(anlyz, r)
 		if err != nil {
 			return recNum, xerrors.Errorf("Failed to generate rand EDT of CODE:0x%X: %w", value.EPC, err)
 		}
@@ -166,7 +217,7 @@ func RandProp(anlyzData interface{}) ([]uint8, error) {
 
 	} else if value, ok := anlyzData.(Number); ok { // argument 'anlyzData' is Number struct
 		if value.enum != nil {
-			randNum := value.enum[rand.Int()%len(value.enum)]
+			randNum := value.enum[r.Int()%len(value.enum)]
 			if strings.HasSuffix(value.format, "int8") {
 				return []uint8{uint8(randNum)}, nil
 			} else if strings.HasSuffix(value.format, "int16") {
@@ -180,7 +231,7 @@ func RandProp(anlyzData interface{}) ([]uint8, error) {
 		} else if strings.HasPrefix(value.format, "uint") {
 			max := uint(value.maximum)
 			min := uint(value.minimum)
-			randNum := uint(rand.Int()) % max
+			randNum := uint(r.Int()) % max
 			if randNum < min {
 				randNum = min
 			}
@@ -196,7 +247,7 @@ func RandProp(anlyzData interface{}) ([]uint8, error) {
 		} else {
 			max := int(value.maximum)
 			min := int(value.minimum)
-			randNum := rand.Int()
+			randNum := r.Int()
 			if randNum > max {
 				randNum = max
 			} else if randNum < min {
@@ -214,7 +265,7 @@ func RandProp(anlyzData interface{}) ([]uint8, error) {
 		}
 	} else if value, ok := anlyzData.(State); ok { // argument 'anlyzData' is State struct
 		size := value.size
-		randIndex := rand.Int() % len(value.enum)
+		randIndex := r.Int() % len(value.enum)
 		retEnum := value.enum[randIndex]
 		retEdt := retEnum.edt
 		if size == 0 {
@@ -235,7 +286,7 @@ func RandProp(anlyzData interface{}) ([]uint8, error) {
 		if err != nil {
 			return nil, xerrors.Errorf("Invalid format of number: %w", err)
 		}
-		randNum := base + int64(rand.Intn(int(value.maximum)))
+		randNum := base + int64(r.Intn(int(value.maximum)))
 		for i := size - 1; i >= 0; i-- {
 			retNum = append(retNum, uint8(randNum>>(i*8))&0xFF)
 		}
@@ -246,10 +297,10 @@ func RandProp(anlyzData interface{}) ([]uint8, error) {
 		if value.minSize == value.maxSize {
 			size = value.maxSize
 		} else {
-			size = int64(rand.Intn(int(value.maxSize-value.minSize))) + value.minSize
+			size = int64(r.Intn(int(value.maxSize-value.minSize))) + value.minSize
 		}
 		for i := size - 1; i >= 0; i-- {
-			randNum := uint8(rand.Intn(0xFF))
+			randNum := uint8(r.Intn(0xFF))
 			retNum = append(retNum, uint8(randNum>>(i*4))&0xFF)
 		}
 
@@ -257,7 +308,7 @@ func RandProp(anlyzData interface{}) ([]uint8, error) {
 
 	} else if value, ok := anlyzData.(Object); ok { // argument 'anlyzData' is Object struct
 		for _, data := range value.element {
-			recNum, err := RandProp(data.data)
+			recNum, err := RandProp(data.data, r)
 			if err != nil {
 				return retNum, xerrors.Errorf("Failed to generate rand Object (NAME:%s): %w", data.name, err)
 			}
@@ -272,10 +323,10 @@ func RandProp(anlyzData interface{}) ([]uint8, error) {
 		if value.maxItems == value.minItems {
 			items = value.maxItems
 		} else {
-			items = int64(rand.Intn(int(value.maxItems-value.minItems))) + value.minItems
+			items = int64(r.Intn(int(value.maxItems-value.minItems))) + value.minItems
 		}
 		for i := 0; int64(i) < items; i++ {
-			recNum, err := RandProp(value.data)
+			recNum, err := RandProp(value.data, r)
 			if err != nil {
 				return retNum, xerrors.Errorf("Failed to generate rand Array: %w", err)
 			}
@@ -295,7 +346,7 @@ func RandProp(anlyzData interface{}) ([]uint8, error) {
 
 		for _, el := range value.bitmaps {
 			index = el.index
-			recNum, err := RandProp(el.value)
+			recNum, err := RandProp(el.value, r)
 			if err != nil {
 				return retNum, xerrors.Errorf("Failed to generate rand Bitmap (Name:%s", el.name, err)
 			}
@@ -339,7 +390,7 @@ func RandProp(anlyzData interface{}) ([]uint8, error) {
 		return retNum, nil
 
 	} else if value, ok := anlyzData.(NumericValues); ok { // argument 'anlyzData' is NumericValues struct
-		randIndex := rand.Intn(len(value.enum))
+		randIndex := r.Intn(len(value.enum))
 		return []uint8{uint8(value.enum[randIndex].edt)}, nil
 
 	} else if value, ok := anlyzData.(DateTime); ok { // argument 'anlyzData' is DateTime struct
@@ -368,7 +419,10 @@ func RandProp(anlyzData interface{}) ([]uint8, error) {
 // Property data (EDT), which you wanna check validetion, designated by argument 'rData'.
 // This function return 2 value, bool and error.
 // if bool is true, EDT and EPC are valid value.
+// If not, Finding is reported.
 func (node *Node) CheckValueValidetion(inst Instance, rData VarByteGroup) (bool, error) {
+	defer node.endCheck(node.beginCheck("CheckValueValidetion", inst.ClassCode, "", ""))
+
 	var prop Property
 	epcExist := false
 	for _, prop = range inst.Props {
@@ -378,18 +432,34 @@ func (node *Node) CheckValueValidetion(inst Instance, rData VarByteGroup) (bool,
 		}
 	}
 	if !epcExist {
-		node.logger.Error("Invalid EPC", zap.String("EPC", fmt.Sprintf("0x%02X", rData.EPC)))
+		node.report(Finding{
+			RuleID:   "EPC-UNKNOWN",
+			Instance: eojString(inst.ClassCode),
+			EPC:      fmt.Sprintf("%02X", rData.EPC),
+			Message:  fmt.Sprintf("Invalid EPC: 0x%02X is not defined in %s", rData.EPC, inst.ClassName),
+		})
 		return false, xerrors.Errorf("Invalid EPC")
 	}
 	for _, edtData := range prop.Data {
 		rslt, err := elementCorrectRange(edtData, rData.EDT)
 		if err != nil {
-			node.logger.Error("Out of range", zap.String("EPC", fmt.Sprintf("0x%02X", rData.EPC)))
+			node.report(Finding{
+				RuleID:   "VALUE-UNCHECKABLE",
+				Instance: eojString(inst.ClassCode),
+				EPC:      fmt.Sprintf("%02X", rData.EPC),
+				Message:  fmt.Sprintf("Couldn't check range of %s (EDT:%X): %s", prop.PropertyName, rData.EDT, err),
+			})
 			return false, xerrors.Errorf("Failed to check data whose range is correct: %w", err)
 		} else if rslt {
 			return true, nil
 		}
 	}
+	node.report(Finding{
+		RuleID:   "VALUE-RANGE",
+		Instance: eojString(inst.ClassCode),
+		EPC:      fmt.Sprintf("%02X", rData.EPC),
+		Message:  fmt.Sprintf("Out of range: %s (EDT:%X)", prop.PropertyName, rData.EDT),
+	})
 	return false, nil
 }
 
@@ -408,6 +478,9 @@ func elementCorrectRange(varType interface{}, edt []uint8) (bool, error) {
 		return false, nil
 
 	} else if value, ok := varType.(Number); ok { // varType is Number struct
+		if size, err := getDataSize(value); err == nil && uint64(len(edt)) != size {
+			return false, nil
+		}
 		if value.enum != nil {
 			var anlyzData int64
 			for i, data := range edt {
@@ -461,6 +534,9 @@ func elementCorrectRange(varType interface{}, edt []uint8) (bool, error) {
 
 	} else if value, ok := varType.(Level); ok { // varType is Level struct
 		var anlyzData uint64
+		if len(edt) != (len(value.base)-2)/2 {
+			return false, nil
+		}
 		anlyzData = 0
 		for i := 0; i < len(edt); i++ {
 			anlyzData = anlyzData + (uint64(edt[i]) * uint64(math.Pow(0x100, float64(len(edt)-1-i))))
@@ -504,6 +580,9 @@ func elementCorrectRange(varType interface{}, edt []uint8) (bool, error) {
 						return false, xerrors.Errorf("Failed to check range or Object: %w", err)
 					}
 				} else {
+					if beforeIndex+sizeOfData > uint64(len(edt)) {
+						continue
+					}
 					check, err := elementCorrectRange(elData, edt[beforeIndex:beforeIndex+sizeOfData])
 					if err != nil {
 						return false, err
@@ -570,6 +649,9 @@ func elementCorrectRange(varType interface{}, edt []uint8) (bool, error) {
 
 	} else if value, ok := varType.(DateTime); ok { // varType is DataTime struct
 		var manth, day int
+		if int64(len(edt)) != value.size {
+			return false, nil
+		}
 		if value.size == 4 || value.size == 6 || value.size == 7 {
 			manth = 2
 			day = 3
@@ -711,45 +793,55 @@ func genBitmask(bitmap Bitmap) (uint64, error) {
 }
 
 // CheckFlowValidation check whether communication flow is valid.
-// If flow is invalid, output ERROR log.
+// If flow is invalid, Finding is reported and error is returned.
 func (node *Node) CheckFlowValidation(sent FrameFormat, recv FrameFormat) error {
+	defer node.endCheck(node.beginCheck("CheckFlowValidation", sent.DEOJ, frameHex(&sent), frameHex(&recv)))
+
 	var retError error
-	retError = node.CheckEpcExist(sent, recv)
-	errormes := xerrors.Errorf("Invalid Flow: ")
+	epcError := node.CheckEpcExist(sent, recv)
 	if sent.TID != recv.TID {
-		node.logger.Error(fmt.Sprintf("%sNot Correspond: Transaction ID", errormes))
+		retError = xerrors.Errorf("Invalid Flow: Not Correspond: Transaction ID")
+		node.report(Finding{RuleID: "FLOW-TID", Message: fmt.Sprintf("Not Correspond: Transaction ID (sent:%04X, recv:%04X)", sent.TID, recv.TID)})
 
 	} else if sent.ESV&0x0F != recv.ESV&0x0F && recv.ESV != 0x74 {
-		node.logger.Error(fmt.Sprintf("%sNot Correspond: ESV", errormes))
+		retError = xerrors.Errorf("Invalid Flow: Not Correspond: ESV")
+		node.report(Finding{RuleID: "FLOW-ESV", Message: fmt.Sprintf("Not Correspond: ESV (sent:%02X, recv:%02X)", sent.ESV, recv.ESV)})
 
-	} else if sent.SEOJ != recv.DEOJ || sent.DEOJ != recv.SEOJ {
-		node.logger.Error(fmt.Sprintf("%sNot Correspond: EOJ", errormes))
+	} else if sent.SEOJ != recv.DEOJ || !eojMatches(sent.DEOJ, recv.SEOJ) {
+		retError = xerrors.Errorf("Invalid Flow: Not Correspond: EOJ")
+		node.report(Finding{RuleID: "FLOW-EOJ", Message: fmt.Sprintf("Not Correspond: EOJ (sent DEOJ:%s, recv SEOJ:%s)", eojString(sent.DEOJ), eojString(recv.SEOJ))})
 
-	} else if (recv.OPC != uint8(len(recv.VarGroups)) && sent.ESV != 0x6E) || (recv.OPC-recv.OPCG != uint8(len(recv.VarGroups)) && sent.ESV == 0x6E) {
-		node.logger.Error(fmt.Sprintf("%sOPC and length of Property data do not match", errormes))
+	} else if recv.OPC != uint8(len(recv.VarGroups)) {
+		retError = xerrors.Errorf("Invalid Flow: OPC and length of Property data do not match")
+		node.report(Finding{RuleID: "FLOW-OPC", Message: "OPC and length of Property data do not match"})
 
 	} else if recv.ESV&0xF0 != 0x50 && recv.ESV&0xF0 != 0x70 {
-		node.logger.Error(fmt.Sprintf("%sInvalid ESV 0x%02X", errormes, recv.ESV))
+		retError = xerrors.Errorf("Invalid Flow: Invalid ESV 0x%02X", recv.ESV)
+		node.report(Finding{RuleID: "FLOW-ESV-INVALID", Message: fmt.Sprintf("Invalid ESV 0x%02X", recv.ESV)})
 
 	} else if sent.OPC < recv.OPC {
-		node.logger.Error(fmt.Sprintf("%srecieve packet OPC is too large", errormes))
+		retError = xerrors.Errorf("Invalid Flow: recieve packet OPC is too large")
+		node.report(Finding{RuleID: "FLOW-OPC-LARGE", Message: fmt.Sprintf("recieve packet OPC is too large (sent:%d, recv:%d)", sent.OPC, recv.OPC)})
 
-	} else if recv.OPC&0xF0 == 0x70 && recv.OPC != sent.OPC {
-		node.logger.Error(fmt.Sprintf("%srecieve packet OPC is too small", errormes))
+	} else if recv.ESV&0xF0 == 0x70 && recv.OPC != sent.OPC {
+		retError = xerrors.Errorf("Invalid Flow: recieve packet OPC is too small")
+		node.report(Finding{RuleID: "FLOW-OPC-SMALL", Message: fmt.Sprintf("recieve packet OPC is too small (sent:%d, recv:%d)", sent.OPC, recv.OPC)})
 
-	} else if retError != nil {
-		node.logger.Error(fmt.Sprintf("%sInvalid EPC: %s", errormes, retError))
+	} else if epcError != nil {
+		retError = xerrors.Errorf("Invalid Flow: Invalid EPC: %w", epcError)
 
 	} else if recv.ESV == 0x71 || recv.ESV == 0x7E {
 		for _, prop := range recv.VarGroups {
 			if prop.PDC != 0x00 || len(prop.EDT) > 0 {
-				node.logger.Error(fmt.Sprintf("%sEDT field has data", errormes))
+				retError = xerrors.Errorf("Invalid Flow: EDT field has data")
+				node.report(Finding{RuleID: "FLOW-EDT-NONEMPTY", EPC: fmt.Sprintf("%02X", prop.EPC), Message: fmt.Sprintf("EDT field has data (EPC:%02X)", prop.EPC)})
 			}
 		}
 	} else if recv.ESV == 0x72 {
 		for _, prop := range recv.VarGroups {
 			if prop.PDC == 0x00 || len(prop.EDT) == 0 {
-				node.logger.Error(fmt.Sprintf("%sEDT field has data", errormes))
+				retError = xerrors.Errorf("Invalid Flow: EDT field has no data")
+				node.report(Finding{RuleID: "FLOW-EDT-EMPTY", EPC: fmt.Sprintf("%02X", prop.EPC), Message: fmt.Sprintf("EDT field has no data (EPC:%02X)", prop.EPC)})
 			}
 		}
 	}
@@ -767,73 +859,56 @@ func (node *Node) CheckFlowValidation(sent FrameFormat, recv FrameFormat) error
 			return err
 		}
 	}
-	return nil
+	return retError
 }
 
 // CheckEpcExist check whether sent.EPC or recv.EPC is only one.
-// If so, output ERROR log.
+// If so, Finding is reported and error is returned.
 func (node *Node) CheckEpcExist(sent FrameFormat, recv FrameFormat) error {
-	// Check property which sent packet has is not in receive packet
-	// dataR is receive packet's data
-	// dataS is sent packet's data
-	for _, dataR := range recv.VarGroups {
-		exist := false
-		for _, dataS := range sent.VarGroups {
-			if dataR.EPC == dataS.EPC {
-				exist = true
-				break
-			}
-		}
-		if !exist {
-			node.logger.Error(fmt.Sprintf("There are strange EPC:%02X in receive packet", dataR))
-		}
-	}
-	// Check property which sent packet has is not in receive packet
-	for _, dataS := range sent.VarGroups {
-		exist := false
-		for _, dataR := range recv.VarGroups {
-			if dataR.EPC == dataS.EPC {
-				exist = true
-				break
-			}
-		}
-		if !exist {
-			node.logger.Error(fmt.Sprintf("There are no EPC:%02X in receive packet", dataS))
-		}
-	}
+	defer node.endCheck(node.beginCheck("CheckEpcExist", sent.DEOJ, frameHex(&sent), frameHex(&recv)))
 
+	var retError error
+	groups := [][2][]VarByteGroup{{sent.VarGroups, recv.VarGroups}}
 	if sent.ESV == 0x6E {
-		for _, dataR := range recv.VarGroupsG {
+		groups = append(groups, [2][]VarByteGroup{sent.VarGroupsG, recv.VarGroupsG})
+	}
+	for _, group := range groups {
+		// Check property which receive packet has is not in sent packet
+		// dataR is receive packet's data
+		// dataS is sent packet's data
+		for _, dataR := range group[1] {
 			exist := false
-			for _, dataS := range sent.VarGroupsG {
+			for _, dataS := range group[0] {
 				if dataR.EPC == dataS.EPC {
 					exist = true
 					break
 				}
 			}
 			if !exist {
-				node.logger.Error(fmt.Sprintf("There are strange EPC:%02X in receive packet", dataR))
+				retError = xerrors.Errorf("There are strange EPC:%02X in receive packet", dataR.EPC)
+				node.report(Finding{RuleID: "EPC-UNEXPECTED", EPC: fmt.Sprintf("%02X", dataR.EPC), Message: fmt.Sprintf("There are strange EPC:%02X in receive packet", dataR.EPC)})
 			}
 		}
 		// Check property which sent packet has is not in receive packet
-		for _, dataS := range sent.VarGroupsG {
+		for _, dataS := range group[0] {
 			exist := false
-			for _, dataR := range recv.VarGroupsG {
+			for _, dataR := range group[1] {
 				if dataR.EPC == dataS.EPC {
 					exist = true
 					break
 				}
 			}
 			if !exist {
-				node.logger.Error(fmt.Sprintf("There are no EPC:%02X in receive packet", dataS))
+				retError = xerrors.Errorf("There are no EPC:%02X in receive packet", dataS.EPC)
+				node.report(Finding{RuleID: "EPC-MISSING", EPC: fmt.Sprintf("%02X", dataS.EPC), Message: fmt.Sprintf("There are no EPC:%02X in receive packet", dataS.EPC)})
 			}
 		}
 	}
-	return nil
+	return retError
 }
 
 // Check check communicatoin flow, epc and data value
-// if invalid, output ERROR log
+// if invalid, Finding is reported
 func (node *Node) Check(sent *FrameFormat, recv *FrameFormat) error {
 	var inst Instance
 	exist := false
@@ -843,20 +918,45 @@ func (node *Node) Check(sent *FrameFormat, recv *FrameFormat) error {
 			break
 		}
 	}
-	if !exist {
-		node.logger.Error("Instance Code incorresponded")
-		return xerrors.Errorf("There are no instance (CODE:%02X%02X%02X)", sent.DEOJ[0], sent.DEOJ[1], sent.DEOJ[2])
+	if !exist && sent.DEOJ[2] != 0x00 {
+		// a request to a nonexistent object should be ignored or replied with SNA
+		var recvs []FrameFormat
+		if recv.EHD1 != 0 {
+			recvs = append(recvs, *recv)
+		}
+		node.checkUnknownDEOJ(sent, recvs)
+		return nil
 	}
+
+	defer node.endCheck(node.beginCheck("Check", sent.DEOJ, frameHex(sent), frameHex(recv)))
 	if recv.EHD1 == 0 {
-		node.logger.Error("Couldn't receive packet", zap.String("SentPacket", fmt.Sprintf("%+v", sent)))
-		return xerrors.Errorf("Receive packet is NULL")
+		node.report(Finding{RuleID: "RECV-NONE", Message: "Couldn't receive packet"})
+		return nil
 	}
-	node.CheckEpcExist(*sent, *recv)
 	node.CheckFlowValidation(*sent, *recv)
+	// EDT is the value of property only in response of Get, INF and the Get part of SetGet
+	values := recv.VarGroups
+	if recv.ESV == 0x7E || recv.ESV == 0x5E {
+		values = recv.VarGroupsG
+	} else if recv.ESV&0xF0 != 0x70 || recv.ESV == 0x71 {
+		return nil
+	}
 	for _, inst := range node.Instances {
 		if inst.ClassCode == recv.SEOJ {
-			for _, varGroup := range recv.VarGroups {
+			for _, varGroup := range values {
+				if varGroup.PDC == 0x00 {
+					continue
+				}
 				node.CheckValueValidetion(inst, varGroup)
+				if node.result != nil {
+					signed := false
+					for _, prop := range inst.Props {
+						if prop.EPC == varGroup.EPC {
+							signed = signedProperty(prop)
+						}
+					}
+					node.result.Observe(node.ip.String(), eojString(inst.ClassCode), varGroup.EPC, varGroup.EDT, signed)
+				}
 			}
 			break
 		}
@@ -952,116 +1052,13 @@ func (node *Node) Check(sent *FrameFormat, recv *FrameFormat) error {
 //	return nil
 //}
 
-// TODO 使われてない
-func edtFuzz(anlyzData interface{}) ([]uint8, error) {
-	var retNum []uint8
-
-	rand.Seed(time.Now().UnixNano())
-
-	if value, ok := anlyzData.([]interface{}); ok { // argument 'anlyzData' is []interface
-		randIndex := rand.Intn(len(value))
-		recNum, err := edtFuzz(value[randIndex])
-		if err != nil {
-			return retNum, err
-		}
-		for _, data := range recNum {
-			retNum = append(retNum, data)
-		}
-		return retNum, nil
-
-	} else if value, ok := anlyzData.(Number); ok { // argument 'anlyzData' is Number struct
-		if value.enum != nil {
-			var outNum int64
-			for random := true; random; {
-				outNum = int64(rand.Int() & 0xFFFFFFFF)
-				for _, enum := range value.enum {
-					if outNum == enum {
-						random = false
-						break
-					}
-				}
-				if !random {
-					random = true
-				} else {
-					random = false
-				}
-			}
-			if strings.HasSuffix(value.format, "int8") {
-				return []uint8{uint8(outNum)}, nil
-			} else if strings.HasSuffix(value.format, "int16") {
-				return []uint8{uint8(outNum >> 8), uint8(outNum) & 0xFF}, nil
-			} else if strings.HasSuffix(value.format, "int32") {
-				return []uint8{uint8(outNum >> 24), uint8(outNum>>16) & 0xFF, uint8(outNum>>8) & 0xFF, uint8(outNum) & 0xFF}, nil
-			} else {
-				return nil, xerrors.Errorf("Invalid format of Property")
-			}
-		} else if strings.HasPrefix(value.format, "uint") {
-			max := uint(value.maximum)
-			outNum := max + 1
-			if strings.HasSuffix(value.format, "int8") {
-				return []uint8{uint8(outNum)}, nil
-			} else if strings.HasSuffix(value.format, "int16") {
-				return []uint8{uint8(outNum >> 8), uint8(outNum) & 0xFF}, nil
-			} else if strings.HasSuffix(value.format, "int32") {
-				return []uint8{uint8(outNum >> 24), uint8(outNum>>16) & 0xFF, uint8(outNum>>8) & 0xFF, uint8(outNum) & 0xFF}, nil
-			} else {
-				return nil, xerrors.Errorf("Invalid format of Property")
-			}
-		} else {
-			max := int(value.maximum)
-			min := int(value.minimum)
-			var outNum int
-			if outNum > max {
-				outNum = max
-			} else if outNum < min {
-				outNum = min
-			}
-			if strings.HasSuffix(value.format, "int8") {
-				return []uint8{uint8(outNum)}, nil
-			} else if strings.HasSuffix(value.format, "int16") {
-				return []uint8{uint8(outNum >> 8), uint8(outNum) & 0xFF}, nil
-			} else if strings.HasSuffix(value.format, "int32") {
-				return []uint8{uint8(outNum >> 24), uint8(outNum>>16) & 0xFF, uint8(outNum>>8) & 0xFF, uint8(outNum) & 0xFF}, nil
-			} else {
-				return nil, xerrors.Errorf("Invalid format of Property")
-			}
-		}
-	} else if value, ok := anlyzData.(State); ok { // argument 'anlyzData' is State struct
-		var outNum int64
-		size := value.size
-		for random := true; random; {
-			outNum = int64(rand.Int() & 0xFFFFFFFF)
-			for _, enum := range value.enum {
-				if outNum == enum.edt {
-					random = false
-					break
-				}
-			}
-			if !random {
-				random = true
-			} else {
-				random = false
-			}
-		}
-		if size == 0 {
-			retNum = append(retNum, uint8(outNum))
-		} else {
-			for i := size - 1; i >= 0; i-- {
-				retNum = append(retNum, uint8(outNum>>(i*4))&0xFF)
-			}
-		}
-		return retNum, nil
-	}
-	return nil, nil
-}
-
 func (a *Auditor) Fuzz(dstIP net.IP, dstCode [3]uint8) error {
-	fmt.Println("---Start Fuzzing---")
+	a.printf("---Start Fuzzing---\n")
 	var payload FrameFormat
 	var node Node
 	var inst Instance
 	var err error
-	rand.Seed(time.Now().UnixNano())
+	r := rand.New(rand.NewSource(a.campaignSeed("Fuzz", dstIP.String(), eojString(dstCode))))
 
 	node, instIndex, err := a.searchInstane(dstIP, dstCode)
 	if err != nil {
@@ -1071,8 +1068,8 @@ func (a *Auditor) Fuzz(dstIP net.IP, dstCode [3]uint8) error {
 
 	payload.EHD1 = 0x10
 	payload.EHD2 = 0x81
-	payload.TID = uint16(rand.Int() & 0xFFFF)
-	payload.SEOJ = nodeProfileEOJ
+	payload.TID = node.client.nextTID()
+	payload.SEOJ = node.client.SEOJ
 	payload.DEOJ = inst.ClassCode
 
 	// ESV Fuzzing
@@ -1090,6 +1087,9 @@ func (a *Auditor) Fuzz(dstIP net.IP, dstCode [3]uint8) error {
 	// EPC Fuzzing
 	esvs := []uint8{0x60, 0x61, 0x62}
 	for _, esv := range esvs {
+		if err := node.canceled(); err != nil {
+			return err
+		}
 		payload.ESV = esv
 		payload.OPC = 0x01
 		payload.VarGroups = []VarByteGroup{
@@ -1098,7 +1098,7 @@ func (a *Auditor) Fuzz(dstIP net.IP, dstCode [3]uint8) error {
 			},
 		}
 		for random := true; random; {
-			payload.VarGroups[0].EPC = uint8(rand.Int() & 0xFF)
+			payload.VarGroups[0].EPC = uint8(r.Int() & 0xFF)
 			for _, epc := range inst.Props {
 				if payload.VarGroups[0].EPC == epc.EPC {
 					random = false
@@ -1113,18 +1113,14 @@ func (a *Auditor) Fuzz(dstIP net.IP, dstCode [3]uint8) error {
 
 		for _, prop := range inst.Props {
 			if prop.EPC == payload.VarGroups[0].EPC {
-				payload.VarGroups[0].EDT, err = RandProp(prop.Data)
+				payload.VarGroups[0].EDT, err = RandProp(prop.Data, r)
 				if err != nil {
 					return xerrors.Errorf("Failed to generate Rand Data (EDT:0x%02X): %w", payload.VarGroups[0].EPC, err)
 				}
 			}
 		}
 
-		err := SendEchonet(payload, node.connSend)
-		if err != nil {
-			return xerrors.Errorf("Failed to send ECHONET Lite packet at Fuzzy: %w", err)
-		}
-		recv, err := node.RecvEchonet()
+		recv, err := node.exchange(payload, node.replyTimeout())
 		if err != nil {
 			return xerrors.Errorf("Failed to recieve ECHONET Lite packet at Fuzzy: %w", err)
 		}
@@ -1192,11 +1188,7 @@ func (a *Auditor) Fuzz(dstIP net.IP, dstCode [3]uint8) error {
 		data[0].PDC = 0x01
 		data[0].EDT = []uint8{0x11}
 		payload.VarGroups = data
-		err := SendEchonet(payload, node.connSend)
-		if err != nil {
-			return xerrors.Errorf("Failed to send ECHONET Lite packet at Fuzzy: %w", err)
-		}
-		recv, err := node.RecvEchonet()
+		recv, err := node.exchange(payload, node.replyTimeout())
 		if err != nil {
 			return xerrors.Errorf("Failed to recieve ECHONET Lite packet at Fuzzy: %w", err)
 		}
@@ -293,7 +293,7 @@ func (node *Node) parseData(json []byte, rel string) ([]interface{}, error) {
 					return retData, xerrors.Errorf("Invalid object of $ref: %w", err)
 				}
 				pathDef := ref[len("#/definitions/"):]
-				valueData, _, _, err := jsonparser.Get(definition, pathDef)
+				valueData, _, _, err := jsonparser.Get(node.definitions, pathDef)
 				if err != nil {
 					return retData, xerrors.Errorf("Invalid path %s: %w", pathDef, err)
 				}
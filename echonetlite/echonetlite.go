@@ -1,12 +1,13 @@
 package echonetlite
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	"encoding/binary"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"math/rand"
 	"net"
 	"os"
 	"path/filepath"
@@ -21,27 +22,20 @@ import (
 	"golang.org/x/xerrors"
 )
 
-var sc = bufio.NewScanner(os.Stdin)
-
-func nextLine() string {
-	sc.Scan()
-	return sc.Text()
-}
-
 // Print all IP address of Nodes
 func chooseNode(a *Auditor) *Node {
-	fmt.Printf("(ECHONET Lite:Information)> Node addresses are...\n")
-	fmt.Printf("   -------------- IP address --------------\n")
+	a.printf("(ECHONET Lite:Information)> Node addresses are...\n")
+	a.printf("   -------------- IP address --------------\n")
 	for i, node := range a.DistNodes {
-		fmt.Printf("   > index[%d]    IP address:%s\n", i+1, node.ip.String())
+		a.printf("   > index[%d]    IP address:%s\n", i+1, node.ip.String())
 	}
-	fmt.Printf("   ----------------------------------------\n")
-	fmt.Printf("(ECHONET Lite:Information)> If choose node IP address %s, input '1'\n", a.DistNodes[0].ip.String())
-	fmt.Printf("(ECHONET Lite:Information)> if you wanna EXIT, input '0'\n")
-	fmt.Printf("(Input)> ")
-	index, err := strconv.ParseInt(nextLine(), 10, 64)
+	a.printf("   ----------------------------------------\n")
+	a.printf("(ECHONET Lite:Information)> If choose node IP address %s, input '1'\n", a.DistNodes[0].ip.String())
+	a.printf("(ECHONET Lite:Information)> if you wanna EXIT, input '0'\n")
+	a.printf("(Input)> ")
+	index, err := strconv.ParseInt(a.nextLine(), 10, 64)
 	if err != nil || index > int64(len(a.DistNodes)) {
-		fmt.Printf("(ECHONET Lite:Error) > Input Error\n")
+		a.printf("(ECHONET Lite:Error) > Input Error\n")
 		a.logger.Error("Input Index Error")
 		return nil
 	} else if index == 0 {
@@ -51,30 +45,108 @@ func chooseNode(a *Auditor) *Node {
 }
 
 func (a *Auditor) executorEchonet(in string) {
+	in = strings.TrimSpace(in)
+	if in != "" {
+		a.appendHistory(in)
+	}
 	if in == "" {
 	} else if in == "OPC Fuzz" {
 		var node *Node
-		var Frames [2][]FrameFormat
-		var err error
 
 		node = chooseNode(a)
 
 		if node == nil {
 			return
 		}
+		// Each packet is checked in OpcFuzz
 		for _, inst := range node.Instances {
 			node.logger.Info("Start to OPC fuzzing", zap.String("instance", inst.ClassName))
-			Frames, err = a.OpcFuzz(node.ip, inst.ClassCode)
+			_, err := a.OpcFuzz(node.ip, inst.ClassCode)
 			if err != nil {
 				return
 			}
 			node.logger.Info("Finished to OPC fuzzing", zap.String("instance", inst.ClassName))
 		}
-		node.logger.Info("Start to Check receive packet", zap.String("IPaddr", fmt.Sprintf("%s", node.ip.String())))
-		for i := 0; i < len(Frames[0]); i++ {
-			node.Check(&Frames[0][i], &Frames[1][i])
+	} else if in == "Frame Fuzz" {
+		node := chooseNode(a)
+		if node == nil {
+			return
+		}
+		for _, inst := range node.Instances {
+			err := a.FrameFuzz(node.ip, inst.ClassCode)
+			if err != nil {
+				a.printf("(ECHONET Lite:Error) > %s\n", err)
+				return
+			}
+		}
+	} else if in == "Boundary Fuzz" {
+		node := chooseNode(a)
+		if node == nil {
+			return
+		}
+		for _, inst := range node.Instances {
+			err := a.BoundaryFuzz(node.ip, inst.ClassCode)
+			if err != nil {
+				a.printf("(ECHONET Lite:Error) > %s\n", err)
+				return
+			}
+		}
+	} else if in == "Profile Fuzz" {
+		node := chooseNode(a)
+		if node == nil {
+			return
+		}
+		err := a.ProfileFuzz(node.ip)
+		if err != nil {
+			a.printf("(ECHONET Lite:Error) > %s\n", err)
+			return
+		}
+	} else if in == "Profile Check" {
+		node := chooseNode(a)
+		if node == nil {
+			return
+		}
+		err := a.ProfileCheck(node.ip)
+		if err != nil {
+			a.printf("(ECHONET Lite:Error) > %s\n", err)
+			return
+		}
+	} else if in == "Address Check" {
+		node := chooseNode(a)
+		if node == nil {
+			return
+		}
+		err := a.AddressCheck(node.ip)
+		if err != nil {
+			a.printf("(ECHONET Lite:Error) > %s\n", err)
+			return
+		}
+	} else if in == "Run Plan" {
+		a.printf("(ECHONET Lite:Information)> Input steps separated by ',' (%s). Empty means the plan in config or all\n", strings.Join(PlanSteps, ", "))
+		a.printf("(Input)> ")
+		line := a.nextLine()
+		if line == "" {
+			line = strings.Join(a.Config.Plan, ",")
+		}
+		steps, err := ParsePlan(line)
+		if err != nil {
+			a.printf("(ECHONET Lite:Error) > %s\n", err)
+			return
+		}
+		a.printf("(ECHONET Lite:Information)> Input interval between packets per node in milliseconds. Empty means 0\n")
+		a.printf("(Input)> ")
+		pace := 0
+		if line := a.nextLine(); line != "" {
+			pace, err = strconv.Atoi(line)
+			if err != nil || pace < 0 {
+				a.printf("(ECHONET Lite:Error) > Input Error\n")
+				return
+			}
+		}
+		_, err = a.RunPlan(steps, nil, time.Duration(pace)*time.Millisecond, a.Prompter)
+		if err != nil {
+			a.printf("(ECHONET Lite:Error) > %s\n", err)
 		}
-		node.logger.Info("Finished Check receive packet")
 	} else if in == "Communicate" {
 		var node *Node
 		node = chooseNode(a)
@@ -83,26 +155,46 @@ func (a *Auditor) executorEchonet(in string) {
 		}
 		err := node.Communicate()
 		if err != nil {
-			fmt.Printf("(ECHONET Lite:Error) > %s\n", err)
+			a.printf("(ECHONET Lite:Error) > %s\n", err)
 			node.logger.Error("Communication Failed", zap.String("message", fmt.Sprintf("%s", err)))
 		}
 		node.logger.Info("Finished to communicate", zap.String("IPaddr", node.ip.String()))
+	} else if in == "Report" {
+		err := a.WriteReports()
+		if err != nil {
+			a.printf("(ECHONET Lite:Error) > %s\n", err)
+		}
 	} else if in == "exit" {
-		fmt.Println("Exit tool")
-		os.Exit(0)
+		err := a.WriteReports()
+		if err != nil {
+			a.printf("(ECHONET Lite:Error) > %s\n", err)
+		}
+		a.printf("Exit tool\n")
+		a.Quit()
 		return
-	} else {
-		fmt.Println("Command not found")
+	} else if !a.executeCommand(in) {
+		a.printf("Command not found\n")
 	}
 }
 
 func (a *Auditor) completerEchonet(d prompt.Document) []prompt.Suggest {
+	if s, ok := a.completeCommand(d); ok {
+		return s
+	}
 	s := []prompt.Suggest{
 		{Text: "OPC Fuzz", Description: "Fuzzing with OPC [0:255] against Target IoT device"},
+		{Text: "Frame Fuzz", Description: "Fuzzing with mutated header, ESV, OPC and PDC fields, truncated and trailing garbage frames"},
+		{Text: "Boundary Fuzz", Description: "Set out-of-range values generated from class definitions with SetC"},
+		{Text: "Profile Fuzz", Description: "Invalid Sets, Gets of undefined EPCs and malformed instance list notifications against node profile"},
+		{Text: "Profile Check", Description: "Check properties of node profile agree with each other and their formats"},
+		{Text: "Address Check", Description: "Requests to nonexistent objects, instance code 0x00 and from unknown SEOJs"},
+		{Text: "Run Plan", Description: "Run fuzzing steps against all nodes in parallel with a progress table"},
 		{Text: "Communicate", Description: "Communicate with IoT device"},
+		{Text: "Report", Description: "Output reports of checks and findings under result directory"},
 		{Text: "exit", Description: "Exit tool"},
 		//{Text: "", Description: ""},
 	}
+	s = append(s, commandSuggests()...)
 	return prompt.FilterHasPrefix(s, d.GetWordBeforeCursor(), true)
 }
 
@@ -115,32 +207,47 @@ func (a *Auditor) AddDistNodes(dsts []net.IP) error {
 	connectionReciveECHONET, err := net.ListenUDP("udp", udpAddr)
 	if err != nil {
 		a.logger.Error("Create Receive UDP Sokcet Failed", zap.String("IPaddr", udpAddr.IP.String()), zap.String("Port", fmt.Sprintf("%d", udpAddr.Port)), zap.String("message", fmt.Sprintf("%s", err)))
+	} else if a.receiver == nil {
+		a.receiver = newReceiver(connectionReciveECHONET)
 	}
 	for _, dst := range dsts {
+		if err := a.operation().context().Err(); err != nil {
+			return xerrors.Errorf("Discovery stopped: %w", err)
+		}
 		var node Node
+		discoveryStart := time.Now()
 
-		dirNodeLog, fileNodeLog := filepath.Split("echonet/" + TimeStr + "-" + dst.String() + ".log")
-		node.logger = util.InitLogger(dirNodeLog + fileNodeLog)
+		dirNodeLog, fileNodeLog := filepath.Split("echonet/" + a.RunID + "-" + dst.String() + ".log")
+		node.logger = util.InitLoggerIn(a.logDir(), dirNodeLog+fileNodeLog)
 		if node.logger == nil {
 			return xerrors.Errorf("Create logger failed")
 		}
 		node.ip = dst
-		nodeProfileEOJ = [3]uint8{0x0E, 0xF0, 0x01}
-		node.connRecv = connectionReciveECHONET
+		conf := a.Config.Node(dst)
+		node.timeout = time.Duration(conf.Timeout) * time.Second
+		node.result = a.Result
+		node.prompter = a.Prompter
+		node.op = a.operation()
 
 		connectionSendECHONET, err := net.Dial("udp4", fmt.Sprintf("%s:3610", dst.String()))
 		if err != nil {
 			a.logger.Error("Create Send Connection Failed", zap.String("IPaddr", dst.String()))
 			return xerrors.Errorf("Couldn't connect to target device %w", err)
 		}
-		node.connSend = connectionSendECHONET
+		node.client = NewClient(dst, connectionSendECHONET, connectionReciveECHONET)
+		node.client.Timeout = node.replyTimeout()
+		node.client.logger = node.logger
+		if a.receiver != nil {
+			node.client.inbox = a.receiver.inbox(dst)
+		}
 
 		// Get Instance list of node
 		payload := FrameFormat{
 			EHD1: 0x10,
 			EHD2: 0x81,
-			SEOJ: nodeProfileEOJ,
-			DEOJ: nodeProfileEOJ,
+			TID:  node.client.nextTID(),
+			SEOJ: node.client.SEOJ,
+			DEOJ: [3]uint8{0x0E, 0xF0, 0x01},
 			ESV:  0x62,
 			OPC:  0x01,
 			VarGroups: []VarByteGroup{
@@ -151,19 +258,9 @@ func (a *Auditor) AddDistNodes(dsts []net.IP) error {
 				},
 			},
 		}
-		go func() {
-			err := SendEchonet(payload, node.connSend)
-			if err != nil {
-				payload.DEOJ = [3]uint8{0x0E, 0xF0, 0x02}
-				err = SendEchonet(payload, node.connSend)
-				if err != nil {
-					a.logger.Error("Sent packet failed")
-				}
-			}
-		}()
-		recv, err := node.RecvEchonet()
+		recv, err := node.exchange(payload, node.replyTimeout())
 		if err != nil {
-			a.logger.Error("Couldn't receive packet from Node Profile Object", zap.String("IPaddr", node.ip.String()))
+			a.logger.Error("Couldn't receive packet from Node Profile Object", zap.String("IPaddr", node.ip.String()), zap.String("message", err.Error()))
 			continue
 		} else if recv.ESV&0x70 != 0x70 {
 			a.logger.Error("Invalid data flow", zap.String("IPaddr", node.ip.String()))
@@ -171,35 +268,64 @@ func (a *Auditor) AddDistNodes(dsts []net.IP) error {
 		}
 
 		// instList: list of instance CODE
-		instList := make([][3]uint8, int(recv.VarGroups[0].EDT[0])+1, int(recv.VarGroups[0].EDT[0])+1)
-		instList[0] = payload.DEOJ
-		for i := 0; i < int(recv.VarGroups[0].EDT[0]); i++ {
-			index := (i * 3) + 1
-			instList[i+1][0] = recv.VarGroups[0].EDT[index]
-			instList[i+1][1] = recv.VarGroups[0].EDT[index+1]
-			instList[i+1][2] = recv.VarGroups[0].EDT[index+2]
+		var edt []uint8
+		if len(recv.VarGroups) > 0 {
+			edt = recv.VarGroups[0].EDT
+		}
+		listed, err := parseInstanceList(edt)
+		if err != nil {
+			a.logger.Error("Invalid instance list", zap.String("IPaddr", node.ip.String()), zap.String("message", err.Error()))
+			func() {
+				defer node.endCheck(node.beginCheck("Discovery", payload.DEOJ, frameHex(&payload), frameHex(&recv)))
+				node.report(Finding{RuleID: "NODEPROFILE-LIST-MALFORMED", EPC: "D6", Message: fmt.Sprintf("Self-node instance list S is malformed: %s", err)})
+			}()
+			continue
+		}
+		instList := append([][3]uint8{payload.DEOJ}, listed...)
+
+		// instances not tested
+		for _, skip := range conf.Skip {
+			for i := 1; i < len(instList); i++ {
+				if eojString(instList[i]) == strings.ToUpper(skip) {
+					node.logger.Info("Skip instance", zap.String("instance", skip))
+					instList = append(instList[:i], instList[i+1:]...)
+					break
+				}
+			}
 		}
-
-		// ECHONET Lite specification version
-		fmt.Printf("(ECHONET Lite:Information)> Input ECHONET Lite Version that test device use ('A' ~ 'M')\n")
-		fmt.Printf("(Input)> ")
-		release := nextLine()
 
 		// Cleate instance
-		dirJson, fileJson := filepath.Split("echonetlite/class.json")
-		json, err := ioutil.ReadFile(dirJson + fileJson)
+		json, err := ioutil.ReadFile(a.definitionsPath())
 		if err != nil {
-			return xerrors.Errorf(fmt.Sprintf("There are not %s", dirJson+fileJson))
+			return xerrors.Errorf("There are not class definitions %s: %w", a.definitionsPath(), err)
 		}
-		for _, instCODE := range instList {
-			instance, err := node.CreateObject(instCODE, release, json)
+
+		// Appendix release of objects. Release designated by flag or config overrides the detected one
+		release := a.Release
+		if release == "" {
+			release = conf.Release
+		}
+		releases := make([]string, len(instList))
+		if release == "" {
+			releases = node.detectReleases(instList, json)
+			release = releases[0]
+		} else {
+			for i := range releases {
+				releases[i] = release
+			}
+		}
+		for i, instCODE := range instList {
+			instance, err := node.CreateObject(instCODE, releases[i], json)
 			if err != nil {
 				a.logger.Error("Create Object Error", zap.String("CLASS", fmt.Sprintf("%02X%02X%02X", instCODE[0], instCODE[1], instCODE[2])))
 				return xerrors.Errorf("Failed to Create Object (CLASSCODE:%+v):%w", instCODE, err)
 			}
 			// Set property map create
 			setPropMap, err := node.GetPropMap(instCODE, 0x9E)
-			if err != nil {
+			if err != nil && isReplyError(err) {
+				// reported by GetPropMap. The instance is tested as if the map is empty
+				a.logger.Error("Invalid Set property map", zap.String("CLASS", eojString(instCODE)), zap.String("message", err.Error()))
+			} else if err != nil {
 				a.logger.Error("Get Set property map Failed", zap.String("CLASS", fmt.Sprintf("%02X%02X%02X", instCODE[0], instCODE[1], instCODE[2])))
 				return xerrors.Errorf("Failed to get Set property map (CLASSCODE:%+v): %w", instCODE, err)
 			}
@@ -213,7 +339,10 @@ func (a *Auditor) AddDistNodes(dsts []net.IP) error {
 
 			// Get property map create
 			getPropMap, err := node.GetPropMap(instCODE, 0x9F)
-			if err != nil {
+			if err != nil && isReplyError(err) {
+				// reported by GetPropMap. The instance is tested as if the map is empty
+				a.logger.Error("Invalid Get property map", zap.String("CLASS", eojString(instCODE)), zap.String("message", err.Error()))
+			} else if err != nil {
 				a.logger.Error("Get Get property map Failed", zap.String("CLASS", fmt.Sprintf("%02X%02X%02X", instCODE[0], instCODE[1], instCODE[2])))
 				return xerrors.Errorf("Failed to get Get property map (CLASSCODE:%+v): %w", instCODE, err)
 			}
@@ -226,7 +355,10 @@ func (a *Auditor) AddDistNodes(dsts []net.IP) error {
 			}
 			// Inf property map create
 			infPropMap, err := node.GetPropMap(instCODE, 0x9D)
-			if err != nil {
+			if err != nil && isReplyError(err) {
+				// reported by GetPropMap. The instance is tested as if the map is empty
+				a.logger.Error("Invalid Inf property map", zap.String("CLASS", eojString(instCODE)), zap.String("message", err.Error()))
+			} else if err != nil {
 				a.logger.Error("Get Inf property map Failed", zap.String("CLASS", fmt.Sprintf("%02X%02X%02X", instCODE[0], instCODE[1], instCODE[2])))
 				return xerrors.Errorf("Failed to get Inf property map (CLASSCODE:%+v): %w", instCODE, err)
 			}
@@ -234,13 +366,16 @@ func (a *Auditor) AddDistNodes(dsts []net.IP) error {
 				for i := 0; i < len(instance.Props); i++ {
 					if infProp == instance.Props[i].EPC {
 						instance.Props[i].ImplementInf = true
-					} else {
-						instance.Props[i].ImplementInf = false
 					}
 				}
 			}
 			node.Instances = append(node.Instances, instance)
 		}
+		if conf.Manufacturer != "" {
+			node.checkManufacturer(conf.Manufacturer)
+		}
+		a.Result.AddNode(&node, release)
+		a.Result.AddTiming("Discovery", node.ip.String(), discoveryStart)
 		a.DistNodes = append(a.DistNodes, node)
 	}
 	return nil
@@ -250,15 +385,34 @@ func (a *Auditor) AddDistNodes(dsts []net.IP) error {
 // Configure logger and Node par dst, argument 1
 func (a *Auditor) NewAuditor(dsts []net.IP) error {
 	// file name config
-	t := time.Now()
-	TimeStr = fmt.Sprint(t.Year()) + "-" + fmt.Sprint(int(t.Month())) + "-" + fmt.Sprint(t.Day()) + "-" + fmt.Sprint(t.Minute()) + "-" + fmt.Sprint(t.Second())
-	dirAuditorLog, fileAuditorLog := filepath.Split("echonet/" + TimeStr + "-" + "echonetlite.log")
-	a.logger = util.InitLogger(dirAuditorLog + fileAuditorLog)
+	if a.RunID == "" {
+		t := time.Now()
+		a.RunID = fmt.Sprint(t.Year()) + "-" + fmt.Sprint(int(t.Month())) + "-" + fmt.Sprint(t.Day()) + "-" + fmt.Sprint(t.Minute()) + "-" + fmt.Sprint(t.Second())
+	}
+	dirAuditorLog, fileAuditorLog := filepath.Split("echonet/" + a.RunID + "-" + "echonetlite.log")
+	a.logger = util.InitLoggerIn(a.logDir(), dirAuditorLog+fileAuditorLog)
 	if a.logger == nil {
 		return xerrors.Errorf("Create new Auditor failed")
 	}
 
-	a.logger.Info("Create Auditor")
+	if a.Seed == 0 {
+		a.Seed = time.Now().UnixNano()
+	}
+	if a.LivenessEvery == 0 {
+		a.LivenessEvery = defaultLivenessEvery
+	}
+	if a.LivenessRecovery == 0 {
+		a.LivenessRecovery = defaultLivenessRecovery
+	}
+	if a.MinimizeTests == 0 {
+		a.MinimizeTests = defaultMinimizeTests
+	}
+	if a.Prompter == nil {
+		a.Prompter = NewPrompter(os.Stdin, os.Stdout)
+	}
+	a.logger.Info("Create Auditor", zap.Int64("seed", a.Seed))
+	a.Result = NewResult(a.RunID)
+	a.Result.Seed = a.Seed
 
 	err := a.AddDistNodes(dsts)
 	if err != nil {
@@ -268,17 +422,153 @@ func (a *Auditor) NewAuditor(dsts []net.IP) error {
 	return nil
 }
 
-// RunEchonetPrompt execute ECHONET Lite prompt
+// WriteReports output reports of Result under result directory
+func (a *Auditor) WriteReports() error {
+	if a.Result == nil {
+		return nil
+	}
+	err := a.Result.SaveTo(a.resultDir())
+	if err != nil {
+		return xerrors.Errorf("Failed to write result JSON: %w", err)
+	}
+	a.logger.Info("Output result", zap.String("path", a.resultDir()+"/"+a.Result.RunID+"-result.json"))
+	paths, err := a.Result.WriteReports(a.resultDir())
+	for _, path := range paths {
+		a.logger.Info("Output report", zap.String("path", path))
+	}
+	return err
+}
+
+// resultDir return the directory reports and checkpoints are output
+func (a *Auditor) resultDir() string {
+	if a.ResultDir != "" {
+		return a.ResultDir
+	}
+	if a.Config.ResultDir != "" {
+		return a.Config.ResultDir
+	}
+	return "result"
+}
+
+// logDir return the directory logs are output
+func (a *Auditor) logDir() string {
+	if a.Config.LogDir == "" {
+		return "log"
+	}
+	return a.Config.LogDir
+}
+
+// definitionsPath return the path of class definitions
+func (a *Auditor) definitionsPath() string {
+	if a.Config.Definitions == "" {
+		return "echonetlite/class.json"
+	}
+	return a.Config.Definitions
+}
+
+// replyTimeout return the time waiting a reply from node
+func (node *Node) replyTimeout() time.Duration {
+	if node.timeout == 0 {
+		return defaultReplyTimeout
+	}
+	return node.timeout
+}
+
+// WriteReports output JUnit XML and HTML reports of Result under dir and return their paths
+func (r *Result) WriteReports(dir string) ([]string, error) {
+	var retPaths []string
+	var junit bytes.Buffer
+	err := r.WriteJUnit(&junit)
+	if err != nil {
+		return retPaths, xerrors.Errorf("Failed to create JUnit XML: %w", err)
+	}
+	path := dir + "/" + r.RunID + "-junit.xml"
+	err = util.WriteByteFile(path, junit.Bytes(), false)
+	if err != nil {
+		return retPaths, xerrors.Errorf("Failed to write JUnit XML: %w", err)
+	}
+	retPaths = append(retPaths, path)
+
+	var html bytes.Buffer
+	err = r.WriteHTML(&html)
+	if err != nil {
+		return retPaths, xerrors.Errorf("Failed to create HTML report: %w", err)
+	}
+	path = dir + "/" + r.RunID + "-report.html"
+	err = util.WriteByteFile(path, html.Bytes(), false)
+	if err != nil {
+		return retPaths, xerrors.Errorf("Failed to write HTML report: %w", err)
+	}
+	retPaths = append(retPaths, path)
+	return retPaths, nil
+}
+
+// RunEchonetPrompt execute ECHONET Lite prompt until exit or Ctrl-C twice.
+// Ctrl-C during a command stops it and results so far are output
 func (a *Auditor) RunEchonetPrompt() {
+	stop := a.HandleInterrupt()
+	defer stop()
 	p := prompt.New(
-		a.executorEchonet,
+		func(in string) {
+			recordCommand(a.Prompter, in)
+			a.execute(in)
+		},
 		a.completerEchonet,
 		prompt.OptionTitle("VulnApplianceScanner"),
 		prompt.OptionPrefix("(Input)> "),
+		prompt.OptionHistory(a.loadHistory()),
+		// Ctrl-C at the prompt is a key, not a signal
+		prompt.OptionAddKeyBind(prompt.KeyBind{Key: prompt.ControlC, Fn: func(*prompt.Buffer) {
+			if !a.Interrupt() {
+				a.Quit()
+				return
+			}
+			a.printf("(ECHONET Lite:Information)> Press Ctrl-C again to quit\n")
+		}}),
+		prompt.OptionSetExitCheckerOnInput(func(in string, breakline bool) bool { return a.Quitting() }),
 	)
 	p.Run()
 }
 
+// execute run a command of the prompt as a new operation. If it is interrupted, results so far are output
+func (a *Auditor) execute(in string) {
+	a.operation().begin()
+	a.executorEchonet(in)
+	a.flushInterrupted()
+}
+
+// isTruncated return whether err of parser means data ended before the length its header and PDCs declare
+func isTruncated(err error) bool {
+	return xerrors.Is(err, io.EOF) || xerrors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// parseReply parse data received from the node. Truncated data is reported as a finding
+func (node *Node) parseReply(data []byte) (*FrameFormat, error) {
+	recv, err := parser(data)
+	if err != nil {
+		node.checkTruncated(&MalformedError{Data: data, Err: err})
+	}
+	return recv, err
+}
+
+// checkTruncated report a finding if err of Client means a reply is truncated.
+// Out of any check, the finding is reported in a check of its own with the request sent
+func (node *Node) checkTruncated(err error) {
+	var malformed *MalformedError
+	if !xerrors.As(err, &malformed) || !isTruncated(malformed.Err) {
+		return
+	}
+	var eoj [3]uint8
+	sent := ""
+	if malformed.Request != nil {
+		eoj = malformed.Request.DEOJ
+		sent = frameHex(malformed.Request)
+	}
+	recv := hex.EncodeToString(malformed.Data)
+	defer node.endCheck(node.beginCheck("Receive", eoj, sent, recv))
+	node.report(Finding{RuleID: "RECV-TRUNCATED", Message: fmt.Sprintf("Reply of %d bytes is truncated: %s", len(malformed.Data), malformed.Err), Sent: sent, Recv: recv})
+}
+
 // parser parse byte to ECHONET Lite frame
 func parser(data []byte) (*FrameFormat, error) {
 	var frame FrameFormat
@@ -334,11 +624,14 @@ func parser(data []byte) (*FrameFormat, error) {
 			}
 		}
 	}
-	// OPCGet Exist
+	// OPCGet exists only in SetGet family (ESV 0x6E, 0x5E, 0x7E)
+	if frame.ESV&0x0F != 0x0E {
+		return &frame, nil
+	}
 	err = binary.Read(r, binary.BigEndian, &frame.OPCG)
 	if err == nil {
-		frame.VarGroupsG = make([]VarByteGroup, frame.OPCG, frame.OPC)
-		for i := 0; uint8(i) < uint8(frame.OPC); i++ {
+		frame.VarGroupsG = make([]VarByteGroup, frame.OPCG, frame.OPCG)
+		for i := 0; uint8(i) < uint8(frame.OPCG); i++ {
 			err = binary.Read(r, binary.BigEndian, &frame.VarGroupsG[i].EPC)
 			if err != nil {
 				return nil, xerrors.Errorf("Failed to read EPC: %w", err)
@@ -370,29 +663,69 @@ func SendEchonet(payload FrameFormat, conn net.Conn) error {
 	return nil
 }
 
-// SendEchonet receive ECHONET Lite packet
-// Return packet frame as FrameFormat
-func (a *Node) RecvEchonet() (FrameFormat, error) {
-	var retFrame FrameFormat
-	err := a.connRecv.SetDeadline(time.Now().Add(15 * time.Second))
+// SendRaw send bytes as ECHONET Lite packet even if they are not consistent frame
+func SendRaw(data []byte, conn net.Conn) error {
+	_, err := conn.Write(data)
 	if err != nil {
-		return retFrame, xerrors.Errorf("Setting Timeout Error: %w", err)
+		return xerrors.Errorf("Failed to send packet: %w", err)
 	}
+	return nil
+}
 
-	buffer := make([]byte, 4096)
-	length, _, err := a.connRecv.ReadFromUDP(buffer)
+// exchange send payload and receive the reply whose TID is the same within timeout.
+// Requests and notifications from the device meanwhile are skipped
+func (node *Node) exchange(payload FrameFormat, timeout time.Duration) (FrameFormat, error) {
+	ctx, cancel := context.WithTimeout(node.context(), timeout)
+	defer cancel()
+	recv, err := node.client.Exchange(ctx, payload)
 	if err != nil {
-		return retFrame, xerrors.Errorf("Failed to recieve ECHONET Lite packet: %w", err)
+		node.checkTruncated(err)
+		return recv, xerrors.Errorf("Failed to exchange with %s: %w", node.ip, err)
 	}
+	return recv, nil
+}
 
-	recv, err := parser(buffer[:length])
-	if err != nil {
-		return *recv, xerrors.Errorf("Failed to parse recieved ECHONET Lite packet: %w", err)
-	} else if recv.EHD1 != 0x10 || recv.EHD2&0x80 != 0x80 {
-		return *recv, xerrors.Errorf("Target device doesn't have ECHONET Lite Service: %w", err)
-	}
+// recvRaw receive a packet within timeout and return it as bytes
+func (a *Node) recvRaw(timeout time.Duration) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(a.context(), timeout)
+	defer cancel()
+	return a.client.receive(ctx)
+}
 
-	return *recv, nil
+// recvReplyTo receive the reply to a raw packet data within timeout and return it as bytes.
+// Notifications and requests from the node are skipped. The reply is correlated by TID of data,
+// and a reply whose TID differs is returned only if the one with the TID doesn't arrive.
+// A packet which can't be parsed is returned as the reply
+func (node *Node) recvReplyTo(data []byte, timeout time.Duration) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(node.context(), timeout)
+	defer cancel()
+	var other []byte
+	for {
+		reply, err := node.client.receive(ctx)
+		if err != nil && other != nil && xerrors.Is(err, ErrTimeout) {
+			return other, nil
+		} else if err != nil {
+			return nil, err
+		}
+		recv, err := parser(reply)
+		if err != nil {
+			return reply, nil
+		}
+		if recv.ESV == 0x73 || (recv.ESV&0xF0 != 0x50 && recv.ESV&0xF0 != 0x70) {
+			if !node.client.notify(recv) {
+				node.logger.Info("Skip packet", zap.String("payload", hex.EncodeToString(reply)))
+			}
+			continue
+		}
+		// a truncated packet has no TID to be correlated
+		if len(data) < 4 || recv.TID == uint16(data[2])<<8|uint16(data[3]) {
+			return reply, nil
+		}
+		node.logger.Info("Skip packet", zap.String("payload", hex.EncodeToString(reply)))
+		if other == nil {
+			other = reply
+		}
+	}
 }
 
 // change FrameFormat to []byte
@@ -413,17 +746,28 @@ func echonetToByte(echoFrame FrameFormat) []byte {
 
 	payloadBytes = append(payloadBytes, echoFrame.ESV)
 	payloadBytes = append(payloadBytes, echoFrame.OPC)
-	for _, varGroup := range echoFrame.VarGroups {
-		payloadBytes = append(payloadBytes, varGroup.EPC)
-		payloadBytes = append(payloadBytes, varGroup.PDC)
+	payloadBytes = appendGroups(payloadBytes, echoFrame.VarGroups)
+	// OPCGet and its properties follow in SetGet family (ESV 0x6E, 0x5E, 0x7E)
+	if echoFrame.ESV&0x0F == 0x0E {
+		payloadBytes = append(payloadBytes, echoFrame.OPCG)
+		payloadBytes = appendGroups(payloadBytes, echoFrame.VarGroupsG)
+	}
+
+	return payloadBytes
+}
+
+// appendGroups append EPC, PDC and EDT of groups to data
+func appendGroups(data []byte, groups []VarByteGroup) []byte {
+	for _, varGroup := range groups {
+		data = append(data, varGroup.EPC)
+		data = append(data, varGroup.PDC)
 		if varGroup.PDC != 0x00 {
 			for _, edt := range varGroup.EDT {
-				payloadBytes = append(payloadBytes, edt)
+				data = append(data, edt)
 			}
 		}
 	}
-
-	return payloadBytes
+	return data
 }
 
 // CreateObject create the struct, Instance whose object code is objectCode(argument 1).
@@ -437,12 +781,13 @@ func (node *Node) CreateObject(objectCode [3]uint8, release string, json []byte)
 
 	node.logger.Info("Create object", zap.String("CLASS", fmt.Sprintf("%02X%02X%02X", objectCode[0], objectCode[1], objectCode[2])))
 
-	definition, _, _, err = jsonparser.Get(json, "definitions")
+	node.definitions, _, _, err = jsonparser.Get(json, "definitions")
 	if err != nil {
 		return retInstance, xerrors.Errorf("Invalid json data: %w", err)
 	}
 
 	retInstance.ClassCode = objectCode
+	retInstance.release = release
 	classCode := fmt.Sprintf("0x%02X%02X", objectCode[0], objectCode[1])
 	//check exists of class
 	_, _, _, err = jsonparser.Get(json, "devices", classCode)
@@ -533,7 +878,15 @@ func (node *Node) CreateObject(objectCode [3]uint8, release string, json []byte)
 // parser property map EDT into Properties []uint8
 func parsePropMap(propertyMapEDT []uint8) ([]uint8, error) {
 	var retProp []uint8
+	if len(propertyMapEDT) == 0 {
+		return nil, xerrors.Errorf("Property map is empty")
+	}
 	length := propertyMapEDT[0]
+	if length < 16 && len(propertyMapEDT) < int(length)+1 {
+		return nil, xerrors.Errorf("Property map of %d properties has only %d bytes", length, len(propertyMapEDT))
+	} else if length >= 16 && len(propertyMapEDT) < 17 {
+		return nil, xerrors.Errorf("Property map of %d properties has only %d bytes, want 17", length, len(propertyMapEDT))
+	}
 	if length < 16 {
 		for i := 1; i < int(length)+1; i++ {
 			retProp = append(retProp, propertyMapEDT[i])
@@ -551,7 +904,7 @@ func parsePropMap(propertyMapEDT []uint8) ([]uint8, error) {
 		}
 	}
 	if len(retProp) != int(length) {
-		return retProp, xerrors.Errorf("Invalid property map")
+		return retProp, xerrors.Errorf("Property map has %d properties, but its count is %d", len(retProp), length)
 	}
 	return retProp, nil
 }
@@ -564,15 +917,15 @@ func (node *Node) PrintInfo(data interface{}, inputMode bool) ([]uint8, error) {
 
 	if value, ok := data.(Property); ok {
 		if len(value.Data) > 1 {
-			fmt.Printf("(ECHONET Lite:Information) > Property '%s' has multiple type of Data\n", value.PropertyName)
+			node.printf("(ECHONET Lite:Information) > Property '%s' has multiple type of Data\n", value.PropertyName)
 			for i, propertyData := range value.Data {
-				fmt.Printf("(ECHONET Lite:Information) > Type %d\n", i)
+				node.printf("(ECHONET Lite:Information) > Type %d\n", i+1)
 				node.PrintInfo(propertyData, false)
 			}
 			if inputMode {
-				fmt.Printf("(ECHONET Lite:Information) > Choose type\n")
-				fmt.Printf("(Input) > ")
-				buf := nextLine()
+				node.printf("(ECHONET Lite:Information) > Choose type\n")
+				node.printf("(Input) > ")
+				buf := node.nextLine()
 				index, err := strconv.ParseInt(buf, 10, 64)
 				if err != nil {
 					node.logger.Error("Input number is invalid")
@@ -581,7 +934,7 @@ func (node *Node) PrintInfo(data interface{}, inputMode bool) ([]uint8, error) {
 					node.logger.Error("Input number is invalid")
 					return nil, xerrors.Errorf("Input number is overflow or underflow")
 				}
-				recvdata, err := node.PrintInfo(value.Data[index], true)
+				recvdata, err := node.PrintInfo(value.Data[index-1], true)
 				if err != nil {
 					node.logger.Error("Couldn't print information of data")
 					return nil, xerrors.Errorf("Couldn't print information of data: %w", err)
@@ -604,15 +957,15 @@ func (node *Node) PrintInfo(data interface{}, inputMode bool) ([]uint8, error) {
 		}
 	} else if value, ok := data.([]interface{}); ok {
 		if len(value) > 1 {
-			fmt.Printf("(ECHONET Lite:Information) > There are Multiple Type")
+			node.printf("(ECHONET Lite:Information) > There are Multiple Type")
 			for i, multiType := range value {
-				fmt.Printf("(ECHONET Lite:Information) > Type %d\n", i)
+				node.printf("(ECHONET Lite:Information) > Type %d\n", i)
 				node.PrintInfo(multiType, false)
 			}
 			if inputMode {
-				fmt.Printf("(ECHONET Lite:Information) > Choose type N\n")
-				fmt.Printf("(Input) > ")
-				buf := nextLine()
+				node.printf("(ECHONET Lite:Information) > Choose type N\n")
+				node.printf("(Input) > ")
+				buf := node.nextLine()
 				index, err := strconv.ParseInt(buf, 10, 64)
 				if err != nil {
 					node.logger.Error("Input number is invalid")
@@ -644,19 +997,19 @@ func (node *Node) PrintInfo(data interface{}, inputMode bool) ([]uint8, error) {
 		}
 	} else if value, ok := data.(Number); ok {
 		var retNum int64
-		fmt.Printf("--- NUMBER ---\n")
-		fmt.Printf("> Format: %s\n", value.format)
+		node.printf("--- NUMBER ---\n")
+		node.printf("> Format: %s\n", value.format)
 		// enum type
 		if len(value.enum) > 0 {
-			fmt.Printf("> Number is below\n")
+			node.printf("> Number is below\n")
 			for _, enum := range value.enum {
-				fmt.Printf("%02X ", enum)
+				node.printf("%02X ", enum)
 			}
-			fmt.Printf("--------------\n")
+			node.printf("--------------\n")
 			if inputMode {
-				fmt.Printf("(ECHONET Lite:Information) > Input Hex Number\n")
-				fmt.Printf("(Input) > ")
-				buf := "0x" + nextLine()
+				node.printf("(ECHONET Lite:Information) > Input Hex Number\n")
+				node.printf("(Input) > ")
+				buf := "0x" + node.nextLine()
 				hexNum, err := strconv.ParseInt(buf, 0, 64)
 				if err != nil {
 					node.logger.Error("Input number is invalid")
@@ -679,16 +1032,16 @@ func (node *Node) PrintInfo(data interface{}, inputMode bool) ([]uint8, error) {
 			}
 			return retData, nil
 		} else {
-			fmt.Printf("> Minimum: %d, Maximum: %d\n", value.minimum, value.maximum)
-			fmt.Printf("> Unit: %s\n", value.unit)
+			node.printf("> Minimum: %d, Maximum: %d\n", value.minimum, value.maximum)
+			node.printf("> Unit: %s\n", value.unit)
 			if value.multipleOf != 0 {
-				fmt.Printf("> Multiple: %f\n", value.multipleOf)
+				node.printf("> Multiple: %f\n", value.multipleOf)
 			}
-			fmt.Printf("--------------\n")
+			node.printf("--------------\n")
 			if inputMode {
-				fmt.Printf("(ECHONET Lite:Information) > Input Number\n")
-				fmt.Printf("(Input) > ")
-				buf := nextLine()
+				node.printf("(ECHONET Lite:Information) > Input Number\n")
+				node.printf("(Input) > ")
+				buf := node.nextLine()
 				retNum, err := strconv.ParseInt(buf, 10, 64)
 				if err != nil {
 					node.logger.Error("Input number is invalid")
@@ -707,15 +1060,15 @@ func (node *Node) PrintInfo(data interface{}, inputMode bool) ([]uint8, error) {
 			return retData, nil
 		}
 	} else if value, ok := data.(State); ok {
-		fmt.Printf("--- STATE ---\n")
+		node.printf("--- STATE ---\n")
 		for _, enum := range value.enum {
-			fmt.Printf("> 0x%X means %s\n", enum.edt, enum.state)
+			node.printf("> 0x%X means %s\n", enum.edt, enum.state)
 		}
-		fmt.Printf("-------------\n")
+		node.printf("-------------\n")
 		if inputMode {
-			fmt.Printf("(ECHONET Lite:Information) > Input EDT Hex Number\n")
-			fmt.Printf("(Input) > ")
-			buf := "0x" + nextLine()
+			node.printf("(ECHONET Lite:Information) > Input EDT Hex Number\n")
+			node.printf("(Input) > ")
+			buf := "0x" + node.nextLine()
 			hexNum, err := strconv.ParseInt(buf, 0, 64)
 			if err != nil {
 				node.logger.Error("Input number is invalid")
@@ -736,15 +1089,15 @@ func (node *Node) PrintInfo(data interface{}, inputMode bool) ([]uint8, error) {
 		}
 		return nil, nil
 	} else if value, ok := data.(Level); ok {
-		fmt.Printf("--- LEVEL ---\n")
-		fmt.Printf("> Base: %s\n", value.base)
-		fmt.Printf("> Maximum: %s+%02X\n", value.base, value.maximum)
-		fmt.Printf("-------------\n")
+		node.printf("--- LEVEL ---\n")
+		node.printf("> Base: %s\n", value.base)
+		node.printf("> Maximum: %s+%02X\n", value.base, value.maximum)
+		node.printf("-------------\n")
 		if inputMode {
-			fmt.Printf("(ECHONET Lite:Information) > Input Hex Number\n")
-			fmt.Printf("(ECHONET Lite:Information) > e.g. %s\n", value.base[2:])
-			fmt.Printf("(Input) > ")
-			buf := nextLine()
+			node.printf("(ECHONET Lite:Information) > Input Hex Number\n")
+			node.printf("(ECHONET Lite:Information) > e.g. %s\n", value.base[2:])
+			node.printf("(Input) > ")
+			buf := node.nextLine()
 			num, err := strconv.ParseInt(buf, 10, 64)
 			if err != nil {
 				node.logger.Error("Input number is invalid")
@@ -755,13 +1108,13 @@ func (node *Node) PrintInfo(data interface{}, inputMode bool) ([]uint8, error) {
 		}
 		return nil, nil
 	} else if value, ok := data.(Raw); ok {
-		fmt.Printf("--- RAW ---\n")
-		fmt.Printf("> Minimum Length:%02X Byte\n> Maximum Length:%02X Byte\n", value.minSize, value.maxSize)
-		fmt.Printf("-----------\n")
+		node.printf("--- RAW ---\n")
+		node.printf("> Minimum Length:%02X Byte\n> Maximum Length:%02X Byte\n", value.minSize, value.maxSize)
+		node.printf("-----------\n")
 		if inputMode {
-			fmt.Printf("(ECHONET Lite:Information) > Input Size\n")
-			fmt.Printf("(Input) > ")
-			buf := nextLine()
+			node.printf("(ECHONET Lite:Information) > Input Size\n")
+			node.printf("(Input) > ")
+			buf := node.nextLine()
 			num, err := strconv.ParseInt(buf, 10, 64)
 			if err != nil {
 				node.logger.Error("Input number is invalid")
@@ -769,10 +1122,10 @@ func (node *Node) PrintInfo(data interface{}, inputMode bool) ([]uint8, error) {
 			}
 			retData = make([]uint8, num, num)
 			for i := 0; int64(i) < num; i++ {
-				fmt.Printf("(ECHONET Lite:Information) > Input data at %d Byte\n", i+1)
-				fmt.Printf("(ECHONET Lite:Information) > e.g. 8F\n")
-				fmt.Printf("(Input) > ")
-				byteData, err := strconv.ParseInt(nextLine(), 16, 64)
+				node.printf("(ECHONET Lite:Information) > Input data at %d Byte\n", i+1)
+				node.printf("(ECHONET Lite:Information) > e.g. 8F\n")
+				node.printf("(Input) > ")
+				byteData, err := strconv.ParseInt(node.nextLine(), 16, 64)
 				if err != nil {
 					node.logger.Error("Input number is invalid")
 					return nil, xerrors.Errorf("Input number is invalid. Please input HEX number: %w", err)
@@ -783,15 +1136,15 @@ func (node *Node) PrintInfo(data interface{}, inputMode bool) ([]uint8, error) {
 		}
 		return nil, nil
 	} else if value, ok := data.(Object); ok {
-		fmt.Printf("--- OBJECT ---\n")
-		fmt.Printf("(ECHONET Lite:Information) > Data type OBJECT\n")
-		fmt.Printf("(ECHONET Lite:Information) > Element Length is %d", len(value.element))
-		fmt.Printf("> ")
+		node.printf("--- OBJECT ---\n")
+		node.printf("(ECHONET Lite:Information) > Data type OBJECT\n")
+		node.printf("(ECHONET Lite:Information) > Element Length is %d", len(value.element))
+		node.printf("> ")
 		for _, el := range value.element {
-			fmt.Printf("%s ", el.name)
+			node.printf("%s ", el.name)
 		}
 		for i, el := range value.element {
-			fmt.Printf("(ECHONET Lite:Information) > Element %d: %s\n", i, el.name)
+			node.printf("(ECHONET Lite:Information) > Element %d: %s\n", i, el.name)
 			recv, err := node.PrintInfo(el.data, inputMode)
 			if err != nil {
 				node.logger.Error("Couldn't print information of data")
@@ -806,14 +1159,14 @@ func (node *Node) PrintInfo(data interface{}, inputMode bool) ([]uint8, error) {
 		return retData, nil
 
 	} else if value, ok := data.(Array); ok {
-		fmt.Printf("--- ARRAY ---\n")
-		fmt.Printf("> Each size is %d Byte", value.itemSize)
-		fmt.Printf("> MinimumItems: %d, MaximumItems: %d\n", value.minItems, value.maxItems)
-		fmt.Printf("-------------\n")
+		node.printf("--- ARRAY ---\n")
+		node.printf("> Each size is %d Byte", value.itemSize)
+		node.printf("> MinimumItems: %d, MaximumItems: %d\n", value.minItems, value.maxItems)
+		node.printf("-------------\n")
 		if inputMode {
-			fmt.Printf("(ECHONET Lite:Information) > Input Array Index Size\n")
-			fmt.Printf("(Input) > ")
-			buf := nextLine()
+			node.printf("(ECHONET Lite:Information) > Input Array Index Size\n")
+			node.printf("(Input) > ")
+			buf := node.nextLine()
 			indexNum, err := strconv.ParseInt(buf, 10, 64)
 			if err != nil {
 				node.logger.Error("Input number is invalid")
@@ -834,23 +1187,23 @@ func (node *Node) PrintInfo(data interface{}, inputMode bool) ([]uint8, error) {
 		return nil, nil
 	} else if value, ok := data.(Bitmap); ok {
 		retData = make([]uint8, value.size, value.size)
-		fmt.Printf("--- BITMAP ---\n")
-		fmt.Printf("(ECHONET Lite:Information) > Size is %d Byte\n", value.size)
+		node.printf("--- BITMAP ---\n")
+		node.printf("(ECHONET Lite:Information) > Size is %d Byte\n", value.size)
 		for i, bitmap := range value.bitmaps {
-			fmt.Printf("--- %d ---\n", i)
+			node.printf("--- %d ---\n", i)
 			recv, err := node.PrintInfo(bitmap, inputMode)
 			if err != nil {
 				node.logger.Error("Couldn't print information of data")
 				return nil, xerrors.Errorf("Couldn't print information of data: %w", err)
 			}
-			fmt.Printf("---------\n")
+			node.printf("---------\n")
 			if inputMode {
 				retData[bitmap.index-1] = retData[bitmap.index-1] | recv[0]
 			}
 		}
 		return retData, nil
 	} else if value, ok := data.(ElBitmap); ok {
-		fmt.Printf("(ECHONET Lite:Information) > Name %s\n", value.descriptions)
+		node.printf("(ECHONET Lite:Information) > Name %s\n", value.descriptions)
 		recv, err := node.PrintInfo(value.value, inputMode)
 		if err != nil {
 			node.logger.Error("Couldn't print information of data")
@@ -874,18 +1227,18 @@ func (node *Node) PrintInfo(data interface{}, inputMode bool) ([]uint8, error) {
 		}
 		return nil, nil
 	} else if value, ok := data.(NumericValues); ok {
-		fmt.Printf("--- NumericValue ---\n")
-		fmt.Printf("(ECHONET Lite:Information) > Size is %d byte \n", value.size)
-		fmt.Printf("--- Numbers ---\n")
+		node.printf("--- NumericValue ---\n")
+		node.printf("(ECHONET Lite:Information) > Size is %d byte \n", value.size)
+		node.printf("--- Numbers ---\n")
 		for _, nNumber := range value.enum {
-			fmt.Printf("> %02X\n is mean %f", nNumber.edt, nNumber.value)
+			node.printf("> %02X\n is mean %f", nNumber.edt, nNumber.value)
 		}
-		fmt.Printf("---------------\n")
+		node.printf("---------------\n")
 		if inputMode {
-			fmt.Printf("(ECHONET Lite:Information) > Input Hex Number\n")
-			fmt.Printf("(ECHONET Lite:Information) > e.g. %02X\n", value.enum[0].edt)
-			fmt.Printf("(Input) > ")
-			buf := "0x" + nextLine()
+			node.printf("(ECHONET Lite:Information) > Input Hex Number\n")
+			node.printf("(ECHONET Lite:Information) > e.g. %02X\n", value.enum[0].edt)
+			node.printf("(Input) > ")
+			buf := "0x" + node.nextLine()
 			hexNum, err := strconv.ParseInt(buf, 0, 64)
 			if err != nil {
 				node.logger.Error("Input number is invalid")
@@ -895,25 +1248,25 @@ func (node *Node) PrintInfo(data interface{}, inputMode bool) ([]uint8, error) {
 		}
 		return nil, nil
 	} else if value, ok := data.(DateTime); ok {
-		fmt.Printf("--- Time ---\n")
+		node.printf("--- Time ---\n")
 		switch value.size {
 		case 2:
-			fmt.Printf("(ECHONET Lite:Information) > Manth:Day\n")
+			node.printf("(ECHONET Lite:Information) > Manth:Day\n")
 		case 3:
-			fmt.Printf("(ECHONET Lite:Information) > Hour:Minute:Second\n")
+			node.printf("(ECHONET Lite:Information) > Hour:Minute:Second\n")
 		case 4:
-			fmt.Printf("(ECHONET Lite:Information) > Year:Manth:Day\n")
+			node.printf("(ECHONET Lite:Information) > Year:Manth:Day\n")
 		case 6:
-			fmt.Printf("(ECHONET Lite:Information) > Year:Manth:Day:Hour:Minute\n")
+			node.printf("(ECHONET Lite:Information) > Year:Manth:Day:Hour:Minute\n")
 		case 7:
-			fmt.Printf("(ECHONET Lite:Information) > Year:Manth:Day:Hour:Minute:Second\n")
+			node.printf("(ECHONET Lite:Information) > Year:Manth:Day:Hour:Minute:Second\n")
 		}
-		fmt.Printf("------------\n")
+		node.printf("------------\n")
 		if inputMode {
 			if value.size == 4 || value.size == 6 || value.size == 7 {
-				fmt.Printf("(ECHONET Lite:Information) > Input Year\n")
-				fmt.Printf("(Input) > ")
-				buf := nextLine()
+				node.printf("(ECHONET Lite:Information) > Input Year\n")
+				node.printf("(Input) > ")
+				buf := node.nextLine()
 				num, err := strconv.ParseInt(buf, 10, 64)
 				if err != nil {
 					node.logger.Error("Input number is invalid")
@@ -923,9 +1276,9 @@ func (node *Node) PrintInfo(data interface{}, inputMode bool) ([]uint8, error) {
 				retData = append(retData, uint8((num>>8)&0xFF))
 			}
 			if value.size != 3 {
-				fmt.Printf("(ECHONET Lite:Information) > Input Month\n")
-				fmt.Printf("(Input) > ")
-				buf := nextLine()
+				node.printf("(ECHONET Lite:Information) > Input Month\n")
+				node.printf("(Input) > ")
+				buf := node.nextLine()
 				num, err := strconv.ParseInt(buf, 10, 64)
 				if err != nil {
 					node.logger.Error("Input number is invalid")
@@ -933,9 +1286,9 @@ func (node *Node) PrintInfo(data interface{}, inputMode bool) ([]uint8, error) {
 				}
 				retData = append(retData, uint8(num))
 
-				fmt.Printf("(ECHONET Lite:Information) > Input Day\n")
-				fmt.Printf("(Input) > ")
-				buf = nextLine()
+				node.printf("(ECHONET Lite:Information) > Input Day\n")
+				node.printf("(Input) > ")
+				buf = node.nextLine()
 				num, err = strconv.ParseInt(buf, 10, 64)
 				if err != nil {
 					node.logger.Error("Input number is invalid")
@@ -944,9 +1297,9 @@ func (node *Node) PrintInfo(data interface{}, inputMode bool) ([]uint8, error) {
 				retData = append(retData, uint8(num))
 			}
 			if value.size == 3 || value.size == 6 || value.size == 7 {
-				fmt.Printf("(ECHONET Lite:Information) > Input Hour\n")
-				fmt.Printf("(Input) > ")
-				buf := nextLine()
+				node.printf("(ECHONET Lite:Information) > Input Hour\n")
+				node.printf("(Input) > ")
+				buf := node.nextLine()
 				num, err := strconv.ParseInt(buf, 10, 64)
 				if err != nil {
 					node.logger.Error("Input number is invalid")
@@ -954,9 +1307,9 @@ func (node *Node) PrintInfo(data interface{}, inputMode bool) ([]uint8, error) {
 				}
 				retData = append(retData, uint8(num))
 
-				fmt.Printf("(ECHONET Lite:Information) > Input Munute\n")
-				fmt.Printf("(Input) > ")
-				buf = nextLine()
+				node.printf("(ECHONET Lite:Information) > Input Munute\n")
+				node.printf("(Input) > ")
+				buf = node.nextLine()
 				num, err = strconv.ParseInt(buf, 10, 64)
 				if err != nil {
 					node.logger.Error("Input number is invalid")
@@ -965,9 +1318,9 @@ func (node *Node) PrintInfo(data interface{}, inputMode bool) ([]uint8, error) {
 				retData = append(retData, uint8(num))
 			}
 			if value.size == 3 || value.size == 7 {
-				fmt.Printf("(ECHONET Lite:Information) > Input Second\n")
-				fmt.Printf("(Input) > ")
-				buf := nextLine()
+				node.printf("(ECHONET Lite:Information) > Input Second\n")
+				node.printf("(Input) > ")
+				buf := node.nextLine()
 				num, err := strconv.ParseInt(buf, 10, 64)
 				if err != nil {
 					node.logger.Error("Input number is invalid")
@@ -989,7 +1342,8 @@ func (node *Node) GetPropMap(classCode [3]uint8, mapEpc uint8) ([]uint8, error)
 	payload := FrameFormat{
 		EHD1: 0x10,
 		EHD2: 0x81,
-		SEOJ: nodeProfileEOJ,
+		TID:  node.client.nextTID(),
+		SEOJ: node.client.SEOJ,
 		DEOJ: classCode,
 		ESV:  0x62,
 		OPC:  0x01,
@@ -1002,21 +1356,39 @@ func (node *Node) GetPropMap(classCode [3]uint8, mapEpc uint8) ([]uint8, error)
 		},
 	}
 
-	go func() {
-		SendEchonet(payload, node.connSend)
-	}()
-	recvFrame, err := node.RecvEchonet()
+	recvFrame, err := node.exchange(payload, node.replyTimeout())
 	if err != nil {
 		node.logger.Error("Couldn't receive the packet")
 		return nil, xerrors.Errorf("Couldn't receive the packet: %w", err)
 	}
+	report := func(message string) {
+		defer node.endCheck(node.beginCheck("Discovery", classCode, frameHex(&payload), frameHex(&recvFrame)))
+		node.report(Finding{RuleID: "NODEPROFILE-PROPMAP-MALFORMED", EPC: fmt.Sprintf("%02X", mapEpc), Message: message})
+	}
+	if recvFrame.ESV&0xF0 == 0x50 {
+		report(fmt.Sprintf("Get of property map %02X is not accepted (ESV %02X)", mapEpc, recvFrame.ESV))
+		return nil, xerrors.Errorf("Get of property map %02X of %s is not accepted: %w", mapEpc, eojString(classCode), &SNAError{Response: &Response{Frame: recvFrame, Properties: recvFrame.VarGroups}})
+	}
+	if recvFrame.ESV != 0x72 || len(recvFrame.VarGroups) == 0 || recvFrame.VarGroups[0].EPC != mapEpc {
+		report(fmt.Sprintf("Property map %02X is not replied to Get (ESV %02X, OPC %d)", mapEpc, recvFrame.ESV, recvFrame.OPC))
+		return nil, xerrors.Errorf("Property map %02X of %s is not replied: %w", mapEpc, eojString(classCode), &MalformedError{Data: echonetToByte(recvFrame), Err: xerrors.Errorf("EPC %02X is missing", mapEpc)})
+	}
 	epcs, err := parsePropMap(recvFrame.VarGroups[0].EDT)
 	if err != nil {
-		node.logger.Error("Parse property map Failed")
+		node.logger.Error("Parse property map Failed", zap.String("message", err.Error()))
+		report(fmt.Sprintf("Property map %02X is malformed: %s", mapEpc, err))
+		return nil, xerrors.Errorf("Invalid property map %02X of %s: %w", mapEpc, eojString(classCode), &MalformedError{Data: recvFrame.VarGroups[0].EDT, Err: err})
 	}
 	return epcs, nil
 }
 
+// isReplyError return whether err is caused by a reply of the device, SNA or malformed, rather than by the tester or the network
+func isReplyError(err error) bool {
+	var sna *SNAError
+	var malformed *MalformedError
+	return xerrors.As(err, &sna) || xerrors.As(err, &malformed)
+}
+
 // Communicate communicate with target PC in ECHONET Lite
 // Nomal Mode: Create and send ECHOENT Lite packet base on supecification
 // Test Mode: Create Any packet
@@ -1027,16 +1399,19 @@ func (node *Node) Communicate() error {
 	var communicateInstance Instance
 
 	testMode := false
-	rand.Seed(time.Now().UnixNano())
 
-	fmt.Printf("\n(ECHONET Lite:Information) > Test Mode:0   Normal Mode:1\n")
+	node.printf("\n(ECHONET Lite:Information) > Test Mode:0   Normal Mode:1\n")
 	for {
-		fmt.Printf("(Input) > ")
-		testNum, err := strconv.ParseInt(nextLine(), 10, 64)
+		node.printf("(Input) > ")
+		line, err := answer(node.prompter)
+		if err != nil { // no more answers
+			return nil
+		}
+		testNum, err := strconv.ParseInt(line, 10, 64)
 		if err != nil { // ParseInt error
-			fmt.Printf("(ECHONET Lite:Error) > Invalid Number\n")
+			node.printf("(ECHONET Lite:Error) > Invalid Number\n")
 		} else if testNum > 1 || testNum < 0 { // testNum is not 1 nor 0
-			fmt.Printf("(ECHONET Lite:Error) > Out of range\n")
+			node.printf("(ECHONET Lite:Error) > Out of range\n")
 		} else {
 			if testNum == 0 {
 				testMode = true
@@ -1049,27 +1424,31 @@ func (node *Node) Communicate() error {
 		payload := FrameFormat{
 			EHD1: 0x10,
 			EHD2: 0x81,
-			TID:  uint16(rand.Int()),
-			SEOJ: nodeProfileEOJ,
+			TID:  node.client.nextTID(),
+			SEOJ: node.client.SEOJ,
 		}
 
 		// choose instance you communicate. designate by Object CODE
-		fmt.Println("\n\n(ECHONET Lite:Information) > Input ECHONET Lite Object CODE you wanna communicate with ECHONET Lite")
-		fmt.Printf("   --------- Instances ---------\n")
+		node.printf("\n\n(ECHONET Lite:Information) > Input ECHONET Lite Object CODE you wanna communicate with ECHONET Lite\n")
+		node.printf("   --------- Instances ---------\n")
 		for i, inst := range node.Instances {
-			fmt.Printf("   > index[%d] CODE:0x%02X%02X%02X, Name:%s\n", i+1, inst.ClassCode[0], inst.ClassCode[1], inst.ClassCode[2], inst.ClassName)
+			node.printf("   > index[%d] CODE:0x%02X%02X%02X, Name:%s\n", i+1, inst.ClassCode[0], inst.ClassCode[1], inst.ClassCode[2], inst.ClassName)
 		}
-		fmt.Printf("   -----------------------------\n")
-		fmt.Printf("(ECHONET Lite:Information) > If you wanna communicate %s, you should Input index '1'\n", node.Instances[0].ClassName)
-		fmt.Println("(ECHONET Lite:Information) > If you wanna EXIT, enter '0'")
+		node.printf("   -----------------------------\n")
+		node.printf("(ECHONET Lite:Information) > If you wanna communicate %s, you should Input index '1'\n", node.Instances[0].ClassName)
+		node.printf("(ECHONET Lite:Information) > If you wanna EXIT, enter '0'\n")
 
 		var index int64
 		for {
-			fmt.Printf("(Input) > ")
-			indexBuf, err := strconv.ParseInt(nextLine(), 10, 64)
+			node.printf("(Input) > ")
+			line, err := answer(node.prompter)
+			if err != nil { // no more answers
+				return nil
+			}
+			indexBuf, err := strconv.ParseInt(line, 10, 64)
 			index = indexBuf
 			if err != nil || index > int64(len(node.Instances)) || index < 0 { // index out of range
-				fmt.Printf("(ECHONET Lite:Error) > Invalid index\n")
+				node.printf("(ECHONET Lite:Error) > Invalid index\n")
 				continue
 			} else if index == 0 { // index 0 means END of communicate
 				return nil
@@ -1082,57 +1461,62 @@ func (node *Node) Communicate() error {
 		// suspend judge END of communication during input EPC or EDT
 		suspend := false
 		if !testMode {
-			fmt.Printf("\n(ECHONET Lite:Information)> Input ESV with hexnumber\n")
-			fmt.Printf("(Input:ESV) > ")
-			num, err := strconv.ParseInt(nextLine(), 16, 64)
+			node.printf("\n(ECHONET Lite:Information)> Input ESV with hexnumber\n")
+			node.printf("(Input:ESV) > ")
+			line, err := answer(node.prompter)
+			if err != nil { // no more answers
+				return nil
+			}
+			num, err := strconv.ParseInt(line, 16, 64)
 			if err != nil {
-				fmt.Printf("(ECHONET Lite:Error) > Invalid number\n")
+				node.printf("(ECHONET Lite:Error) > Invalid number\n")
 				continue
 			}
 			inputMode := false
-			for {
-				if num == 0x60 || num == 0x61 {
-					inputMode = true
-					fmt.Printf("\n(ECHONET Lite:Information)> Set Properties are\n")
-					for _, prop := range communicateInstance.Props {
-						if prop.ImplementSet {
-							fmt.Printf("> EPC: %02X, Name: %s\n", prop.EPC, prop.PropertyName)
-						}
+			if num == 0x60 || num == 0x61 {
+				inputMode = true
+				node.printf("\n(ECHONET Lite:Information)> Set Properties are\n")
+				for _, prop := range communicateInstance.Props {
+					if prop.ImplementSet {
+						node.printf("> EPC: %02X, Name: %s\n", prop.EPC, prop.PropertyName)
 					}
-					break
-				} else if num == 0x62 {
-					fmt.Printf("\n(ECHONET Lite:Information)> Get Properties are\n")
-					for _, prop := range communicateInstance.Props {
-						if prop.ImplementGet {
-							fmt.Printf("> EPC: %02X, Name: %s\n", prop.EPC, prop.PropertyName)
-						}
+				}
+			} else if num == 0x62 {
+				node.printf("\n(ECHONET Lite:Information)> Get Properties are\n")
+				for _, prop := range communicateInstance.Props {
+					if prop.ImplementGet {
+						node.printf("> EPC: %02X, Name: %s\n", prop.EPC, prop.PropertyName)
 					}
-					break
-				} else {
-					fmt.Printf("(ECHONET Lite:Error) > Invalid ESV\n")
 				}
+			} else {
+				node.printf("(ECHONET Lite:Error) > Invalid ESV\n")
+				continue
 			}
 			payload.ESV = uint8(num)
-			fmt.Printf("\n(ECHONET Lite:Information) > Input OPC (Operation Property COUNTER) with HEX number\n")
-			fmt.Printf("(Input:OPC)> ")
-			num, err = strconv.ParseInt(nextLine(), 16, 64)
+			node.printf("\n(ECHONET Lite:Information) > Input OPC (Operation Property COUNTER) with HEX number\n")
+			node.printf("(Input:OPC)> ")
+			line, err = answer(node.prompter)
+			if err != nil { // no more answers
+				return nil
+			}
+			num, err = strconv.ParseInt(line, 16, 64)
 			if err != nil {
-				fmt.Printf("(ECHONET Lite:Error) > Invalid number\n")
+				node.printf("(ECHONET Lite:Error) > Invalid number\n")
 				continue
 			}
 			payload.OPC = uint8(num)
 			for i := 0; int64(i) < num; i++ {
-				fmt.Printf("(ECHONET Lite:Information)> Input EPC with HEX number\n")
-				fmt.Printf("(ECHONET Lite:Information)> Type 'END' to suspend communication\n")
-				fmt.Printf("(Input:EPC%d)> ", i+1)
-				buf := nextLine()
-				if strings.Contains(buf, "END") {
+				node.printf("(ECHONET Lite:Information)> Input EPC with HEX number\n")
+				node.printf("(ECHONET Lite:Information)> Type 'END' to suspend communication\n")
+				node.printf("(Input:EPC%d)> ", i+1)
+				buf, err := answer(node.prompter)
+				if err != nil || strings.Contains(buf, "END") {
 					suspend = true
 					break
 				}
 				epc, err := strconv.ParseInt(buf, 16, 64)
 				if err != nil {
-					fmt.Printf("(ECHONET Lite:Error) > Invalid number\n")
+					node.printf("(ECHONET Lite:Error) > Invalid number\n")
 					return xerrors.Errorf("Invalid number: %w", err)
 				}
 				var recv []uint8
@@ -1156,9 +1540,16 @@ func (node *Node) Communicate() error {
 			}
 		} else { // Test Mode
 			for {
-				fmt.Printf("\n(ECHONET Lite:Information)> Input HEX number\n")
-				fmt.Printf("(Input)> ")
-				varByte := nextLine()
+				node.printf("\n(ECHONET Lite:Information)> Input HEX number\n")
+				node.printf("(Input)> ")
+				varByte, err := answer(node.prompter)
+				if err != nil { // no more answers
+					return nil
+				}
+				if len(varByte) < 8 { // ESV, OPC, EPC and PDC
+					node.printf("(ECHONET Lite:Error) > Invalid number\n")
+					continue
+				}
 				index := 0
 				esv, err := strconv.ParseUint(varByte[index:index+2], 16, 64)
 				index += 2
@@ -1212,48 +1603,49 @@ func (node *Node) Communicate() error {
 			}
 		}
 		node.logger.Info("Send packet", zap.String("payload", fmt.Sprintf("%+v", payload)))
-		fmt.Printf("--- Send ---\n")
-		printPacket(payload)
-		fmt.Printf("------------\n")
-		go func() {
-			SendEchonet(payload, node.connSend)
-		}()
-		recv, _ := node.RecvEchonet()
+		node.printf("--- Send ---\n")
+		printPacket(node.prompter, payload)
+		node.printf("------------\n")
+		recv, err := node.exchange(payload, node.replyTimeout())
+		if err != nil {
+			node.printf("(ECHONET Lite:Error) > %s\n", err)
+			continue
+		}
 		node.logger.Info("receive packet", zap.String("payload", fmt.Sprintf("%+v", recv)))
-		fmt.Printf("--- Recv ---\n")
-		printPacket(recv)
-		fmt.Printf("------------\n")
+		node.printf("--- Recv ---\n")
+		printPacket(node.prompter, recv)
+		node.printf("------------\n")
 	}
 }
 
 // print FrameFormat
-func printPacket(payload FrameFormat) error {
-	fmt.Printf("> EHD1:   %02X\n", payload.EHD1)
-	fmt.Printf("> EHD2:   %02X\n", payload.EHD2)
-	fmt.Printf("> TID:    %02X\n", payload.TID)
-	fmt.Printf("> SEOJ:   %02X\n", payload.SEOJ)
-	fmt.Printf("> DEOJ:   %02X\n", payload.DEOJ)
-	fmt.Printf("> ESV:    %02X\n", payload.ESV)
+func printPacket(w io.Writer, payload FrameFormat) error {
+	fmt.Fprintf(w, "> EHD1:   %02X\n", payload.EHD1)
+	fmt.Fprintf(w, "> EHD2:   %02X\n", payload.EHD2)
+	fmt.Fprintf(w, "> TID:    %02X\n", payload.TID)
+	fmt.Fprintf(w, "> SEOJ:   %02X\n", payload.SEOJ)
+	fmt.Fprintf(w, "> DEOJ:   %02X\n", payload.DEOJ)
+	fmt.Fprintf(w, "> ESV:    %02X\n", payload.ESV)
 	if payload.VarGroupsG != nil {
-		fmt.Printf("> OPCSet: %02X\n", payload.OPC)
+		fmt.Fprintf(w, "> OPCSet: %02X\n", payload.OPC)
 	} else {
-		fmt.Printf("> OPC:    %02X\n", payload.OPC)
+		fmt.Fprintf(w, "> OPC:    %02X\n", payload.OPC)
 	}
 	for i, varGroup := range payload.VarGroups {
 		i++
-		fmt.Printf(">  EPC%d:  		%02X\n", i, varGroup.EPC)
-		fmt.Printf(">  PDC%d:  		%02X\n", i, varGroup.PDC)
+		fmt.Fprintf(w, ">  EPC%d:  		%02X\n", i, varGroup.EPC)
+		fmt.Fprintf(w, ">  PDC%d:  		%02X\n", i, varGroup.PDC)
 		if varGroup.EDT != nil {
-			fmt.Printf(">  EDT%d:  		%02X\n", i, varGroup.EDT)
+			fmt.Fprintf(w, ">  EDT%d:  		%02X\n", i, varGroup.EDT)
 		}
 	}
 	if payload.VarGroupsG != nil {
-		fmt.Printf("> OPCGet: %02X\n", payload.OPC)
+		fmt.Fprintf(w, "> OPCGet: %02X\n", payload.OPC)
 		for i, varGroup := range payload.VarGroups {
-			fmt.Printf(">  EPC%d:  		%02X\n", i, varGroup.EPC)
-			fmt.Printf(">  PDC%d:  		%02X\n", i, varGroup.PDC)
+			fmt.Fprintf(w, ">  EPC%d:  		%02X\n", i, varGroup.EPC)
+			fmt.Fprintf(w, ">  PDC%d:  		%02X\n", i, varGroup.PDC)
 			if varGroup.EDT != nil {
-				fmt.Printf(">  EDT%d:  		%02X\n", i, varGroup.EDT)
+				fmt.Fprintf(w, ">  EDT%d:  		%02X\n", i, varGroup.EDT)
 			}
 		}
 	}
@@ -1318,48 +1710,24 @@ func printPacket(payload FrameFormat) error {
 //}
 //
 
-// parseInstanceList EPC: 0xD5, 0xD6
-func parseInstanceList(edt []uint8) ([]Instance, error) {
-	var retInstances []Instance
-
-	numberInstance := edt[0]
-
-	for i := 0; i < int(numberInstance); i += 3 {
-		var inst Instance
-		inst.ClassCode = [3]uint8{edt[i], edt[i+1], edt[i+2]}
-		retInstances = append(retInstances, inst)
-	}
-	return retInstances, nil
-}
-
-// TODO 使われてない
+// GetProp get properties props of the object deoj with a request from seoj and return the reply
 func (a *Node) GetProp(deoj [3]uint8, seoj [3]uint8, props ...uint8) (FrameFormat, error) {
-	var properties []VarByteGroup
-
 	payload := FrameFormat{
 		EHD1: 0x10,
 		EHD2: 0x81,
 		SEOJ: seoj,
 		DEOJ: deoj,
-		TID:  uint16(rand.Intn(65535)),
+		TID:  a.client.nextTID(),
 		ESV:  0x62,
 		OPC:  uint8(len(props)),
 	}
-
-	for i, prop := range props {
-		properties[i].EPC = prop
-		properties[i].PDC = 0x00
+	for _, prop := range props {
+		payload.VarGroups = append(payload.VarGroups, VarByteGroup{EPC: prop, PDC: 0x00})
 	}
-	payload.VarGroups = properties
 
-	err := SendEchonet(payload, a.connSend)
+	recv, err := a.exchange(payload, a.replyTimeout())
 	if err != nil {
-		return payload, xerrors.Errorf("Failed to send ECHONET Lite packet: %w", err)
+		return recv, xerrors.Errorf("Failed to receive ECHONET Lite packet: %w", err)
 	}
-	recv, err := a.RecvEchonet()
-	if err != nil {
-		return payload, xerrors.Errorf("Failed to send ECHONET Lite packet: %w", err)
-	}
-
 	return recv, nil
 }
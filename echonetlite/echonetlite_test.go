@@ -1,7 +1,9 @@
 package echonetlite
 
 import (
+	"io/ioutil"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -57,6 +59,32 @@ func Test_parser(t *testing.T) {
 				},
 			},
 		},
+		{
+			EHD1: 0x10,
+			EHD2: 0x81,
+			TID:  0x0000,
+			SEOJ: [3]uint8{0x05, 0xff, 0x01},
+			DEOJ: [3]uint8{0x01, 0x30, 0x01},
+			ESV:  0x60,
+			OPC:  0x03,
+			VarGroups: []VarByteGroup{
+				{
+					EPC: 0x80,
+					PDC: 0x01,
+					EDT: []uint8{0x30},
+				},
+				{
+					EPC: 0x80,
+					PDC: 0x00,
+					EDT: nil,
+				},
+				{
+					EPC: 0x80,
+					PDC: 0x01,
+					EDT: []uint8{0x30},
+				},
+			},
+		},
 		{
 			EHD1: 0x10,
 			EHD2: 0x81,
@@ -87,7 +115,7 @@ func Test_parser(t *testing.T) {
 	for index, tc := range normalTestCase {
 		actual, err := parser(tc)
 		if err != nil {
-			t.Errorf("Return value is not nil from function parser: %w", err)
+			t.Errorf("Return value is not nil from function parser: %v", err)
 		} else {
 			if actual.EHD1 != normalTestExpect[index].EHD1 {
 				t.Errorf("EHD1 value is return parser(tc) => %v, want %v", actual.EHD1, normalTestExpect[index].EHD1)
@@ -216,3 +244,49 @@ func Test_parser(t *testing.T) {
 		}
 	}
 }
+
+func Test_CreateObject_parallel(t *testing.T) {
+	json, err := ioutil.ReadFile("class.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// nodes of Auditors with different releases don't share class definitions
+	releases := []string{"J", "M"}
+	insts := make([]Instance, len(releases))
+	errs := make([]error, len(releases))
+	var wg sync.WaitGroup
+	for i, release := range releases {
+		wg.Add(1)
+		go func(i int, release string) {
+			defer wg.Done()
+			insts[i], errs[i] = newTestNode().CreateObject([3]uint8{0x01, 0x30, 0x01}, release, json)
+		}(i, release)
+	}
+	wg.Wait()
+	for i := range releases {
+		if errs[i] != nil || len(insts[i].Props) == 0 || insts[i].release != releases[i] {
+			t.Errorf("CreateObject of release %s => %d properties, %v", releases[i], len(insts[i].Props), errs[i])
+		}
+	}
+}
+
+func Test_parsePropMap(t *testing.T) {
+	epcs, err := parsePropMap([]uint8{0x02, 0x80, 0x81})
+	if err != nil || len(epcs) != 2 || epcs[0] != 0x80 || epcs[1] != 0x81 {
+		t.Errorf("parsePropMap(list) => %X, %v", epcs, err)
+	}
+	bitmap := make([]uint8, 17)
+	bitmap[0] = 16
+	for i := 1; i < 17; i++ {
+		bitmap[i] = 0x01
+	}
+	epcs, err = parsePropMap(bitmap)
+	if err != nil || len(epcs) != 16 {
+		t.Errorf("parsePropMap(bitmap) => %X, %v", epcs, err)
+	}
+	for _, edt := range [][]uint8{nil, {0x03, 0x80}, {0x10, 0xFF, 0xFF}} {
+		if _, err := parsePropMap(edt); err == nil {
+			t.Errorf("parsePropMap(%X) => nil error, want an error", edt)
+		}
+	}
+}
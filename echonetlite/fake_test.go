@@ -0,0 +1,86 @@
+package echonetlite
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// listenLoopback return a UDP socket on loopback, which is closed when the test finishes
+func listenLoopback(t *testing.T) *net.UDPConn {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// newFakeClient return Client of a fake device on loopback which replies reply(request) to every request.
+// nil reply means no reply
+func newFakeClient(t *testing.T, reply func(req *FrameFormat) []byte) (*Client, *net.UDPConn) {
+	return newRawFakeClient(t, func(data []byte) []byte {
+		req, err := parser(data)
+		if err != nil {
+			return nil
+		}
+		return reply(req)
+	})
+}
+
+// newRawFakeClient return Client of a fake device like newFakeClient, whose reply is given the bytes received
+func newRawFakeClient(t *testing.T, reply func(data []byte) []byte) (*Client, *net.UDPConn) {
+	device := listenLoopback(t)
+	connRecv := listenLoopback(t)
+	conn, err := net.Dial("udp4", device.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient(net.IPv4(127, 0, 0, 1), conn, connRecv)
+	c.owned = true
+	c.Timeout = 300 * time.Millisecond
+	go func() {
+		buffer := make([]byte, maxDatagram)
+		for {
+			length, _, err := device.ReadFromUDP(buffer)
+			if err != nil {
+				return
+			}
+			if data := reply(append([]byte(nil), buffer[:length]...)); data != nil {
+				device.WriteToUDP(data, connRecv.LocalAddr().(*net.UDPAddr))
+			}
+		}
+	}()
+	t.Cleanup(func() { c.Close() })
+	return c, device
+}
+
+// fakeDevice replace the client of node with a fake device which replies to heartbeats with instance lists in order.
+// nil in lists, or requests after lists, mean no reply
+func fakeDevice(t *testing.T, node *Node, lists [][]uint8) {
+	next := 0
+	node.client, _ = newFakeClient(t, func(req *FrameFormat) []byte {
+		if next >= len(lists) {
+			return nil
+		}
+		list := lists[next]
+		next++
+		if list == nil {
+			return nil
+		}
+		reply := FrameFormat{
+			EHD1: 0x10,
+			EHD2: 0x81,
+			TID:  req.TID,
+			SEOJ: req.DEOJ,
+			DEOJ: req.SEOJ,
+			ESV:  0x72,
+			OPC:  0x02,
+			VarGroups: []VarByteGroup{
+				{EPC: 0x80, PDC: 0x01, EDT: []uint8{0x30}},
+				{EPC: 0xD6, PDC: uint8(len(list)), EDT: list},
+			},
+		}
+		return echonetToByte(reply)
+	})
+}
@@ -0,0 +1,207 @@
+package echonetlite
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// Severity expresses how serious a Finding is
+type Severity string
+
+const (
+	SeverityInfo   Severity = "info"
+	SeverityLow    Severity = "low"
+	SeverityMedium Severity = "medium"
+	SeverityHigh   Severity = "high"
+)
+
+// Rank return the order of Severity. The larger, the more serious.
+// Unknown Severity is 0
+func (s Severity) Rank() int {
+	switch s {
+	case SeverityInfo:
+		return 1
+	case SeverityLow:
+		return 2
+	case SeverityMedium:
+		return 3
+	case SeverityHigh:
+		return 4
+	}
+	return 0
+}
+
+// ParseSeverity change string into Severity
+func ParseSeverity(s string) (Severity, bool) {
+	sev := Severity(s)
+	if sev.Rank() == 0 {
+		return sev, false
+	}
+	return sev, true
+}
+
+// Finding expresses a violation of ECHONET Lite specification detected by a check
+type Finding struct {
+	RuleID   string   `json:"ruleId"`
+	Severity Severity `json:"severity"`
+	Check    string   `json:"check"`
+	CheckID  int      `json:"checkId"`
+	Node     string   `json:"node"`
+	Instance string   `json:"instance,omitempty"` // Object code like "013001"
+	EPC      string   `json:"epc,omitempty"`      // Property code like "80"
+	SpecRef  string   `json:"specRef"`
+	Message  string   `json:"message"`
+
+	// Sent and Recv are the evidence, frames in HEX
+	Sent string `json:"sent,omitempty"`
+	Recv string `json:"recv,omitempty"`
+
+	// Minimized is the smallest frame in HEX which still reproduces the finding
+	Minimized string `json:"minimized,omitempty"`
+}
+
+// rule is the specification of the Finding whose ID is the key of rules
+type rule struct {
+	severity Severity
+	specRef  string
+}
+
+// rules has all rule ID checks report
+var rules = map[string]rule{
+	"RECV-TRUNCATED":                {SeverityHigh, "ECHONET Lite Part II 3.2"},
+	"RECV-NONE":                     {SeverityMedium, "ECHONET Lite Part II 4.2.3"},
+	"FLOW-TID":                      {SeverityMedium, "ECHONET Lite Part II 3.2.2"},
+	"FLOW-ESV":                      {SeverityHigh, "ECHONET Lite Part II 3.2.5"},
+	"FLOW-ESV-INVALID":              {SeverityHigh, "ECHONET Lite Part II 3.2.5"},
+	"FLOW-EOJ":                      {SeverityMedium, "ECHONET Lite Part II 3.2.4"},
+	"FLOW-OPC":                      {SeverityHigh, "ECHONET Lite Part II 3.2.6"},
+	"FLOW-OPC-LARGE":                {SeverityHigh, "ECHONET Lite Part II 3.2.6"},
+	"FLOW-OPC-SMALL":                {SeverityMedium, "ECHONET Lite Part II 4.2.3"},
+	"FLOW-EDT-NONEMPTY":             {SeverityLow, "ECHONET Lite Part II 4.2.3.1"},
+	"FLOW-EDT-EMPTY":                {SeverityMedium, "ECHONET Lite Part II 4.2.3.3"},
+	"EPC-UNEXPECTED":                {SeverityMedium, "ECHONET Lite Part II 4.2.3"},
+	"EPC-MISSING":                   {SeverityMedium, "ECHONET Lite Part II 4.2.3"},
+	"EPC-UNKNOWN":                   {SeverityLow, "APPENDIX Detailed Requirements for ECHONET Device objects"},
+	"VALUE-RANGE":                   {SeverityMedium, "APPENDIX Detailed Requirements for ECHONET Device objects"},
+	"VALUE-UNCHECKABLE":             {SeverityInfo, "APPENDIX Detailed Requirements for ECHONET Device objects"},
+	"FUZZ-MALFORMED-REPLY":          {SeverityHigh, "ECHONET Lite Part II 3.2"},
+	"FUZZ-EHD-ACCEPTED":             {SeverityMedium, "ECHONET Lite Part II 3.2.1"},
+	"FUZZ-ESV-ACCEPTED":             {SeverityMedium, "ECHONET Lite Part II 3.2.5"},
+	"FUZZ-MALFORMED-ACCEPTED":       {SeverityMedium, "ECHONET Lite Part II 3.2.6"},
+	"FUZZ-TRAILING-ACCEPTED":        {SeverityInfo, "ECHONET Lite Part II 3.2"},
+	"BOUNDARY-ACCEPTED":             {SeverityHigh, "ECHONET Lite Part II 4.2.3.1"},
+	"BOUNDARY-NOT-REJECTED":         {SeverityMedium, "ECHONET Lite Part II 4.2.3.1"},
+	"BOUNDARY-VALUE-CHANGED":        {SeverityHigh, "ECHONET Lite Part II 4.2.3.1"},
+	"PROFILE-SET-ACCEPTED":          {SeverityHigh, "ECHONET Lite Part II 4.2.3.1"},
+	"PROFILE-SET-NOT-REJECTED":      {SeverityMedium, "ECHONET Lite Part II 4.2.3.1"},
+	"PROFILE-GET-NOT-REJECTED":      {SeverityMedium, "ECHONET Lite Part II 4.2.3.3"},
+	"ADDR-UNKNOWN-DEOJ":             {SeverityMedium, "ECHONET Lite Part II 4.2.2"},
+	"ADDR-BROADCAST-MISSING":        {SeverityMedium, "ECHONET Lite Part II 3.2.4"},
+	"ADDR-BROADCAST-SEOJ":           {SeverityMedium, "ECHONET Lite Part II 3.2.4"},
+	"ADDR-BROADCAST-DUPLICATE":      {SeverityLow, "ECHONET Lite Part II 3.2.4"},
+	"ADDR-SEOJ-IGNORED":             {SeverityLow, "ECHONET Lite Part II 3.2.4"},
+	"RELEASE-INVALID":               {SeverityMedium, "ECHONET Lite Appendix 1.3"},
+	"RELEASE-UNDEFINED":             {SeverityLow, "ECHONET Lite Appendix 1.3"},
+	"RELEASE-VERSION-MISMATCH":      {SeverityLow, "ECHONET Lite Part II 6.11.1"},
+	"NODEPROFILE-LIST-MALFORMED":    {SeverityHigh, "ECHONET Lite Part II 6.11.1"},
+	"NODEPROFILE-PROPMAP-MALFORMED": {SeverityHigh, "ECHONET Lite Appendix 1"},
+	"NODEPROFILE-PROPERTY-MISSING":  {SeverityMedium, "ECHONET Lite Part II 6.11.1"},
+	"NODEPROFILE-INSTANCE-COUNT":    {SeverityMedium, "ECHONET Lite Part II 6.11.1"},
+	"NODEPROFILE-CLASS-COUNT":       {SeverityMedium, "ECHONET Lite Part II 6.11.1"},
+	"NODEPROFILE-CLASS-LIST":        {SeverityMedium, "ECHONET Lite Part II 6.11.1"},
+	"NODEPROFILE-ANNOUNCE":          {SeverityMedium, "ECHONET Lite Part II 6.11.1"},
+	"NODEPROFILE-ANNOUNCE-MISSING":  {SeverityLow, "ECHONET Lite Part II 6.11.1"},
+	"NODEPROFILE-VERSION-FORMAT":    {SeverityMedium, "ECHONET Lite Part II 6.11.1"},
+	"NODEPROFILE-ID-FORMAT":         {SeverityMedium, "ECHONET Lite Part II 6.11.1"},
+	"NODEPROFILE-MANUFACTURER":      {SeverityMedium, "ECHONET Lite Part II 6.11.1"},
+	"IDENTITY-MANUFACTURER":         {SeverityMedium, "ECHONET Lite Part II 6.11.1"},
+	"LIVENESS-HANG":                 {SeverityHigh, "ECHONET Lite Part II 4.3.1"},
+	"LIVENESS-REBOOT":               {SeverityHigh, "ECHONET Lite Part II 4.3.1"},
+	"LIVENESS-LOST":                 {SeverityHigh, "ECHONET Lite Part II 4.3.1"},
+}
+
+// frameHex change FrameFormat into HEX string
+func frameHex(frame *FrameFormat) string {
+	if frame == nil || frame.EHD1 == 0 {
+		return ""
+	}
+	return hex.EncodeToString(echonetToByte(*frame))
+}
+
+// eojString change object code into string like "013001"
+func eojString(eoj [3]uint8) string {
+	return fmt.Sprintf("%02X%02X%02X", eoj[0], eoj[1], eoj[2])
+}
+
+// checkScope is the check Node is running now
+type checkScope struct {
+	id   int
+	name string
+	eoj  [3]uint8
+	sent string // HEX
+	recv string // HEX
+}
+
+// beginCheck start a check named name.
+// If Node is already running a check, the check is the part of it and beginCheck return true
+func (node *Node) beginCheck(name string, eoj [3]uint8, sent string, recv string) bool {
+	if node.scope != nil {
+		return true
+	}
+	node.scope = &checkScope{
+		name: name,
+		eoj:  eoj,
+		sent: sent,
+		recv: recv,
+	}
+	if node.result != nil {
+		node.scope.id = node.result.beginCheck(name, node.ip.String(), eojString(eoj), sent, recv)
+	}
+	return false
+}
+
+// endCheck finish the check begun by beginCheck
+func (node *Node) endCheck(nested bool) {
+	if nested || node.scope == nil {
+		return
+	}
+	if node.result != nil {
+		node.result.endCheck(node.scope.id)
+	}
+	node.scope = nil
+}
+
+// report store Finding into Result and output ERROR log.
+// Severity and SpecRef are filled by RuleID, Node, Instance and evidence are filled by running check
+func (node *Node) report(f Finding) {
+	if r, ok := rules[f.RuleID]; ok {
+		if f.Severity == "" {
+			f.Severity = r.severity
+		}
+		if f.SpecRef == "" {
+			f.SpecRef = r.specRef
+		}
+	}
+	f.Node = node.ip.String()
+	if node.scope != nil {
+		f.Check = node.scope.name
+		f.CheckID = node.scope.id
+		if f.Instance == "" {
+			f.Instance = eojString(node.scope.eoj)
+		}
+		if f.Sent == "" {
+			f.Sent = node.scope.sent
+		}
+		if f.Recv == "" {
+			f.Recv = node.scope.recv
+		}
+	}
+	if node.logger != nil {
+		node.logger.Error(f.Message, zap.String("rule", f.RuleID), zap.String("severity", string(f.Severity)), zap.String("instance", f.Instance), zap.String("EPC", f.EPC))
+	}
+	if node.result != nil {
+		node.result.Add(f)
+	}
+}
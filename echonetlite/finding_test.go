@@ -0,0 +1,137 @@
+package echonetlite
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func newTestNode() *Node {
+	return &Node{
+		ip:     net.ParseIP("192.0.2.1"),
+		client: NewClient(net.ParseIP("192.0.2.1"), nil, nil),
+		logger: zap.NewNop(),
+		result: NewResult("test"),
+	}
+}
+
+func Test_CheckFlowValidation(t *testing.T) {
+	sent := FrameFormat{
+		EHD1: 0x10,
+		EHD2: 0x81,
+		TID:  0x0001,
+		SEOJ: [3]uint8{0x0E, 0xF0, 0x01},
+		DEOJ: [3]uint8{0x01, 0x30, 0x01},
+		ESV:  0x62,
+		OPC:  0x01,
+		VarGroups: []VarByteGroup{
+			{EPC: 0x80, PDC: 0x00},
+		},
+	}
+	recv := FrameFormat{
+		EHD1: 0x10,
+		EHD2: 0x81,
+		TID:  0x0001,
+		SEOJ: [3]uint8{0x01, 0x30, 0x01},
+		DEOJ: [3]uint8{0x0E, 0xF0, 0x01},
+		ESV:  0x72,
+		OPC:  0x01,
+		VarGroups: []VarByteGroup{
+			{EPC: 0x80, PDC: 0x01, EDT: []uint8{0x30}},
+		},
+	}
+
+	node := newTestNode()
+	if err := node.CheckFlowValidation(sent, recv); err != nil {
+		t.Errorf("CheckFlowValidation(valid flow) => %v, want nil", err)
+	}
+	if len(node.result.Findings) != 0 {
+		t.Errorf("valid flow reported %d findings, want 0", len(node.result.Findings))
+	}
+	if len(node.result.Checks) != 1 || !node.result.Checks[0].Passed {
+		t.Errorf("valid flow checks => %+v, want 1 passed check", node.result.Checks)
+	}
+
+	invalid := recv
+	invalid.TID = 0x0002
+	invalid.VarGroups = []VarByteGroup{
+		{EPC: 0x81, PDC: 0x01, EDT: []uint8{0x00}},
+	}
+	node = newTestNode()
+	if err := node.CheckFlowValidation(sent, invalid); err == nil {
+		t.Errorf("CheckFlowValidation(invalid flow) => nil, want error")
+	}
+	want := map[string]bool{"FLOW-TID": false, "EPC-UNEXPECTED": false, "EPC-MISSING": false}
+	for _, f := range node.result.Findings {
+		if _, ok := want[f.RuleID]; !ok {
+			t.Errorf("unexpected finding %s", f.RuleID)
+			continue
+		}
+		want[f.RuleID] = true
+		if f.CheckID != 1 || f.Check != "CheckFlowValidation" {
+			t.Errorf("finding %s belongs to check %d(%s), want 1(CheckFlowValidation)", f.RuleID, f.CheckID, f.Check)
+		}
+		if f.Instance != "013001" || f.Node != "192.0.2.1" || f.Sent == "" || f.Recv == "" {
+			t.Errorf("finding %s lacks evidence: %+v", f.RuleID, f)
+		}
+	}
+	for id, found := range want {
+		if !found {
+			t.Errorf("finding %s is not reported", id)
+		}
+	}
+	if len(node.result.Checks) != 1 || node.result.Checks[0].Passed {
+		t.Errorf("invalid flow checks => %+v, want 1 failed check", node.result.Checks)
+	}
+	if code := node.result.ExitCode(SeverityMedium); code != 1 {
+		t.Errorf("ExitCode(medium) => %d, want 1", code)
+	}
+	if code := node.result.ExitCode(SeverityHigh); code != 0 {
+		t.Errorf("ExitCode(high) => %d, want 0", code)
+	}
+}
+
+func Test_Check_ESV(t *testing.T) {
+	eoj := [3]uint8{0x01, 0x30, 0x01}
+	get := FrameFormat{EHD1: 0x10, EHD2: 0x81, TID: 0x0001, SEOJ: [3]uint8{0x0E, 0xF0, 0x01}, DEOJ: eoj, ESV: 0x62, OPC: 0x01, VarGroups: []VarByteGroup{{EPC: 0x80}}}
+	setGet := get
+	setGet.ESV = 0x6E
+	setGet.VarGroups = []VarByteGroup{{EPC: 0x80, PDC: 0x01, EDT: []uint8{0x30}}}
+	setGet.OPCG = 0x01
+	setGet.VarGroupsG = []VarByteGroup{{EPC: 0x80}}
+	cases := []struct {
+		esv      uint8
+		observed bool // values are checked
+	}{
+		{0x72, true},  // Get_Res
+		{0x73, true},  // INF
+		{0x7E, true},  // SetGet_Res
+		{0x5E, true},  // SetGet_SNA
+		{0x71, false}, // Set_Res has no value
+		{0x52, false}, // Get_SNA
+	}
+	for _, c := range cases {
+		node := newTestNode()
+		node.Instances = []Instance{{ClassCode: eoj, Props: []Property{{EPC: 0x80}}}}
+		sent := get
+		recv := FrameFormat{EHD1: 0x10, EHD2: 0x81, TID: 0x0001, SEOJ: eoj, DEOJ: sent.SEOJ, ESV: c.esv, OPC: 0x01, VarGroups: []VarByteGroup{{EPC: 0x80, PDC: 0x01, EDT: []uint8{0x30}}}}
+		if c.esv&0x0F == 0x0E {
+			// the Set part has no value and the Get part carries it
+			sent = setGet
+			recv.VarGroups = []VarByteGroup{{EPC: 0x80}}
+			recv.OPCG = 0x01
+			recv.VarGroupsG = []VarByteGroup{{EPC: 0x80, PDC: 0x01, EDT: []uint8{0x30}}}
+		}
+		node.Check(&sent, &recv)
+		if observed := len(node.result.Observations) == 1; observed != c.observed {
+			t.Errorf("Check of ESV %02X => values checked %v, want %v", c.esv, observed, c.observed)
+		}
+		for _, f := range node.result.Findings {
+			if c.esv == 0x7E && strings.HasPrefix(f.RuleID, "FLOW-") {
+				t.Errorf("Check of valid SetGet_Res reported %s: %s", f.RuleID, f.Message)
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package echonetlite
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+// checkManufacturer get the manufacturer code (0x8A) of the node profile and compare it with expected in HEX like "000077"
+func (node *Node) checkManufacturer(expected string) {
+	get := FrameFormat{
+		EHD1:      0x10,
+		EHD2:      0x81,
+		TID:       node.client.nextTID(),
+		SEOJ:      node.client.SEOJ,
+		DEOJ:      [3]uint8{0x0E, 0xF0, 0x01},
+		ESV:       0x62,
+		OPC:       0x01,
+		VarGroups: []VarByteGroup{{EPC: 0x8A, PDC: 0x00}},
+	}
+	node.logger.Info("sent packet", zap.String("check", "manufacturer code"), zap.String("payload", frameHex(&get)))
+	recv, err := node.exchange(get, node.replyTimeout())
+	if err != nil && !xerrors.Is(err, ErrTimeout) && !isTruncated(err) {
+		node.logger.Error("Receive packet Failed", zap.String("message", err.Error()))
+	}
+	node.compareManufacturer(&get, &recv, expected)
+}
+
+// compareManufacturer check the reply recv to Get of the manufacturer code has expected
+func (node *Node) compareManufacturer(sent *FrameFormat, recv *FrameFormat, expected string) {
+	defer node.endCheck(node.beginCheck("Identity", sent.DEOJ, frameHex(sent), frameHex(recv)))
+	expected = strings.ToUpper(expected)
+	if recv.EHD1 == 0 || recv.ESV != 0x72 || len(recv.VarGroups) == 0 {
+		node.report(Finding{RuleID: "IDENTITY-MANUFACTURER", EPC: "8A", Message: fmt.Sprintf("Couldn't get manufacturer code, expected %s", expected)})
+		return
+	}
+	actual := strings.ToUpper(hex.EncodeToString(recv.VarGroups[0].EDT))
+	if actual != expected {
+		node.report(Finding{RuleID: "IDENTITY-MANUFACTURER", EPC: "8A", Message: fmt.Sprintf("Manufacturer code is %s, expected %s", actual, expected)})
+	}
+}
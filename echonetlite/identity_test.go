@@ -0,0 +1,36 @@
+package echonetlite
+
+import (
+	"testing"
+)
+
+func Test_compareManufacturer(t *testing.T) {
+	sent := FrameFormat{EHD1: 0x10, EHD2: 0x81, TID: 0xF100, SEOJ: [3]uint8{0x0E, 0xF0, 0x01}, DEOJ: [3]uint8{0x0E, 0xF0, 0x01}, ESV: 0x62, OPC: 0x01, VarGroups: []VarByteGroup{{EPC: 0x8A}}}
+	reply := func(esv uint8, edt []uint8) FrameFormat {
+		return FrameFormat{EHD1: 0x10, EHD2: 0x81, TID: 0xF100, SEOJ: sent.DEOJ, DEOJ: sent.SEOJ, ESV: esv, OPC: 0x01, VarGroups: []VarByteGroup{{EPC: 0x8A, PDC: uint8(len(edt)), EDT: edt}}}
+	}
+	tests := []struct {
+		name     string
+		recv     FrameFormat
+		expected string
+		want     int
+	}{
+		{"same", reply(0x72, []uint8{0x00, 0x00, 0x77}), "000077", 0},
+		{"lower case", reply(0x72, []uint8{0x00, 0x00, 0xAB}), "0000ab", 0},
+		{"different", reply(0x72, []uint8{0x00, 0x00, 0x78}), "000077", 1},
+		{"SNA", reply(0x52, nil), "000077", 1},
+		{"no reply", FrameFormat{}, "000077", 1},
+	}
+	for _, tt := range tests {
+		node := newTestNode()
+		node.compareManufacturer(&sent, &tt.recv, tt.expected)
+		if got := len(node.result.Findings); got != tt.want {
+			t.Errorf("%s: findings => %d, want %d", tt.name, got, tt.want)
+		}
+		for _, f := range node.result.Findings {
+			if f.RuleID != "IDENTITY-MANUFACTURER" || f.EPC != "8A" {
+				t.Errorf("%s: finding => %s %s", tt.name, f.RuleID, f.EPC)
+			}
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package echonetlite
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+// operation is the cancellation of the operation running now, shared by Auditor and its nodes
+type operation struct {
+	mu          sync.Mutex
+	ctx         context.Context
+	cancel      context.CancelFunc
+	interrupted bool // the operation was interrupted
+	quit        bool // the user asked to quit
+}
+
+func newOperation() *operation {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &operation{ctx: ctx, cancel: cancel}
+}
+
+// begin start a new operation
+func (o *operation) begin() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.cancel()
+	o.ctx, o.cancel = context.WithCancel(context.Background())
+	o.interrupted = false
+}
+
+// requestQuit record that the user asked to quit and cancel the operation running now
+func (o *operation) requestQuit() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.quit = true
+	o.cancel()
+}
+
+// quitRequested return whether the user asked to quit
+func (o *operation) quitRequested() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.quit
+}
+
+// context return the context of the operation running now
+func (o *operation) context() context.Context {
+	if o == nil {
+		return context.Background()
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.ctx
+}
+
+// interrupt cancel the operation running now. Return false if it is already interrupted
+func (o *operation) interrupt() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.interrupted {
+		return false
+	}
+	o.interrupted = true
+	o.cancel()
+	return true
+}
+
+// wasInterrupted return whether the latest operation was interrupted
+func (o *operation) wasInterrupted() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.interrupted
+}
+
+// context return the context of the operation running now. Sends and receives stop when it is done
+func (node *Node) context() context.Context {
+	return node.op.context()
+}
+
+// canceled return an error if the operation running now is interrupted
+func (node *Node) canceled() error {
+	if err := node.context().Err(); err != nil {
+		return xerrors.Errorf("Interrupted at %s: %w", node.ip, err)
+	}
+	return nil
+}
+
+// Interrupt cancel the operation running now. Its loops stop at the next packet and partial results are kept.
+// Return false if it is already interrupted, which means the user wants to quit
+func (a *Auditor) Interrupt() bool {
+	return a.operation().interrupt()
+}
+
+// Interrupted return whether the latest operation was interrupted
+func (a *Auditor) Interrupted() bool {
+	return a.operation().wasInterrupted()
+}
+
+// operation return the cancellation shared with nodes
+func (a *Auditor) operation() *operation {
+	if a.op == nil {
+		a.op = newOperation()
+	}
+	return a.op
+}
+
+// Quit ask a to quit. The operation running now is canceled, and the prompt and scripts stop after it returns
+func (a *Auditor) Quit() {
+	a.operation().requestQuit()
+}
+
+// Quitting return whether a is asked to quit
+func (a *Auditor) Quitting() bool {
+	return a.operation().quitRequested()
+}
+
+// HandleInterrupt cancel the operation running now at the first interrupt signal (Ctrl-C).
+// At the second one, a is asked to quit by Quit, and the caller returns through its usual path to output results
+// and close a. Call stop to stop handling signals
+func (a *Auditor) HandleInterrupt() (stop func()) {
+	op := a.operation()
+	sigCh := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sigCh, os.Interrupt)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-sigCh:
+			}
+			if op.interrupt() {
+				a.log("Interrupted")
+				a.printf("\n(ECHONET Lite:Information)> Interrupted. Press Ctrl-C again to quit\n")
+				continue
+			}
+			a.log("Quit by interrupt")
+			a.printf("\n(ECHONET Lite:Information)> Quitting\n")
+			op.requestQuit()
+		}
+	}()
+	return func() {
+		signal.Stop(sigCh)
+		close(done)
+	}
+}
+
+// log write msg into the log of a if the logger is created
+func (a *Auditor) log(msg string) {
+	if a.logger != nil {
+		a.logger.Info(msg)
+	}
+}
+
+// Flush output the checkpoint of campaigns and reports of checks done so far
+func (a *Auditor) Flush() error {
+	if a.checkpoints != nil {
+		err := a.SaveCheckpoint()
+		if err != nil {
+			return err
+		}
+	}
+	return a.WriteReports()
+}
+
+// flushInterrupted output results so far if the latest operation was interrupted
+func (a *Auditor) flushInterrupted() {
+	if !a.Interrupted() {
+		return
+	}
+	err := a.Flush()
+	if err != nil {
+		a.printf("(ECHONET Lite:Error) > %s\n", err)
+		return
+	}
+	a.printf("(ECHONET Lite:Information)> Partial results are output under %s\n", a.resultDir())
+}
+
+// Close cancel the operation running now, close sockets of nodes and sync loggers.
+// Reports aren't output by Close. a can't be used after Close
+func (a *Auditor) Close() error {
+	var retErr error
+	a.closeOnce.Do(func() {
+		a.operation().interrupt()
+		for _, node := range a.DistNodes {
+			if node.client != nil && node.client.conn != nil {
+				if err := node.client.conn.Close(); err != nil && retErr == nil {
+					retErr = xerrors.Errorf("Failed to close connection to %s: %w", node.ip, err)
+				}
+			}
+			if node.logger != nil {
+				node.logger.Sync()
+			}
+		}
+		if a.receiver != nil {
+			if err := a.receiver.conn.Close(); err != nil && retErr == nil {
+				retErr = xerrors.Errorf("Failed to close receive socket: %w", err)
+			}
+		}
+		if a.logger != nil {
+			a.logger.Info("Closed", zap.Int("nodes", len(a.DistNodes)))
+			a.logger.Sync()
+		}
+	})
+	return retErr
+}
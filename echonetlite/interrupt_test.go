@@ -0,0 +1,94 @@
+package echonetlite
+
+import (
+	"context"
+	"net"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+func Test_Interrupt(t *testing.T) {
+	a := &Auditor{}
+	ctx := a.operation().context()
+	if !a.Interrupt() || ctx.Err() != context.Canceled || !a.Interrupted() {
+		t.Errorf("the first Interrupt doesn't cancel the operation")
+	}
+	if a.Interrupt() {
+		t.Errorf("the second Interrupt => true, want false to quit")
+	}
+	a.operation().begin()
+	if a.Interrupted() || a.operation().context().Err() != nil {
+		t.Errorf("a new operation is interrupted")
+	}
+	if !a.Interrupt() {
+		t.Errorf("Interrupt of a new operation => false")
+	}
+}
+
+func Test_Sweep_interrupted(t *testing.T) {
+	eoj := [3]uint8{0x01, 0x30, 0x01}
+	var requests int32
+	c, _ := newFakeClient(t, func(req *FrameFormat) []byte {
+		atomic.AddInt32(&requests, 1)
+		return nil
+	})
+	a := &Auditor{}
+	node := Node{
+		ip:      net.IPv4(127, 0, 0, 1),
+		client:  c,
+		timeout: 5 * time.Second,
+		logger:  zap.NewNop(),
+		op:      a.operation(),
+		Instances: []Instance{{ClassCode: eoj, Props: []Property{
+			{EPC: 0x80, ImplementGet: true},
+			{EPC: 0x81, ImplementGet: true},
+			{EPC: 0x82, ImplementGet: true},
+		}}},
+	}
+	a.DistNodes = []Node{node}
+
+	time.AfterFunc(100*time.Millisecond, func() { a.Interrupt() })
+	start := time.Now()
+	err := a.Sweep(node.ip, eoj)
+	if !xerrors.Is(err, context.Canceled) {
+		t.Errorf("Sweep => %v, want canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Sweep stopped after %s, want before the reply timeout", elapsed)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("%d requests are sent, want 1", n)
+	}
+}
+
+func Test_HandleInterrupt(t *testing.T) {
+	a := &Auditor{}
+	stop := a.HandleInterrupt()
+	defer stop()
+	self, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor := func(cond func() bool) bool {
+		for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if cond() {
+				return true
+			}
+		}
+		return false
+	}
+	self.Signal(os.Interrupt)
+	if !waitFor(a.Interrupted) || a.Quitting() {
+		t.Fatalf("the first interrupt => interrupted %v, quitting %v, want true, false", a.Interrupted(), a.Quitting())
+	}
+	// the second one returns through the caller instead of exiting the process
+	self.Signal(os.Interrupt)
+	if !waitFor(a.Quitting) {
+		t.Errorf("the second interrupt doesn't ask to quit")
+	}
+}
@@ -0,0 +1,192 @@
+package echonetlite
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// junitTestSuites is the root element of JUnit XML
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite corresponds to an instance of a node
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr,omitempty"`
+	Properties []junitProperty `xml:"properties>property"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// junitTestCase corresponds to a check
+type junitTestCase struct {
+	Name      string         `xml:"name,attr"`
+	ClassName string         `xml:"classname,attr"`
+	Time      string         `xml:"time,attr"`
+	Failures  []junitFailure `xml:"failure"`
+	SystemOut string         `xml:"system-out,omitempty"`
+}
+
+// junitFailure corresponds to findings of a check
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// WriteJUnit write Result as JUnit XML into w.
+// Test suites are instances of nodes and test cases are checks.
+// A failure of a check has its findings with sent and received frames as evidence.
+// Findings reported outside any check are test cases of their own
+func (r *Result) WriteJUnit(w io.Writer) error {
+	r.mu.Lock()
+	checks := append([]CheckRecord(nil), r.Checks...)
+	findings := append([]Finding(nil), r.Findings...)
+	r.mu.Unlock()
+
+	findingsOf := make(map[int][]Finding)
+	for _, f := range findings {
+		findingsOf[f.CheckID] = append(findingsOf[f.CheckID], f)
+	}
+
+	root := junitTestSuites{Name: "ECHONET Lite"}
+	suiteIndex := make(map[string]int)
+	var suiteTime []float64
+	var total float64
+	suiteOf := func(node string, instance string, start time.Time) int {
+		key := node + "/" + instance
+		index, ok := suiteIndex[key]
+		if !ok {
+			index = len(root.Suites)
+			suiteIndex[key] = index
+			suite := junitTestSuite{
+				Name: key,
+				Properties: []junitProperty{
+					{Name: "node", Value: node},
+					{Name: "instance", Value: instance},
+				},
+			}
+			if !start.IsZero() {
+				suite.Timestamp = start.Format("2006-01-02T15:04:05")
+			}
+			root.Suites = append(root.Suites, suite)
+			suiteTime = append(suiteTime, 0)
+		}
+		return index
+	}
+	for _, check := range checks {
+		index := suiteOf(check.Node, check.Instance, check.Start)
+		suite := &root.Suites[index]
+		testCase := junitTestCase{
+			Name:      fmt.Sprintf("%s #%d", check.Name, check.ID),
+			ClassName: junitClassName(check.Node, check.Instance),
+			Time:      fmt.Sprintf("%.3f", check.Duration.Seconds()),
+			SystemOut: fmt.Sprintf("sent: %s\nrecv: %s", check.Sent, check.Recv),
+		}
+		if failure := junitFailureOf(findingsOf[check.ID]); failure != nil {
+			testCase.Failures = []junitFailure{*failure}
+		}
+		suite.Tests++
+		if len(testCase.Failures) > 0 || !check.Passed {
+			suite.Failures++
+			root.Failures++
+		}
+		suite.Cases = append(suite.Cases, testCase)
+		root.Tests++
+		suiteTime[index] += check.Duration.Seconds()
+		total += check.Duration.Seconds()
+	}
+	for i, f := range findingsOf[0] {
+		index := suiteOf(f.Node, f.Instance, time.Time{})
+		suite := &root.Suites[index]
+		suite.Cases = append(suite.Cases, junitTestCase{
+			Name:      fmt.Sprintf("%s (outside checks) #%d", f.RuleID, i+1),
+			ClassName: junitClassName(f.Node, f.Instance),
+			Time:      "0.000",
+			Failures:  []junitFailure{*junitFailureOf([]Finding{f})},
+		})
+		suite.Tests++
+		suite.Failures++
+		root.Tests++
+		root.Failures++
+	}
+
+	for i := range root.Suites {
+		root.Suites[i].Time = fmt.Sprintf("%.3f", suiteTime[i])
+	}
+	sort.SliceStable(root.Suites, func(i, j int) bool {
+		return root.Suites[i].Name < root.Suites[j].Name
+	})
+	root.Time = fmt.Sprintf("%.3f", total)
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(root)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// junitClassName return classname of test cases of the instance of the node
+func junitClassName(node string, instance string) string {
+	return strings.Replace(node, ".", "_", -1) + "." + instance
+}
+
+// junitFailureOf return a failure which has all findings, nil if there is no finding.
+// JUnit allows only one failure per test case
+func junitFailureOf(findings []Finding) *junitFailure {
+	if len(findings) == 0 {
+		return nil
+	}
+	var types []string
+	var bodies []string
+	for _, f := range findings {
+		types = append(types, f.RuleID)
+		bodies = append(bodies, f.Message+"\n"+junitFailureBody(f))
+	}
+	message := findings[0].Message
+	if len(findings) > 1 {
+		message += fmt.Sprintf(" (and %d more findings)", len(findings)-1)
+	}
+	return &junitFailure{
+		Message: message,
+		Type:    strings.Join(types, ","),
+		Body:    strings.Join(bodies, "\n"),
+	}
+}
+
+// junitFailureBody describe the finding and its evidence
+func junitFailureBody(f Finding) string {
+	body := fmt.Sprintf("rule: %s\nseverity: %s\nspec: %s\n", f.RuleID, f.Severity, f.SpecRef)
+	if f.EPC != "" {
+		body += fmt.Sprintf("EPC: %s\n", f.EPC)
+	}
+	body += fmt.Sprintf("sent: %s\nrecv: %s\n", f.Sent, f.Recv)
+	if f.Minimized != "" {
+		body += fmt.Sprintf("minimized: %s\n", f.Minimized)
+	}
+	return body
+}
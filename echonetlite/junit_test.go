@@ -0,0 +1,35 @@
+package echonetlite
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func Test_WriteJUnit(t *testing.T) {
+	node := newTestNode()
+	sent := FrameFormat{EHD1: 0x10, EHD2: 0x81, TID: 0x0001, DEOJ: [3]uint8{0x01, 0x30, 0x01}, ESV: 0x62, OPC: 0x01, VarGroups: []VarByteGroup{{EPC: 0x80}}}
+	node.Instances = []Instance{{ClassCode: sent.DEOJ}}
+	node.Check(&sent, &FrameFormat{})
+	scope := node.beginCheck("Twice", sent.DEOJ, frameHex(&sent), "")
+	node.report(Finding{RuleID: "EPC-MISSING", Message: "first"})
+	node.report(Finding{RuleID: "EPC-UNEXPECTED", Message: "second"})
+	node.endCheck(scope)
+	node.report(Finding{RuleID: "RECV-TRUNCATED", Instance: "013001", Message: "unscoped"})
+
+	var buf bytes.Buffer
+	err := node.result.WriteJUnit(&buf)
+	if err != nil {
+		t.Fatalf("WriteJUnit => %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{`<testsuite name="192.0.2.1/013001" tests="3" failures="3"`, `<testcase name="Check #1"`, `type="RECV-NONE"`, "sent: 1081000100000001300162018000",
+		`message="first (and 1 more findings)" type="EPC-MISSING,EPC-UNEXPECTED"`, `<testcase name="RECV-TRUNCATED (outside checks) #1"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("JUnit XML doesn't contain %q\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, "<failure "); n != 3 {
+		t.Errorf("JUnit XML has %d failures, want 1 per test case", n)
+	}
+}
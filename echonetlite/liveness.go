@@ -0,0 +1,232 @@
+package echonetlite
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+// liveness watches whether a node is alive during fuzzing.
+// Get of 0x80 (operation status) and 0xD6 (instance list) on the node profile is sent as heartbeat
+type liveness struct {
+	node      *Node
+	every     int           // cases sent between probes
+	timeout   time.Duration // waiting a reply to a probe
+	recovery  time.Duration // waiting a node recovers
+	interval  time.Duration // between probes while waiting recovery
+	instances []uint8       // EDT of 0xD6 of the node seen last
+	pending   []FuzzCase    // cases sent since the last probe succeeded
+}
+
+// probeResult is the result of a heartbeat probe
+type probeResult struct {
+	alive     bool
+	instances []uint8 // EDT of 0xD6
+	announced bool    // instance list change announcement (INF of 0xD5) was received
+}
+
+// newLiveness create liveness of node probed every cases and waiting recovery, and probe it to know its instance list
+func newLiveness(node *Node, every int, recovery time.Duration) *liveness {
+	l := &liveness{
+		node:     node,
+		every:    every,
+		timeout:  node.replyTimeout(),
+		recovery: recovery,
+		interval: time.Second,
+	}
+	if probe := l.probe(); probe.alive {
+		l.instances = probe.instances
+	}
+	return l
+}
+
+// probe send heartbeat to the node profile and wait the reply.
+// Announcements received meanwhile are recorded
+func (l *liveness) probe() probeResult {
+	var retProbe probeResult
+	payload := FrameFormat{
+		EHD1: 0x10,
+		EHD2: 0x81,
+		TID:  l.node.client.nextTID(),
+		SEOJ: l.node.client.SEOJ,
+		DEOJ: [3]uint8{0x0E, 0xF0, 0x01},
+		ESV:  0x62,
+		OPC:  0x02,
+		VarGroups: []VarByteGroup{
+			{EPC: 0x80, PDC: 0x00},
+			{EPC: 0xD6, PDC: 0x00},
+		},
+	}
+	notifications, stop := l.node.client.listen()
+	defer stop()
+	ctx, cancel := context.WithTimeout(l.node.context(), l.timeout)
+	defer cancel()
+	var recv FrameFormat
+	var err error
+	for {
+		recv, err = l.node.client.Exchange(ctx, payload)
+		var malformed *MalformedError
+		if !xerrors.As(err, &malformed) {
+			break
+		}
+		// a malformed late reply to a fuzz case. Heartbeat is sent again
+	}
+	for drained := false; !drained; {
+		select {
+		case n := <-notifications:
+			retProbe.announced = retProbe.announced || isInstanceAnnouncement(n)
+		default:
+			drained = true
+		}
+	}
+	if err != nil {
+		l.node.logger.Error("No reply to heartbeat", zap.String("message", err.Error()))
+		return retProbe
+	}
+	retProbe.alive = true
+	for _, group := range recv.VarGroups {
+		if group.EPC == 0xD6 {
+			retProbe.instances = group.EDT
+		}
+	}
+	return retProbe
+}
+
+// isInstanceAnnouncement return whether n is the notification of instance list change (0xD5) from node profile
+func isInstanceAnnouncement(n Notification) bool {
+	if n.SEOJ[0] != 0x0E || n.SEOJ[1] != 0xF0 {
+		return false
+	}
+	for _, group := range n.Properties {
+		if group.EPC == 0xD5 {
+			return true
+		}
+	}
+	return false
+}
+
+// after record c sent and probe the node every l.every cases.
+// If the node hangs or reboots, the cases sent since the last probe are reported as the cause
+// and after waits until the node recovers. Error is returned if the node does not recover
+func (l *liveness) after(c FuzzCase, stats *FuzzStats) error {
+	l.pending = append(l.pending, c)
+	if len(l.pending) < l.every {
+		return nil
+	}
+	return l.check(stats)
+}
+
+// flush probe the node for the cases not probed yet
+func (l *liveness) flush(stats *FuzzStats) error {
+	if len(l.pending) == 0 {
+		return nil
+	}
+	return l.check(stats)
+}
+
+func (l *liveness) check(stats *FuzzStats) error {
+	probe := l.probe()
+	if err := l.node.canceled(); err != nil {
+		// no reply because the operation is interrupted
+		return err
+	}
+	if probe.alive && !probe.announced && l.rebooted(probe.instances) == "" {
+		l.instances = probe.instances
+		l.pending = nil
+		return nil
+	}
+
+	cases := l.pending
+	l.pending = nil
+	suspects := caseRange(cases)
+	if !probe.alive {
+		stats.Hangs++
+		l.node.logger.Error("Heartbeat failed", zap.String("cases", suspects))
+		l.reportLiveness("LIVENESS-HANG", cases, fmt.Sprintf("No reply to heartbeat after %s", suspects))
+		l.markSuspects(cases)
+
+		probe = l.waitRecovery()
+		if err := l.node.canceled(); err != nil {
+			return err
+		} else if !probe.alive {
+			l.reportLiveness("LIVENESS-LOST", cases, fmt.Sprintf("Device did not recover within %s after %s", l.recovery, suspects))
+			return xerrors.Errorf("Device %s did not recover after %s", l.node.ip, suspects)
+		}
+	} else {
+		l.markSuspects(cases)
+	}
+
+	if reason := l.rebooted(probe.instances); reason != "" || probe.announced {
+		if reason == "" {
+			reason = "instance list change was announced"
+		}
+		stats.Reboots++
+		l.node.logger.Error("Reboot detected", zap.String("reason", reason), zap.String("cases", suspects))
+		l.reportLiveness("LIVENESS-REBOOT", cases, fmt.Sprintf("Device rebooted (%s) after %s", reason, suspects))
+	}
+	l.instances = probe.instances
+	return nil
+}
+
+// rebooted return why the node looks rebooted. Empty string means not rebooted
+func (l *liveness) rebooted(instances []uint8) string {
+	if l.instances != nil && instances != nil && !bytes.Equal(l.instances, instances) {
+		return fmt.Sprintf("instance list changed from %X to %X", l.instances, instances)
+	}
+	return ""
+}
+
+// waitRecovery probe the node until it replies or l.recovery passes
+func (l *liveness) waitRecovery() probeResult {
+	var retProbe probeResult
+	deadline := time.Now().Add(l.recovery)
+	announced := false
+	for time.Now().Before(deadline) && l.node.canceled() == nil {
+		time.Sleep(l.interval)
+		retProbe = l.probe()
+		announced = announced || retProbe.announced
+		if retProbe.alive {
+			l.node.logger.Info("Device recovered")
+			break
+		}
+	}
+	retProbe.announced = announced
+	return retProbe
+}
+
+func (l *liveness) reportLiveness(ruleID string, cases []FuzzCase, message string) {
+	var sent string
+	if len(cases) > 0 {
+		sent = cases[len(cases)-1].Sent
+	}
+	defer l.node.endCheck(l.node.beginCheck("Liveness", [3]uint8{0x0E, 0xF0, 0x01}, sent, ""))
+	l.node.report(Finding{RuleID: ruleID, Message: message})
+}
+
+func (l *liveness) markSuspects(cases []FuzzCase) {
+	if l.node.result == nil {
+		return
+	}
+	l.node.result.MarkSuspects(cases)
+}
+
+// caseRange return the strategy and indexes of cases as text
+func caseRange(cases []FuzzCase) string {
+	if len(cases) == 0 {
+		return "no cases"
+	}
+	first, last := cases[0], cases[len(cases)-1]
+	if len(cases) == 1 {
+		return fmt.Sprintf("%s case #%d", first.Strategy, first.Index)
+	}
+	strategies := []string{first.Strategy}
+	if last.Strategy != first.Strategy {
+		strategies = append(strategies, last.Strategy)
+	}
+	return fmt.Sprintf("%s cases #%d-#%d", strings.Join(strategies, ", "), first.Index, last.Index)
+}
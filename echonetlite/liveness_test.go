@@ -0,0 +1,55 @@
+package echonetlite
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_liveness(t *testing.T) {
+	node := newTestNode()
+	before := []uint8{0x01, 0x01, 0x30, 0x01}
+	after := []uint8{0x02, 0x01, 0x30, 0x01, 0x01, 0x30, 0x02}
+	// baseline, hang, recovered with another instance list
+	fakeDevice(t, node, [][]uint8{before, nil, after})
+
+	live := newLiveness(node, 2, time.Second)
+	live.timeout = 200 * time.Millisecond
+	live.interval = 10 * time.Millisecond
+	if string(live.instances) != string(before) {
+		t.Fatalf("instances => %X, want %X", live.instances, before)
+	}
+
+	stats := FuzzStats{Strategy: "Test"}
+	cases := []FuzzCase{
+		{Strategy: "Test", Index: 1, Sent: "01", SentAt: time.Now()},
+		{Strategy: "Test", Index: 2, Sent: "02", SentAt: time.Now()},
+	}
+	for _, c := range cases {
+		node.result.AddFuzzCase(c)
+		err := live.after(c, &stats)
+		if err != nil {
+			t.Fatalf("after => %v", err)
+		}
+	}
+	if stats.Hangs != 1 || stats.Reboots != 1 {
+		t.Errorf("hangs, reboots => %d, %d, want 1, 1", stats.Hangs, stats.Reboots)
+	}
+	rules := make(map[string]bool)
+	for _, f := range node.result.Findings {
+		rules[f.RuleID] = true
+		if f.Sent != "02" {
+			t.Errorf("%s is attributed to %s, want 02", f.RuleID, f.Sent)
+		}
+	}
+	if !rules["LIVENESS-HANG"] || !rules["LIVENESS-REBOOT"] {
+		t.Errorf("findings => %v, want LIVENESS-HANG and LIVENESS-REBOOT", rules)
+	}
+	for _, c := range node.result.FuzzCases {
+		if !c.Suspect {
+			t.Errorf("case #%d is not marked as suspect", c.Index)
+		}
+	}
+	if string(live.instances) != string(after) {
+		t.Errorf("instances => %X, want %X", live.instances, after)
+	}
+}
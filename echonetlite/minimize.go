@@ -0,0 +1,327 @@
+package echonetlite
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// minimizeRules are rules of findings whose cases are minimized and the symptom reproduced
+var minimizeRules = map[string]string{
+	"LIVENESS-HANG":           symptomHang,
+	"RECV-TRUNCATED":          symptomMalformed,
+	"FUZZ-MALFORMED-REPLY":    symptomMalformed,
+	"FUZZ-EHD-ACCEPTED":       symptomESV,
+	"FUZZ-ESV-ACCEPTED":       symptomESV,
+	"FUZZ-MALFORMED-ACCEPTED": symptomESV,
+	"BOUNDARY-ACCEPTED":       symptomESV,
+	"PROFILE-SET-ACCEPTED":    symptomESV,
+	"FLOW-ESV":                symptomESV,
+	"FLOW-ESV-INVALID":        symptomESV,
+}
+
+// Symptoms a minimized case should reproduce
+const (
+	symptomHang      = "hang"      // heartbeat fails after the case
+	symptomMalformed = "malformed" // reply is not ECHONET Lite frame
+	symptomESV       = "esv"       // reply has the same ESV as the original reply
+)
+
+// symptom is the behavior of device a case triggered
+type symptom struct {
+	kind string
+	esv  uint8
+}
+
+// minimizer reduce a case with delta debugging while reproduce returns true
+type minimizer struct {
+	reproduce func(data []byte) bool
+	tests     int // cases sent
+	max       int // cases sent at most
+}
+
+// test return whether data reproduces the symptom. After m.max tests, it always returns false
+func (m *minimizer) test(data []byte) bool {
+	if m.tests >= m.max {
+		return false
+	}
+	m.tests++
+	return m.reproduce(data)
+}
+
+// ddmin return the minimal subset of indexes [0, n) for which test returns true.
+// test(all indexes) is assumed to be true
+func ddmin(n int, test func(keep []int) bool) []int {
+	items := make([]int, n)
+	for i := range items {
+		items[i] = i
+	}
+	granularity := 2
+	for len(items) >= 2 {
+		chunks := splitIndexes(items, granularity)
+		reduced := false
+		// a chunk alone
+		for _, chunk := range chunks {
+			if test(chunk) {
+				items = chunk
+				granularity = 2
+				reduced = true
+				break
+			}
+		}
+		// complement of a chunk
+		if !reduced && granularity > 2 {
+			for i := range chunks {
+				var complement []int
+				for j, chunk := range chunks {
+					if i != j {
+						complement = append(complement, chunk...)
+					}
+				}
+				if test(complement) {
+					items = complement
+					granularity--
+					reduced = true
+					break
+				}
+			}
+		}
+		if !reduced {
+			if granularity >= len(items) {
+				break
+			}
+			granularity *= 2
+			if granularity > len(items) {
+				granularity = len(items)
+			}
+		}
+	}
+	if len(items) == 1 && test(nil) {
+		return nil
+	}
+	return items
+}
+
+// splitIndexes split items into n chunks
+func splitIndexes(items []int, n int) [][]int {
+	var retChunks [][]int
+	start := 0
+	for i := 0; i < n; i++ {
+		end := start + (len(items)-start)/(n-i)
+		if end > start {
+			retChunks = append(retChunks, items[start:end])
+		}
+		start = end
+	}
+	return retChunks
+}
+
+// pick return the bytes of data at indexes keep
+func pick(data []uint8, keep []int) []uint8 {
+	retData := make([]uint8, 0, len(keep))
+	for _, i := range keep {
+		retData = append(retData, data[i])
+	}
+	return retData
+}
+
+// minimize return the smallest frame which still reproduces the symptom of data.
+// Consistent frames are reduced per VarGroup, EDT and value. Inconsistent ones are reduced per byte after the header
+func (m *minimizer) minimize(data []byte) []byte {
+	frame, err := parser(data)
+	if err == nil && frame.ESV&0x0F != 0x0E && bytes.Equal(echonetToByte(*frame), data) {
+		return m.minimizeFrame(*frame)
+	}
+	return m.minimizeRaw(data)
+}
+
+func (m *minimizer) minimizeFrame(frame FrameFormat) []byte {
+	encode := func(groups []VarByteGroup) []byte {
+		candidate := frame
+		candidate.VarGroups = groups
+		candidate.OPC = uint8(len(groups))
+		return echonetToByte(candidate)
+	}
+
+	// drop VarGroups, OPC is reduced with them
+	groups := frame.VarGroups
+	keep := ddmin(len(groups), func(keep []int) bool {
+		var candidate []VarByteGroup
+		for _, i := range keep {
+			candidate = append(candidate, groups[i])
+		}
+		return m.test(encode(candidate))
+	})
+	var reduced []VarByteGroup
+	for _, i := range keep {
+		reduced = append(reduced, groups[i])
+	}
+
+	with := func(i int, edt []uint8) []VarByteGroup {
+		candidate := append([]VarByteGroup(nil), reduced...)
+		candidate[i] = VarByteGroup{EPC: reduced[i].EPC, PDC: uint8(len(edt)), EDT: edt}
+		return candidate
+	}
+	for i := range reduced {
+		// shrink EDT
+		edt := reduced[i].EDT
+		keepBytes := ddmin(len(edt), func(keep []int) bool {
+			return m.test(encode(with(i, pick(edt, keep))))
+		})
+		reduced[i] = VarByteGroup{EPC: reduced[i].EPC, PDC: uint8(len(keepBytes)), EDT: pick(edt, keepBytes)}
+
+		// simplify values
+		for j := range reduced[i].EDT {
+			if reduced[i].EDT[j] == 0x00 {
+				continue
+			}
+			simple := append([]uint8(nil), reduced[i].EDT...)
+			simple[j] = 0x00
+			if m.test(encode(with(i, simple))) {
+				reduced[i] = VarByteGroup{EPC: reduced[i].EPC, PDC: uint8(len(simple)), EDT: simple}
+			}
+		}
+	}
+	return encode(reduced)
+}
+
+func (m *minimizer) minimizeRaw(data []byte) []byte {
+	if len(data) <= offsetEPC1 {
+		return data
+	}
+	header := data[:offsetEPC1]
+	body := data[offsetEPC1:]
+	keep := ddmin(len(body), func(keep []int) bool {
+		return m.test(append(append([]byte(nil), header...), pick(body, keep)...))
+	})
+	retData := append(append([]byte(nil), header...), pick(body, keep)...)
+
+	// reduce OPC
+	for opc := retData[offsetOPC] / 2; opc < retData[offsetOPC]; opc = retData[offsetOPC] / 2 {
+		candidate := append([]byte(nil), retData...)
+		candidate[offsetOPC] = opc
+		if !m.test(candidate) {
+			break
+		}
+		retData = candidate
+		if opc == 0 {
+			break
+		}
+	}
+
+	// simplify values
+	for i := offsetEPC1; i < len(retData); i++ {
+		if retData[i] == 0x00 {
+			continue
+		}
+		candidate := append([]byte(nil), retData...)
+		candidate[i] = 0x00
+		if m.test(candidate) {
+			retData = candidate
+		}
+	}
+	return retData
+}
+
+// reproduces send data to node and return whether the device shows the symptom s.
+// The node is probed after every case, and a case leaving the node dead reproduces only the hang
+func (a *Auditor) reproduces(node *Node, live *liveness, s symptom, data []byte) bool {
+	if node.canceled() != nil {
+		return false
+	}
+	err := SendRaw(data, node.client.conn)
+	if err != nil {
+		node.logger.Error("Send packet Failed", zap.String("payload", hex.EncodeToString(data)))
+		return false
+	}
+	reply, err := node.recvReplyTo(data, live.timeout)
+	if probe := live.probe(); !probe.alive {
+		if node.canceled() != nil {
+			return false
+		}
+		node.logger.Warn("Node hangs after candidate", zap.String("symptom", s.kind), zap.String("payload", hex.EncodeToString(data)))
+		live.waitRecovery()
+		return s.kind == symptomHang
+	}
+	switch s.kind {
+	case symptomMalformed:
+		if err != nil {
+			return false
+		}
+		recv, err := parser(reply)
+		return err != nil || recv.EHD1 != 0x10 || recv.EHD2&0x80 != 0x80
+	case symptomESV:
+		if err != nil {
+			return false
+		}
+		recv, err := parser(reply)
+		return err == nil && recv.ESV == s.esv
+	}
+	return false
+}
+
+// findingCount return the number of findings of node stored in a.Result
+func (a *Auditor) findingCount(node *Node) int {
+	if a.Result == nil {
+		return 0
+	}
+	return a.Result.countNode(node.ip.String())
+}
+
+// minimizedCases are the original and the minimized case in HEX per rule, EPC and symptom.
+// Only the first case of them in a campaign is minimized
+type minimizedCases map[string][2]string
+
+// minimizeFindings minimize the cases of findings of node stored in a.Result since the from-th one
+// and store the smallest frames reproducing them with the findings
+func (a *Auditor) minimizeFindings(node *Node, live *liveness, from int, done minimizedCases) {
+	if a.Result == nil || a.MinimizeTests <= 0 {
+		return
+	}
+	for _, i := range a.Result.findingIndexesFrom(node.ip.String(), from) {
+		if node.canceled() != nil {
+			return
+		}
+		f := a.Result.finding(i)
+		kind, ok := minimizeRules[f.RuleID]
+		if !ok || f.Sent == "" {
+			continue
+		}
+		s := symptom{kind: kind}
+		if kind == symptomESV {
+			recv, err := hex.DecodeString(f.Recv)
+			if err != nil || len(recv) <= offsetESV {
+				continue
+			}
+			s.esv = recv[offsetESV]
+		}
+		key := fmt.Sprintf("%s/%s/%s/%02X", f.RuleID, f.EPC, s.kind, s.esv)
+		if cases, ok := done[key]; ok {
+			if cases[0] == f.Sent && cases[1] != "" {
+				a.Result.setMinimized(i, cases[1])
+			}
+			continue
+		}
+		done[key] = [2]string{f.Sent, ""}
+
+		data, err := hex.DecodeString(f.Sent)
+		if err != nil {
+			continue
+		}
+		m := &minimizer{
+			reproduce: func(data []byte) bool { return a.reproduces(node, live, s, data) },
+			max:       a.MinimizeTests,
+		}
+		// the original case must reproduce the symptom alone
+		if !m.test(data) {
+			node.logger.Info("Case does not reproduce alone", zap.String("rule", f.RuleID), zap.String("payload", f.Sent))
+			continue
+		}
+		minimized := hex.EncodeToString(m.minimize(data))
+		node.logger.Info("Minimized case", zap.String("rule", f.RuleID), zap.String("original", f.Sent), zap.String("minimized", minimized), zap.Int("tests", m.tests))
+		done[key] = [2]string{f.Sent, minimized}
+		a.Result.setMinimized(i, minimized)
+	}
+}
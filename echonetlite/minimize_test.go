@@ -0,0 +1,122 @@
+package echonetlite
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func Test_ddmin(t *testing.T) {
+	// 3 and 7 are needed
+	keep := ddmin(10, func(keep []int) bool {
+		found := 0
+		for _, i := range keep {
+			if i == 3 || i == 7 {
+				found++
+			}
+		}
+		return found == 2
+	})
+	if len(keep) != 2 || keep[0] != 3 || keep[1] != 7 {
+		t.Errorf("ddmin => %v, want [3 7]", keep)
+	}
+
+	if keep := ddmin(4, func(keep []int) bool { return true }); keep != nil {
+		t.Errorf("ddmin => %v, want empty", keep)
+	}
+}
+
+func Test_minimizer(t *testing.T) {
+	frame := FrameFormat{
+		EHD1: 0x10,
+		EHD2: 0x81,
+		TID:  0x0001,
+		SEOJ: [3]uint8{0x0E, 0xF0, 0x01},
+		DEOJ: [3]uint8{0x01, 0x30, 0x01},
+		ESV:  0x61,
+		OPC:  0x03,
+		VarGroups: []VarByteGroup{
+			{EPC: 0x80, PDC: 0x01, EDT: []uint8{0x30}},
+			{EPC: 0xB3, PDC: 0x04, EDT: []uint8{0x12, 0xFF, 0x34, 0x56}},
+			{EPC: 0xB0, PDC: 0x01, EDT: []uint8{0x41}},
+		},
+	}
+	// device misbehaves if EDT of 0xB3 has 0xFF
+	m := &minimizer{
+		reproduce: func(data []byte) bool {
+			recv, err := parser(data)
+			if err != nil {
+				return false
+			}
+			for _, group := range recv.VarGroups {
+				if group.EPC == 0xB3 && bytes.IndexByte(group.EDT, 0xFF) >= 0 {
+					return true
+				}
+			}
+			return false
+		},
+		max: 1000,
+	}
+	got := m.minimize(echonetToByte(frame))
+	want := frame
+	want.OPC = 0x01
+	want.VarGroups = []VarByteGroup{{EPC: 0xB3, PDC: 0x01, EDT: []uint8{0xFF}}}
+	if !bytes.Equal(got, echonetToByte(want)) {
+		t.Errorf("minimize => %X, want %X", got, echonetToByte(want))
+	}
+
+	// inconsistent frame whose OPC is larger than groups
+	raw := echonetToByte(frame)
+	raw[offsetOPC] = 0x10
+	m.reproduce = func(data []byte) bool {
+		return len(data) > offsetOPC && data[offsetOPC] > 0x03
+	}
+	m.tests = 0
+	got = m.minimize(raw)
+	if len(got) != offsetEPC1 || got[offsetOPC] != 0x04 {
+		t.Errorf("minimize => %X, want header only with OPC 04", got)
+	}
+}
+
+func Test_reproduces(t *testing.T) {
+	node := newTestNode()
+	dead := false
+	node.client, _ = newFakeClient(t, func(req *FrameFormat) []byte {
+		reply := FrameFormat{EHD1: 0x10, EHD2: 0x81, TID: req.TID, SEOJ: req.DEOJ, DEOJ: req.SEOJ, ESV: 0x72, OPC: 0x01, VarGroups: []VarByteGroup{{EPC: 0x80, PDC: 0x01, EDT: []uint8{0x30}}}}
+		if req.ESV == 0x62 {
+			// heartbeat is not replied once after 0xFF
+			if dead {
+				dead = false
+				return nil
+			}
+			return echonetToByte(reply)
+		}
+		dead = req.VarGroups[0].EDT[0] == 0xFF
+		reply.ESV = 0x51
+		reply.VarGroups = req.VarGroups
+		return echonetToByte(reply)
+	})
+	live := newLiveness(node, 1, time.Second)
+	live.timeout = 200 * time.Millisecond
+	live.interval = 10 * time.Millisecond
+
+	a := &Auditor{}
+	candidate := func(edt uint8) []byte {
+		return echonetToByte(FrameFormat{EHD1: 0x10, EHD2: 0x81, TID: 0x0001, SEOJ: node.client.SEOJ, DEOJ: [3]uint8{0x01, 0x30, 0x01}, ESV: 0x61, OPC: 0x01, VarGroups: []VarByteGroup{{EPC: 0x80, PDC: 0x01, EDT: []uint8{edt}}}})
+	}
+	cases := []struct {
+		s    symptom
+		edt  uint8
+		want bool
+	}{
+		{symptom{kind: symptomESV, esv: 0x51}, 0x30, true},
+		{symptom{kind: symptomESV, esv: 0x51}, 0xFF, false}, // the node hangs
+		{symptom{kind: symptomHang}, 0x30, false},
+		{symptom{kind: symptomHang}, 0xFF, true},
+	}
+	for _, c := range cases {
+		if got := a.reproduces(node, live, c.s, candidate(c.edt)); got != c.want {
+			t.Errorf("reproduces %s with EDT %02X => %v, want %v", c.s.kind, c.edt, got, c.want)
+		}
+	}
+}
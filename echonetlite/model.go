@@ -2,7 +2,10 @@ package echonetlite
 
 import (
 	"net"
+	"sync"
+	"time"
 
+	"github.com/tttfrfr2/ECHONETTester/util"
 	"go.uber.org/zap"
 )
 
@@ -152,18 +155,39 @@ type DateTime struct {
 
 // Auditor is ECHONET Lite test struct
 type Auditor struct {
-	SrcNodes  []Node // Tester ECHONET Lite nodes
-	DistNodes []Node // Target ECHONET Lite nodes
-	logger    *zap.Logger
+	SrcNodes  []Node               // Tester ECHONET Lite nodes
+	DistNodes []Node               // Target ECHONET Lite nodes
+	Result    *Result              // Checks and findings of this run
+	Seed      int64                // Seed of the run. If 0, NewAuditor decides it from time
+	Release   string               // Appendix release of nodes like "M". If empty, it is detected from 0x82 at discovery
+	ResultDir string               // Directory reports are output. If empty, resultDir in Config or "result"
+	Config    util.EchonetLiteConf // Settings of nodes, class definitions and directories from config file
+	RunID     string               // ID of the run naming logs and results. If empty, NewAuditor decides it from time
+
+	LivenessEvery    int           // Fuzz cases sent between heartbeat probes. If 0, NewAuditor set 10
+	LivenessRecovery time.Duration // Time waiting a device recovers from hang or reboot. If 0, NewAuditor set 120 seconds
+	MinimizeTests    int           // Cases sent at most to minimize a case of finding. If 0, NewAuditor set 100, and if negative, no case is minimized
+	Prompter         Prompter      // User of interactive flows. If nil, NewAuditor uses stdin and stdout
+
+	checkpoints *checkpointer // progress of campaigns
+	receiver    *receiver     // dispatcher of packets received to nodes
+	op          *operation    // cancellation of the operation running now, shared with nodes
+	closeOnce   sync.Once     // sockets are closed once by Close
+	logger      *zap.Logger
 }
 
 // Node has the imformation of ECHONET Lite node
 type Node struct {
-	ip        net.IP
-	connSend  net.Conn
-	connRecv  *net.UDPConn
-	Instances []Instance // Instances in Node
-	logger    *zap.Logger
+	ip          net.IP
+	client      *Client       // requests and packets to and from the node
+	timeout     time.Duration // waiting a reply. If 0, defaultReplyTimeout
+	prompter    Prompter      // user of interactive flows, shared with Auditor
+	op          *operation    // cancellation of the operation running now, shared with Auditor
+	definitions []byte        // "definitions" of class definitions referred by $ref
+	Instances   []Instance    // Instances in Node
+	logger      *zap.Logger
+	result      *Result
+	scope       *checkScope // the check running now
 }
 
 type SettingECHONET struct {
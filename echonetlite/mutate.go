@@ -0,0 +1,412 @@
+package echonetlite
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+// mutation is a packet mutated from encoded valid frame.
+// data may be inconsistent, so it is sent without echonetToByte
+type mutation struct {
+	strategy string
+	name     string
+	data     []byte
+}
+
+// Strategies of FrameFuzz
+const (
+	strategyEHD      = "EHD"
+	strategyTID      = "TID"
+	strategyESV      = "ESV"
+	strategyOPC      = "OPC"
+	strategyPDC      = "PDC"
+	strategyTruncate = "Truncate"
+	strategyTrailing = "Trailing"
+	strategySize     = "Size"
+)
+
+// mutationStrategies are strategies of FrameFuzz in the order they are executed
+var mutationStrategies = []string{strategyEHD, strategyTID, strategyESV, strategyOPC, strategyPDC, strategyTruncate, strategyTrailing, strategySize}
+
+// Datagram sizes of Size strategy
+const (
+	sizeUnfragmented = 1472  // maximum UDP payload not fragmented on Ethernet (MTU 1500)
+	sizeOldBuffer    = 4096  // receive buffer size of older versions of this tool
+	sizeMaxUDP       = 65507 // maximum UDP payload over IPv4
+)
+
+// offsets of fields in encoded frame
+const (
+	offsetEHD1 = 0
+	offsetEHD2 = 1
+	offsetTID  = 2
+	offsetESV  = 10
+	offsetOPC  = 11
+	offsetEPC1 = 12
+)
+
+// sizedFrame return a frame of size bytes whose header is the one of valid.
+// It is filled with groups of epc whose PDC is 0xFF as possible and OPC is the count of them
+func sizedFrame(valid []byte, epc uint8, size int) []byte {
+	data := append([]byte(nil), valid[:offsetEPC1]...)
+	opc := 0
+	for len(data)+2 <= size && opc < 0xFF {
+		rest := size - len(data)
+		pdc := rest - 2
+		if pdc > 0xFF {
+			pdc = 0xFF
+			// a byte left can't be a group
+			if rest-2-pdc == 1 {
+				pdc--
+			}
+		}
+		data = append(data, epc, uint8(pdc))
+		data = append(data, make([]byte, pdc)...)
+		opc++
+	}
+	data[offsetOPC] = uint8(opc)
+	return data
+}
+
+// unknownESVs are ESVs which are not request services or are not defined
+var unknownESVs = []uint8{0x00, 0x01, 0x50, 0x5F, 0x64, 0x65, 0x6D, 0x6F, 0x71, 0x72, 0x7A, 0x7E, 0x7F, 0x80, 0xFF}
+
+// mutateFrame generate mutations of base frame.
+// r is used to generate trailing garbage
+func mutateFrame(base FrameFormat, r *rand.Rand) []mutation {
+	var retMutations []mutation
+	valid := echonetToByte(base)
+	mutate := func(strategy string, name string, change func(data []byte) []byte) {
+		data := append([]byte(nil), valid...)
+		retMutations = append(retMutations, mutation{strategy: strategy, name: name, data: change(data)})
+	}
+
+	// EHD1, EHD2 variants
+	for _, ehd1 := range []uint8{0x00, 0x11, 0x1F, 0x90, 0xFF} {
+		ehd1 := ehd1
+		mutate(strategyEHD, fmt.Sprintf("EHD1=%02X", ehd1), func(data []byte) []byte {
+			data[offsetEHD1] = ehd1
+			return data
+		})
+	}
+	// 0x82 is format 2, whose EDATA is free
+	for _, ehd2 := range []uint8{0x00, 0x80, 0x01, 0xFF} {
+		ehd2 := ehd2
+		mutate(strategyEHD, fmt.Sprintf("EHD2=%02X", ehd2), func(data []byte) []byte {
+			data[offsetEHD2] = ehd2
+			return data
+		})
+	}
+
+	// TID edge cases
+	for _, tid := range []uint16{0x0000, 0x0001, 0x7FFF, 0x8000, 0xFFFF} {
+		tid := tid
+		mutate(strategyTID, fmt.Sprintf("TID=%04X", tid), func(data []byte) []byte {
+			data[offsetTID] = uint8(tid >> 8)
+			data[offsetTID+1] = uint8(tid & 0xFF)
+			return data
+		})
+	}
+
+	// unknown ESV
+	for _, esv := range unknownESVs {
+		esv := esv
+		mutate(strategyESV, fmt.Sprintf("ESV=%02X", esv), func(data []byte) []byte {
+			data[offsetESV] = esv
+			return data
+		})
+	}
+
+	// OPC larger or smaller than actual groups
+	groups := len(base.VarGroups)
+	for _, opc := range []int{0, groups - 1, groups + 1, 0xFF} {
+		if opc < 0 || opc > 0xFF || opc == groups {
+			continue
+		}
+		opc := opc
+		mutate(strategyOPC, fmt.Sprintf("OPC=%02X (groups:%d)", opc, groups), func(data []byte) []byte {
+			data[offsetOPC] = uint8(opc)
+			return data
+		})
+	}
+
+	// PDC larger or smaller than EDT
+	offset := offsetEPC1
+	for i, group := range base.VarGroups {
+		offsetPDC := offset + 1
+		for _, pdc := range []int{0, len(group.EDT) - 1, len(group.EDT) + 1, 0xFF} {
+			if pdc < 0 || pdc > 0xFF || pdc == len(group.EDT) {
+				continue
+			}
+			pdc := pdc
+			mutate(strategyPDC, fmt.Sprintf("PDC%d=%02X (EDT:%d)", i+1, pdc, len(group.EDT)), func(data []byte) []byte {
+				data[offsetPDC] = uint8(pdc)
+				return data
+			})
+		}
+		offset += 2 + len(group.EDT)
+	}
+
+	// truncation at every offset
+	for i := 1; i < len(valid); i++ {
+		length := i
+		mutate(strategyTruncate, fmt.Sprintf("Length=%d", length), func(data []byte) []byte {
+			return data[:length]
+		})
+	}
+
+	// trailing garbage
+	garbage := make([]byte, 64)
+	r.Read(garbage)
+	trailings := []struct {
+		name string
+		data []byte
+	}{
+		{"Trailing 00", []byte{0x00}},
+		{"Trailing FF x16", []byte(strings.Repeat("\xFF", 16))},
+		{"Trailing random", garbage},
+		{"Trailing frame", valid},
+		{"Trailing EPC only", []byte{0x80}},
+	}
+	for _, trailing := range trailings {
+		trailing := trailing
+		mutate(strategyTrailing, trailing.name, func(data []byte) []byte {
+			return append(data, trailing.data...)
+		})
+	}
+
+	// datagrams around MTU and maximum UDP payload, and the empty one
+	epc := valid[offsetEPC1]
+	for _, size := range []int{sizeUnfragmented, sizeUnfragmented + 1, sizeOldBuffer, sizeOldBuffer + 1, sizeMaxUDP} {
+		size := size
+		mutate(strategySize, fmt.Sprintf("Size=%d", size), func(data []byte) []byte {
+			return sizedFrame(data, epc, size)
+		})
+	}
+	mutate(strategySize, fmt.Sprintf("OPC=FF PDC=FF cut at %d", sizeMaxUDP), func(data []byte) []byte {
+		data = append(data[:offsetEPC1], make([]byte, 0xFF*(2+0xFF))...)
+		data[offsetOPC] = 0xFF
+		for i := 0; i < 0xFF; i++ {
+			data[offsetEPC1+i*(2+0xFF)] = epc
+			data[offsetEPC1+i*(2+0xFF)+1] = 0xFF
+		}
+		return data[:sizeMaxUDP]
+	})
+	mutate(strategySize, "Length=0", func(data []byte) []byte {
+		return data[:0]
+	})
+	return retMutations
+}
+
+// frameFuzzBases return valid frames from seoj mutated in FrameFuzz against inst
+func frameFuzzBases(inst Instance, seoj [3]uint8, r *rand.Rand) []FrameFormat {
+	get := FrameFormat{
+		EHD1: 0x10,
+		EHD2: 0x81,
+		TID:  uint16(r.Intn(0xFFFF)),
+		SEOJ: seoj,
+		DEOJ: inst.ClassCode,
+		ESV:  0x62,
+	}
+	for _, epc := range []uint8{0x80, 0x9F} {
+		get.VarGroups = append(get.VarGroups, VarByteGroup{EPC: epc, PDC: 0x00})
+	}
+	get.OPC = uint8(len(get.VarGroups))
+	bases := []FrameFormat{get}
+
+	for _, prop := range inst.Props {
+		if !prop.ImplementSet || len(prop.Data) == 0 {
+			continue
+		}
+		edt, err := RandProp(prop, r)
+		if err != nil || len(edt) == 0 {
+			continue
+		}
+		setC := get
+		setC.ESV = 0x61
+		setC.OPC = 0x01
+		setC.VarGroups = []VarByteGroup{{EPC: prop.EPC, PDC: uint8(len(edt)), EDT: edt}}
+		bases = append(bases, setC)
+		break
+	}
+	return bases
+}
+
+// FrameFuzz send mutations of encoded valid frames to the instance designated by dstIP and dstCode.
+// Header, TID, ESV, OPC and PDC fields are mutated, frames are truncated and trailing garbage is added.
+// Replies are checked and the statistics per strategy are stored in a.Result
+func (a *Auditor) FrameFuzz(dstIP net.IP, dstCode [3]uint8) error {
+	a.printf("---Start Frame fuzzy---\n")
+	node, instIndex, err := a.searchInstane(dstIP, dstCode)
+	if err != nil {
+		a.logger.Error("Invalid Class Code", zap.String("IPaddr", dstIP.String()), zap.String("CLASSCODE", eojString(dstCode)))
+		return xerrors.Errorf("Invalid Class Code")
+	}
+	inst := node.Instances[instIndex]
+	node.logger.Info("Start Frame fuzzy", zap.String("instance", inst.ClassName))
+
+	campaign := a.beginCampaign("Frame Fuzz", dstIP.String(), eojString(dstCode), a.campaignSeed("Frame Fuzz", dstIP.String(), eojString(dstCode)))
+	r := rand.New(rand.NewSource(campaign.Seed))
+	var mutations []mutation
+	for _, base := range frameFuzzBases(inst, node.client.SEOJ, r) {
+		mutations = append(mutations, mutateFrame(base, r)...)
+	}
+	// execute strategy by strategy
+	order := make(map[string]int)
+	for i, strategy := range mutationStrategies {
+		order[strategy] = i
+	}
+	sort.SliceStable(mutations, func(i, j int) bool {
+		return order[mutations[i].strategy] < order[mutations[j].strategy]
+	})
+
+	stats := make(map[string]*FuzzStats)
+	for _, strategy := range mutationStrategies {
+		stat := campaign.statsOf("Frame Fuzz/" + strategy)
+		stats[strategy] = &stat
+	}
+	// statistics of strategies executed for checkpoint
+	executed := func() []FuzzStats {
+		var retStats []FuzzStats
+		for _, strategy := range mutationStrategies {
+			if stats[strategy].Sent > 0 {
+				retStats = append(retStats, *stats[strategy])
+			}
+		}
+		return retStats
+	}
+	defer func() {
+		if a.Result == nil {
+			return
+		}
+		for _, stat := range executed() {
+			a.Result.AddFuzz(stat)
+		}
+	}()
+
+	live := newLiveness(&node, a.LivenessEvery, a.LivenessRecovery)
+	minimized := make(minimizedCases)
+	for i, m := range mutations {
+		if i < campaign.Next {
+			// sent before the checkpoint resumed
+			continue
+		}
+		if err := node.canceled(); err != nil {
+			return xerrors.Errorf("Frame fuzzy stopped: %w", err)
+		}
+		stat := stats[m.strategy]
+		if stat.Sent == 0 {
+			stat.Start = time.Now()
+		}
+		findingsBefore := a.findingCount(&node)
+
+		node.logger.Info("sent packet", zap.String("strategy", m.strategy), zap.String("mutation", m.name), zap.String("payload", hex.EncodeToString(m.data)))
+		fuzzCase := FuzzCase{
+			Strategy: stat.Strategy,
+			Node:     stat.Node,
+			Instance: stat.Instance,
+			Index:    i + 1,
+			Sent:     hex.EncodeToString(m.data),
+			SentAt:   time.Now(),
+		}
+		err = SendRaw(m.data, node.client.conn)
+		if err != nil && m.strategy == strategySize {
+			// the OS may limit the size of datagrams sent
+			node.logger.Error("Send large packet Failed", zap.String("mutation", m.name), zap.String("message", err.Error()))
+			a.progress(campaign, i+1, executed()...)
+			continue
+		}
+		if err != nil {
+			node.logger.Error("Send packet Failed", zap.String("payload", fuzzCase.Sent))
+			return xerrors.Errorf("Failed to send packet at Frame fuzzy: %w", err)
+		}
+		stat.Sent++
+		reply, err := node.recvReplyTo(m.data, node.replyTimeout())
+		fuzzCase.RTT = time.Since(fuzzCase.SentAt)
+		if err != nil {
+			if !xerrors.Is(err, ErrTimeout) {
+				node.logger.Error("Receive packet Failed", zap.String("message", err.Error()))
+				return xerrors.Errorf("Failed to recieve packet at Frame fuzzy: %w", err)
+			}
+			fuzzCase.Timeout = true
+			stat.Timeouts++
+		} else {
+			fuzzCase.Recv = hex.EncodeToString(reply)
+			stat.Replied++
+			node.checkMutationReply(inst, m, reply)
+		}
+		a.recordCase(fuzzCase)
+		err = live.after(fuzzCase, stat)
+		stat.Findings += a.findingCount(&node) - findingsBefore
+		stat.Duration = time.Since(stat.Start)
+		if err != nil {
+			return xerrors.Errorf("Frame fuzzy stopped: %w", err)
+		}
+		a.minimizeFindings(&node, live, findingsBefore, minimized)
+		a.progress(campaign, i+1, executed()...)
+	}
+	if len(mutations) > 0 {
+		findingsBefore := a.findingCount(&node)
+		stat := stats[mutations[len(mutations)-1].strategy]
+		err = live.flush(stat)
+		stat.Findings += a.findingCount(&node) - findingsBefore
+		if err != nil {
+			return xerrors.Errorf("Frame fuzzy stopped: %w", err)
+		}
+		a.minimizeFindings(&node, live, findingsBefore, minimized)
+	}
+	a.endCampaign(campaign)
+	node.logger.Info("Finished Frame fuzzy", zap.String("instance", inst.ClassName))
+	return nil
+}
+
+// checkMutationReply check the reply to a mutated packet.
+// The device should ignore inconsistent requests or reply SNA
+func (node *Node) checkMutationReply(inst Instance, m mutation, reply []byte) {
+	defer node.endCheck(node.beginCheck("FrameFuzz/"+m.strategy, inst.ClassCode, hex.EncodeToString(m.data), hex.EncodeToString(reply)))
+
+	recv, err := node.parseReply(reply)
+	if err != nil && isTruncated(err) {
+		return
+	}
+	if err != nil || recv.EHD1 != 0x10 || recv.EHD2&0x80 != 0x80 {
+		node.report(Finding{RuleID: "FUZZ-MALFORMED-REPLY", Message: fmt.Sprintf("Malformed reply to %s: %v", m.name, err)})
+		return
+	}
+	positive := recv.ESV&0xF0 == 0x70
+	switch m.strategy {
+	case strategyEHD:
+		node.report(Finding{RuleID: "FUZZ-EHD-ACCEPTED", Message: fmt.Sprintf("Device replied ESV %02X to the packet whose header is invalid (%s)", recv.ESV, m.name)})
+	case strategyTID:
+		tid := uint16(m.data[offsetTID])<<8 | uint16(m.data[offsetTID+1])
+		if recv.TID != tid {
+			node.report(Finding{RuleID: "FLOW-TID", Message: fmt.Sprintf("Not Correspond: Transaction ID (sent:%04X, recv:%04X)", tid, recv.TID)})
+		}
+	case strategyESV:
+		if positive {
+			node.report(Finding{RuleID: "FUZZ-ESV-ACCEPTED", Message: fmt.Sprintf("Device accepted unknown ESV (%s) and replied ESV %02X", m.name, recv.ESV)})
+		}
+	case strategyOPC, strategyPDC, strategyTruncate:
+		if positive {
+			node.report(Finding{RuleID: "FUZZ-MALFORMED-ACCEPTED", Message: fmt.Sprintf("Device accepted inconsistent packet (%s) and replied ESV %02X", m.name, recv.ESV)})
+		}
+	case strategySize:
+		// sizes within the limits are consistent frames
+		if sent, err := parser(m.data); positive && (err != nil || !bytes.Equal(echonetToByte(*sent), m.data)) {
+			node.report(Finding{RuleID: "FUZZ-MALFORMED-ACCEPTED", Message: fmt.Sprintf("Device accepted inconsistent packet (%s) and replied ESV %02X", m.name, recv.ESV)})
+		}
+	case strategyTrailing:
+		if positive {
+			node.report(Finding{RuleID: "FUZZ-TRAILING-ACCEPTED", Message: fmt.Sprintf("Device accepted packet with trailing garbage (%s)", m.name)})
+		}
+	}
+}
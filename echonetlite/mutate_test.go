@@ -0,0 +1,118 @@
+package echonetlite
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+func Test_mutateFrame(t *testing.T) {
+	base := FrameFormat{
+		EHD1: 0x10,
+		EHD2: 0x81,
+		TID:  0x0102,
+		SEOJ: [3]uint8{0x0E, 0xF0, 0x01},
+		DEOJ: [3]uint8{0x01, 0x30, 0x01},
+		ESV:  0x61,
+		OPC:  0x01,
+		VarGroups: []VarByteGroup{
+			{EPC: 0x80, PDC: 0x01, EDT: []uint8{0x30}},
+		},
+	}
+	valid := echonetToByte(base)
+	mutations := mutateFrame(base, rand.New(rand.NewSource(1)))
+
+	count := make(map[string]int)
+	for _, m := range mutations {
+		count[m.strategy]++
+		if string(m.data) == string(valid) {
+			t.Errorf("mutation %s/%s is the same as valid frame", m.strategy, m.name)
+		}
+		switch m.strategy {
+		case strategyTruncate:
+			if len(m.data) >= len(valid) {
+				t.Errorf("truncated frame %s is not shorter than valid frame", m.name)
+			}
+		case strategyTrailing:
+			if len(m.data) <= len(valid) {
+				t.Errorf("frame with trailing garbage %s is not longer than valid frame", m.name)
+			}
+		case strategyPDC:
+			if m.data[13] == 0x01 {
+				t.Errorf("PDC of %s is not mutated", m.name)
+			}
+		case strategyOPC:
+			if m.data[offsetOPC] == 0x01 {
+				t.Errorf("OPC of %s is not mutated", m.name)
+			}
+		}
+	}
+	if count[strategyTruncate] != len(valid)-1 {
+		t.Errorf("truncated frames => %d, want %d", count[strategyTruncate], len(valid)-1)
+	}
+	for _, strategy := range mutationStrategies {
+		if count[strategy] == 0 {
+			t.Errorf("strategy %s generated no mutation", strategy)
+		}
+	}
+}
+
+func Test_sizedFrame(t *testing.T) {
+	valid := []byte{0x10, 0x81, 0x00, 0x01, 0x0E, 0xF0, 0x01, 0x01, 0x30, 0x01, 0x62, 0x01, 0x80, 0x00}
+	for _, size := range []int{14, 270, 271, sizeUnfragmented, sizeOldBuffer + 1, sizeMaxUDP} {
+		data := sizedFrame(valid, 0x80, size)
+		if len(data) != size {
+			t.Errorf("sizedFrame(%d) => %d bytes", size, len(data))
+			continue
+		}
+		frame, err := parser(data)
+		if err != nil || string(echonetToByte(*frame)) != string(data) {
+			t.Errorf("sizedFrame(%d) is not consistent: %v", size, err)
+		}
+	}
+}
+
+func Test_parseReply(t *testing.T) {
+	node := newTestNode()
+	// PDC declares 2 bytes but EDT is 1 byte
+	_, err := node.parseReply([]byte{0x10, 0x81, 0x00, 0x01, 0x01, 0x30, 0x01, 0x0E, 0xF0, 0x01, 0x72, 0x01, 0x80, 0x02, 0x30})
+	if err == nil || !isTruncated(err) {
+		t.Fatalf("parseReply => %v, want truncated", err)
+	}
+	if len(node.result.Findings) != 1 || node.result.Findings[0].RuleID != "RECV-TRUNCATED" {
+		t.Errorf("findings => %+v, want RECV-TRUNCATED", node.result.Findings)
+	}
+}
+
+func Test_recvReplyTo(t *testing.T) {
+	node := newTestNode()
+	node.client.inbox = make(chan []byte, 4)
+	node.client.Timeout = 100 * time.Millisecond
+	sent := []byte{0x10, 0x81, 0x00, 0x05, 0x0E, 0xF0, 0x01, 0x01, 0x30, 0x01, 0x62, 0x01, 0x80, 0x00}
+	announcement := []byte{0x10, 0x81, 0x00, 0x05, 0x01, 0x30, 0x01, 0x0E, 0xF0, 0x01, 0x73, 0x01, 0x80, 0x01, 0x30}
+	other := []byte{0x10, 0x81, 0x00, 0x04, 0x01, 0x30, 0x01, 0x0E, 0xF0, 0x01, 0x72, 0x01, 0x80, 0x01, 0x30}
+	reply := []byte{0x10, 0x81, 0x00, 0x05, 0x01, 0x30, 0x01, 0x0E, 0xF0, 0x01, 0x72, 0x01, 0x80, 0x01, 0x31}
+
+	node.client.inbox <- announcement
+	node.client.inbox <- other
+	node.client.inbox <- reply
+	got, err := node.recvReplyTo(sent, time.Second)
+	if err != nil || !bytes.Equal(got, reply) {
+		t.Errorf("recvReplyTo => %X, %v, want the reply with the TID", got, err)
+	}
+
+	node.client.inbox <- announcement
+	node.client.inbox <- other
+	got, err = node.recvReplyTo(sent, 100*time.Millisecond)
+	if err != nil || !bytes.Equal(got, other) {
+		t.Errorf("recvReplyTo without the reply with the TID => %X, %v, want the reply with another TID", got, err)
+	}
+
+	node.client.inbox <- announcement
+	if got, err := node.recvReplyTo(sent, 100*time.Millisecond); !xerrors.Is(err, ErrTimeout) {
+		t.Errorf("recvReplyTo of an announcement => %X, %v, want timeout", got, err)
+	}
+}
@@ -0,0 +1,264 @@
+package echonetlite
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+// maxListedInstances is the number of instances self-node instance list S (0xD6) has at most
+const maxListedInstances = 84
+
+// maxListedClasses is the number of classes self-node class list S (0xD7) has at most
+const maxListedClasses = 8
+
+// nodeProfileProps are properties of the node profile compared with each other
+var nodeProfileProps = []uint8{0x82, 0x83, 0x8A, 0xD3, 0xD4, 0xD6, 0xD7}
+
+// parseInstanceList parse an instance list like 0xD5 and 0xD6, the count and codes of 3 bytes
+func parseInstanceList(edt []uint8) ([][3]uint8, error) {
+	if len(edt) < 1 {
+		return nil, xerrors.Errorf("Instance list is empty")
+	}
+	count := int(edt[0])
+	if len(edt) != 1+count*3 {
+		return nil, xerrors.Errorf("Instance list has %d instances, but %d bytes", count, len(edt))
+	}
+	retList := make([][3]uint8, count)
+	for i := range retList {
+		copy(retList[i][:], edt[1+i*3:4+i*3])
+	}
+	return retList, nil
+}
+
+// parseClassList parse a class list like 0xD7, the count and codes of 2 bytes
+func parseClassList(edt []uint8) ([][2]uint8, error) {
+	if len(edt) < 1 {
+		return nil, xerrors.Errorf("Class list is empty")
+	}
+	count := int(edt[0])
+	if len(edt) != 1+count*2 {
+		return nil, xerrors.Errorf("Class list has %d classes, but %d bytes", count, len(edt))
+	}
+	retList := make([][2]uint8, count)
+	for i := range retList {
+		copy(retList[i][:], edt[1+i*2:3+i*2])
+	}
+	return retList, nil
+}
+
+// uintOf change big endian bytes into a number
+func uintOf(edt []uint8) int {
+	n := 0
+	for _, b := range edt {
+		n = n<<8 | int(b)
+	}
+	return n
+}
+
+// requestInf send INF_REQ of epc to the object eoj and return EDT of the notification replied.
+// If INF_SNA is replied, error is returned
+func (node *Node) requestInf(eoj [3]uint8, epc uint8) ([]uint8, error) {
+	ctx, cancel := context.WithTimeout(node.context(), node.replyTimeout())
+	defer cancel()
+	res, err := node.client.InfReq(ctx, eoj, epc)
+	var sna *SNAError
+	if xerrors.As(err, &sna) {
+		return nil, xerrors.Errorf("INF_REQ of %02X is not accepted: %w", epc, err)
+	} else if err != nil {
+		node.checkTruncated(err)
+		return nil, err
+	}
+	edt, _ := res.Value(epc)
+	return edt, nil
+}
+
+// ProfileCheck get properties of the node profile of the node designated by dstIP and check they agree with each other:
+// the number of instances (0xD3) and classes (0xD4) with instance lists (0xD5, 0xD6) and class list (0xD7),
+// and the formats of version information (0x82), identification number (0x83) and manufacturer code (0x8A)
+func (a *Auditor) ProfileCheck(dstIP net.IP) error {
+	node, err := a.findNode(dstIP)
+	if err != nil {
+		return err
+	}
+	eoj := [3]uint8{0x0E, 0xF0, 0x01}
+	for _, inst := range node.Instances {
+		if isNodeProfile(inst.ClassCode) {
+			eoj = inst.ClassCode
+			break
+		}
+	}
+	node.logger.Info("Start profile check", zap.String("instance", eojString(eoj)))
+	start := time.Now()
+
+	props := make(map[uint8][]uint8)
+	for _, epc := range nodeProfileProps {
+		edt, err := node.getProperty(eoj, epc)
+		var sna *SNAError
+		var malformed *MalformedError
+		if err != nil && !xerrors.Is(err, ErrTimeout) && !xerrors.As(err, &sna) && !xerrors.As(err, &malformed) {
+			return xerrors.Errorf("Failed to check node profile: %w", err)
+		} else if err != nil {
+			node.logger.Error("Get property Failed", zap.String("message", err.Error()))
+			continue
+		}
+		props[epc] = edt
+	}
+	announced, err := node.requestInf(eoj, 0xD5)
+	if err != nil {
+		node.logger.Error("Request instance list notification Failed", zap.String("message", err.Error()))
+	}
+	node.checkNodeProfile(eoj, props, announced)
+
+	if node.result != nil {
+		node.result.AddTiming("Profile Check", node.ip.String(), start)
+	}
+	node.logger.Info("Finished profile check")
+	return nil
+}
+
+// checkNodeProfile check properties of the node profile eoj which are replied to Gets, and instance list notification announced.
+// Properties which aren't replied are missing, and announced is nil if it wasn't notified
+func (node *Node) checkNodeProfile(eoj [3]uint8, props map[uint8][]uint8, announced []uint8) {
+	defer node.endCheck(node.beginCheck("ProfileCheck", eoj, "", ""))
+	report := func(rule string, epc uint8, format string, args ...interface{}) {
+		node.report(Finding{RuleID: rule, EPC: fmt.Sprintf("%02X", epc), Message: fmt.Sprintf(format, args...)})
+	}
+	for _, epc := range nodeProfileProps {
+		if _, ok := props[epc]; !ok {
+			report("NODEPROFILE-PROPERTY-MISSING", epc, "Mandatory property %02X of node profile isn't replied to Get", epc)
+		}
+	}
+
+	// version information: major version 1, minor version, message formats
+	if edt, ok := props[0x82]; ok {
+		if len(edt) != 4 {
+			report("NODEPROFILE-VERSION-FORMAT", 0x82, "Version information should be 4 bytes, but %d bytes (%X)", len(edt), edt)
+		} else if edt[0] != 0x01 {
+			report("NODEPROFILE-VERSION-FORMAT", 0x82, "Major version of ECHONET Lite should be 1, but %d", edt[0])
+		} else if edt[2]&0x01 == 0 {
+			report("NODEPROFILE-VERSION-FORMAT", 0x82, "Message formats %02X%02X don't have the specified message format", edt[2], edt[3])
+		}
+	}
+
+	// manufacturer code: 3 bytes registered by ECHONET Consortium
+	manufacturer, ok := props[0x8A]
+	if ok && len(manufacturer) != 3 {
+		report("NODEPROFILE-MANUFACTURER", 0x8A, "Manufacturer code should be 3 bytes, but %d bytes (%X)", len(manufacturer), manufacturer)
+		manufacturer = nil
+	} else if ok && (uintOf(manufacturer) == 0x000000 || uintOf(manufacturer) == 0xFFFFFF) {
+		report("NODEPROFILE-MANUFACTURER", 0x8A, "Manufacturer code %X isn't registered", manufacturer)
+	}
+
+	// identification number: 0xFE, manufacturer code and unique ID of 13 bytes
+	if edt, ok := props[0x83]; ok {
+		if len(edt) != 17 {
+			report("NODEPROFILE-ID-FORMAT", 0x83, "Identification number should be 17 bytes, but %d bytes (%X)", len(edt), edt)
+		} else if edt[0] != 0xFE {
+			report("NODEPROFILE-ID-FORMAT", 0x83, "1st byte of identification number should be 0xFE, but 0x%02X", edt[0])
+		} else if len(manufacturer) == 3 && string(edt[1:4]) != string(manufacturer) {
+			report("NODEPROFILE-ID-FORMAT", 0x83, "Identification number has manufacturer code %X, but 0x8A is %X", edt[1:4], manufacturer)
+		}
+	}
+
+	// instance list and the number of instances
+	var instances [][3]uint8
+	instancesOK := false
+	if edt, ok := props[0xD6]; ok {
+		list, err := parseInstanceList(edt)
+		if err != nil {
+			report("NODEPROFILE-LIST-MALFORMED", 0xD6, "Self-node instance list S is malformed: %s", err)
+		} else {
+			instances, instancesOK = list, true
+		}
+	}
+	if edt, ok := props[0xD3]; ok && instancesOK {
+		if len(edt) != 3 {
+			report("NODEPROFILE-INSTANCE-COUNT", 0xD3, "Number of self-node instances should be 3 bytes, but %d bytes", len(edt))
+		} else if count := uintOf(edt); count <= maxListedInstances && count != len(instances) {
+			report("NODEPROFILE-INSTANCE-COUNT", 0xD3, "Number of self-node instances is %d, but instance list S (0xD6) has %d", count, len(instances))
+		} else if count > maxListedInstances && len(instances) != maxListedInstances {
+			report("NODEPROFILE-INSTANCE-COUNT", 0xD3, "Number of self-node instances is %d, but instance list S (0xD6) has %d, not %d", count, len(instances), maxListedInstances)
+		}
+	}
+
+	// classes of instances, except the node profile
+	var classes [][2]uint8
+	for _, inst := range instances {
+		class := [2]uint8{inst[0], inst[1]}
+		found := false
+		for _, c := range classes {
+			if c == class {
+				found = true
+			}
+		}
+		if !found && !isNodeProfile(inst) {
+			classes = append(classes, class)
+		}
+	}
+	if edt, ok := props[0xD4]; ok && instancesOK && len(instances) < maxListedInstances {
+		// the node profile class is counted
+		if len(edt) != 2 {
+			report("NODEPROFILE-CLASS-COUNT", 0xD4, "Number of self-node classes should be 2 bytes, but %d bytes", len(edt))
+		} else if count := uintOf(edt); count != len(classes)+1 {
+			report("NODEPROFILE-CLASS-COUNT", 0xD4, "Number of self-node classes is %d, but instance list S (0xD6) has %d classes and the node profile", count, len(classes))
+		}
+	}
+	if edt, ok := props[0xD7]; ok {
+		list, err := parseClassList(edt)
+		if err != nil {
+			report("NODEPROFILE-LIST-MALFORMED", 0xD7, "Self-node class list S is malformed: %s", err)
+		} else if instancesOK && len(instances) < maxListedInstances && len(classes) <= maxListedClasses && !sameClasses(list, classes) {
+			report("NODEPROFILE-CLASS-LIST", 0xD7, "Self-node class list S %X doesn't agree with classes %X of instance list S (0xD6)", list, classes)
+		}
+	}
+
+	// instance list notification
+	if announced == nil {
+		report("NODEPROFILE-ANNOUNCE-MISSING", 0xD5, "Instance list notification isn't notified to INF_REQ")
+	} else if list, err := parseInstanceList(announced); err != nil {
+		report("NODEPROFILE-LIST-MALFORMED", 0xD5, "Instance list notification is malformed: %s", err)
+	} else if instancesOK && !sameInstances(list, instances) {
+		report("NODEPROFILE-ANNOUNCE", 0xD5, "Instance list notification %X doesn't agree with instance list S (0xD6) %X", list, instances)
+	}
+}
+
+// sameInstances return whether a and b have the same instances regardless of order
+func sameInstances(a [][3]uint8, b [][3]uint8) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	count := make(map[[3]uint8]int)
+	for _, eoj := range a {
+		count[eoj]++
+	}
+	for _, eoj := range b {
+		count[eoj]--
+		if count[eoj] < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// sameClasses return whether a and b have the same classes regardless of order
+func sameClasses(a [][2]uint8, b [][2]uint8) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	count := make(map[[2]uint8]int)
+	for _, class := range a {
+		count[class]++
+	}
+	for _, class := range b {
+		count[class]--
+		if count[class] < 0 {
+			return false
+		}
+	}
+	return true
+}
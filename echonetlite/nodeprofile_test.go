@@ -0,0 +1,80 @@
+package echonetlite
+
+import (
+	"testing"
+)
+
+func Test_parseInstanceList(t *testing.T) {
+	tests := []struct {
+		edt     []uint8
+		want    int
+		wantErr bool
+	}{
+		{[]uint8{0x00}, 0, false},
+		{[]uint8{0x02, 0x01, 0x30, 0x01, 0x02, 0x88, 0x01}, 2, false},
+		{nil, 0, true},
+		{[]uint8{0x02, 0x01, 0x30, 0x01}, 0, true},
+		{[]uint8{0x01, 0x01, 0x30, 0x01, 0x00}, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseInstanceList(tt.edt)
+		if (err != nil) != tt.wantErr || len(got) != tt.want {
+			t.Errorf("parseInstanceList(%X) => %X, %v", tt.edt, got, err)
+		}
+	}
+	if _, err := parseClassList([]uint8{0x02, 0x01, 0x30}); err == nil {
+		t.Errorf("parseClassList of short list => no error")
+	}
+}
+
+func Test_checkNodeProfile(t *testing.T) {
+	eoj := [3]uint8{0x0E, 0xF0, 0x01}
+	valid := func() map[uint8][]uint8 {
+		return map[uint8][]uint8{
+			0x82: {0x01, 0x0D, 0x01, 0x00},
+			0x83: {0xFE, 0x00, 0x00, 0x77, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0A, 0x0B, 0x0C, 0x0D},
+			0x8A: {0x00, 0x00, 0x77},
+			0xD3: {0x00, 0x00, 0x03},
+			0xD4: {0x00, 0x03},
+			0xD6: {0x03, 0x01, 0x30, 0x01, 0x01, 0x30, 0x02, 0x02, 0x88, 0x01},
+			0xD7: {0x02, 0x02, 0x88, 0x01, 0x30},
+		}
+	}
+	announced := []uint8{0x03, 0x02, 0x88, 0x01, 0x01, 0x30, 0x01, 0x01, 0x30, 0x02}
+
+	node := newTestNode()
+	node.checkNodeProfile(eoj, valid(), announced)
+	if len(node.result.Findings) != 0 {
+		t.Fatalf("findings of valid node profile => %v", node.result.Findings)
+	}
+
+	tests := []struct {
+		name      string
+		change    func(props map[uint8][]uint8)
+		announced []uint8
+		rule      string
+	}{
+		{"missing", func(p map[uint8][]uint8) { delete(p, 0x83) }, announced, "NODEPROFILE-PROPERTY-MISSING"},
+		{"version", func(p map[uint8][]uint8) { p[0x82] = []uint8{0x00, 0x00, 0x4D, 0x00} }, announced, "NODEPROFILE-VERSION-FORMAT"},
+		{"id", func(p map[uint8][]uint8) { p[0x83][3] = 0x78 }, announced, "NODEPROFILE-ID-FORMAT"},
+		{"manufacturer", func(p map[uint8][]uint8) {
+			p[0x8A] = []uint8{0xFF, 0xFF, 0xFF}
+			copy(p[0x83][1:4], p[0x8A])
+		}, announced, "NODEPROFILE-MANUFACTURER"},
+		{"instances", func(p map[uint8][]uint8) { p[0xD3] = []uint8{0x00, 0x00, 0x02} }, announced, "NODEPROFILE-INSTANCE-COUNT"},
+		{"classes", func(p map[uint8][]uint8) { p[0xD4] = []uint8{0x00, 0x02} }, announced, "NODEPROFILE-CLASS-COUNT"},
+		{"class list", func(p map[uint8][]uint8) { p[0xD7] = []uint8{0x01, 0x01, 0x30} }, announced, "NODEPROFILE-CLASS-LIST"},
+		{"short list", func(p map[uint8][]uint8) { p[0xD7] = []uint8{0x02, 0x01, 0x30} }, announced, "NODEPROFILE-LIST-MALFORMED"},
+		{"announce", func(p map[uint8][]uint8) {}, []uint8{0x01, 0x01, 0x30, 0x01}, "NODEPROFILE-ANNOUNCE"},
+		{"no announce", func(p map[uint8][]uint8) {}, nil, "NODEPROFILE-ANNOUNCE-MISSING"},
+	}
+	for _, tt := range tests {
+		node := newTestNode()
+		props := valid()
+		tt.change(props)
+		node.checkNodeProfile(eoj, props, tt.announced)
+		if len(node.result.Findings) != 1 || node.result.Findings[0].RuleID != tt.rule {
+			t.Errorf("%s: findings => %v, want %s", tt.name, node.result.Findings, tt.rule)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package echonetlite
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+// planTableEvery is the interval the progress table is written during a test plan
+const planTableEvery = 5 * time.Second
+
+// PlanSteps are the names of operations a test plan can have, in the order they are executed
+var PlanSteps = []string{"Sweep", "Profile Check", "Address Check", "OPC Fuzz", "Frame Fuzz", "Boundary Fuzz", "Profile Fuzz"}
+
+// planSteps run an operation against an instance of a node
+var planSteps = map[string]func(a *Auditor, ip net.IP, code [3]uint8) error{
+	"Sweep": (*Auditor).Sweep,
+	"OPC Fuzz": func(a *Auditor, ip net.IP, code [3]uint8) error {
+		_, err := a.OpcFuzz(ip, code)
+		return err
+	},
+	"Frame Fuzz":    (*Auditor).FrameFuzz,
+	"Boundary Fuzz": (*Auditor).BoundaryFuzz,
+	// once per node, with the node profile
+	"Profile Fuzz": func(a *Auditor, ip net.IP, code [3]uint8) error {
+		if !isNodeProfile(code) {
+			return nil
+		}
+		return a.ProfileFuzz(ip)
+	},
+	"Profile Check": func(a *Auditor, ip net.IP, code [3]uint8) error {
+		if !isNodeProfile(code) {
+			return nil
+		}
+		return a.ProfileCheck(ip)
+	},
+	"Address Check": func(a *Auditor, ip net.IP, code [3]uint8) error {
+		if !isNodeProfile(code) {
+			return nil
+		}
+		return a.AddressCheck(ip)
+	},
+}
+
+// Plan states of a node
+const (
+	planWaiting = "waiting"
+	planRunning = "running"
+	planDone    = "done"
+	planFailed  = "failed"
+)
+
+// PlanStatus is the progress of a test plan against a node
+type PlanStatus struct {
+	Node     string
+	State    string
+	Step     string // step running now
+	Done     int    // steps finished
+	Steps    int
+	Cases    int // fuzzing cases sent
+	Findings int
+	Err      error
+	Start    time.Time
+	End      time.Time
+}
+
+// plan is a test plan running against nodes in parallel
+type plan struct {
+	mu       sync.Mutex
+	statuses []*PlanStatus
+}
+
+func (p *plan) update(i int, f func(s *PlanStatus)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	f(p.statuses[i])
+}
+
+// snapshot return copies of statuses with cases and findings counted from result
+func (p *plan) snapshot(result *Result) []PlanStatus {
+	p.mu.Lock()
+	retStatuses := make([]PlanStatus, len(p.statuses))
+	for i, s := range p.statuses {
+		retStatuses[i] = *s
+	}
+	p.mu.Unlock()
+	if result != nil {
+		for i := range retStatuses {
+			retStatuses[i].Cases = result.countCases(retStatuses[i].Node)
+			retStatuses[i].Findings = result.countNode(retStatuses[i].Node)
+		}
+	}
+	return retStatuses
+}
+
+// pacedConn is a connection which waits at least pace between packets sent
+type pacedConn struct {
+	net.Conn
+	pace time.Duration
+	mu   sync.Mutex
+	last time.Time
+}
+
+func (c *pacedConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if wait := c.pace - time.Since(c.last); wait > 0 {
+		time.Sleep(wait)
+	}
+	c.last = time.Now()
+	return c.Conn.Write(b)
+}
+
+// ParsePlan change comma separated names like "OPC Fuzz,Boundary Fuzz" into steps.
+// Empty string means all steps
+func ParsePlan(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return PlanSteps, nil
+	}
+	var retSteps []string
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := planSteps[name]; !ok {
+			return nil, xerrors.Errorf("Unknown step %s. Steps are %s", name, strings.Join(PlanSteps, ", "))
+		}
+		retSteps = append(retSteps, name)
+	}
+	return retSteps, nil
+}
+
+// RunPlan run steps against all instances of nodes designated by ips concurrently, a worker per node.
+// If ips is empty, all nodes in a.DistNodes are tested. Each node waits at least pace between packets sent.
+// Progress table is written into w periodically, and findings of all nodes are stored in a.Result
+func (a *Auditor) RunPlan(steps []string, ips []net.IP, pace time.Duration, w io.Writer) ([]PlanStatus, error) {
+	for _, step := range steps {
+		if _, ok := planSteps[step]; !ok {
+			return nil, xerrors.Errorf("Unknown step %s", step)
+		}
+	}
+	var targets []int
+	for i, node := range a.DistNodes {
+		if len(ips) == 0 {
+			targets = append(targets, i)
+			continue
+		}
+		for _, ip := range ips {
+			if node.ip.Equal(ip) {
+				targets = append(targets, i)
+			}
+		}
+	}
+	if len(targets) == 0 {
+		return nil, xerrors.Errorf("No node to test")
+	}
+
+	// nodes share the checkpointer
+	a.checkpoint()
+	if pace > 0 {
+		for _, i := range targets {
+			conn := a.DistNodes[i].client.conn
+			a.DistNodes[i].client.conn = &pacedConn{Conn: conn, pace: pace}
+			defer func(i int) { a.DistNodes[i].client.conn = conn }(i)
+		}
+	}
+
+	p := &plan{}
+	for _, i := range targets {
+		p.statuses = append(p.statuses, &PlanStatus{Node: a.DistNodes[i].ip.String(), State: planWaiting, Steps: len(steps)})
+	}
+	a.logger.Info("Start test plan", zap.Strings("steps", steps), zap.Int("nodes", len(targets)), zap.Duration("pace", pace))
+
+	var wg sync.WaitGroup
+	for worker, i := range targets {
+		wg.Add(1)
+		go func(worker int, node Node) {
+			defer wg.Done()
+			a.runPlanNode(p, worker, node, steps)
+		}(worker, a.DistNodes[i])
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+	ticker := time.NewTicker(planTableEvery)
+	defer ticker.Stop()
+	for running := true; running; {
+		select {
+		case <-ticker.C:
+			if w != nil {
+				WritePlanTable(w, p.snapshot(a.Result))
+			}
+		case <-finished:
+			running = false
+		}
+	}
+
+	statuses := p.snapshot(a.Result)
+	if w != nil {
+		WritePlanTable(w, statuses)
+	}
+	var failed []string
+	for _, s := range statuses {
+		if s.State == planFailed {
+			failed = append(failed, s.Node)
+		}
+	}
+	a.logger.Info("Finished test plan", zap.Int("nodes", len(statuses)), zap.Strings("failed", failed))
+	if len(failed) > 0 {
+		return statuses, xerrors.Errorf("Test plan failed against %s", strings.Join(failed, ", "))
+	}
+	return statuses, nil
+}
+
+// runPlanNode run steps against all instances of node in order. Failure of a step stops the steps of node only
+func (a *Auditor) runPlanNode(p *plan, worker int, node Node, steps []string) {
+	p.update(worker, func(s *PlanStatus) {
+		s.State = planRunning
+		s.Start = time.Now()
+	})
+	node.logger.Info("Start test plan", zap.Strings("steps", steps))
+	for _, step := range steps {
+		p.update(worker, func(s *PlanStatus) { s.Step = step })
+		for _, inst := range node.Instances {
+			node.logger.Info("Start step", zap.String("step", step), zap.String("instance", inst.ClassName))
+			err := planSteps[step](a, node.ip, inst.ClassCode)
+			if err != nil {
+				node.logger.Error("Step Failed", zap.String("step", step), zap.String("instance", inst.ClassName), zap.String("message", err.Error()))
+				p.update(worker, func(s *PlanStatus) {
+					s.State = planFailed
+					s.Err = xerrors.Errorf("%s against %s: %w", step, eojString(inst.ClassCode), err)
+					s.End = time.Now()
+				})
+				return
+			}
+		}
+		p.update(worker, func(s *PlanStatus) { s.Done++ })
+	}
+	node.logger.Info("Finished test plan")
+	p.update(worker, func(s *PlanStatus) {
+		s.State = planDone
+		s.Step = ""
+		s.End = time.Now()
+	})
+}
+
+// WritePlanTable write progress of a test plan per node as text table into w
+func WritePlanTable(w io.Writer, statuses []PlanStatus) {
+	fmt.Fprintf(w, "   %-39s %-8s %-14s %-5s %-7s %-8s %s\n", "NODE", "STATE", "STEP", "STEPS", "CASES", "FINDINGS", "ELAPSED")
+	for _, s := range statuses {
+		elapsed := time.Duration(0)
+		if !s.Start.IsZero() {
+			end := s.End
+			if end.IsZero() {
+				end = time.Now()
+			}
+			elapsed = end.Sub(s.Start).Truncate(time.Second)
+		}
+		step := s.Step
+		if step == "" {
+			step = "-"
+		}
+		fmt.Fprintf(w, "   %-39s %-8s %-14s %-5s %-7d %-8d %s\n", s.Node, s.State, step, fmt.Sprintf("%d/%d", s.Done, s.Steps), s.Cases, s.Findings, elapsed)
+		if s.Err != nil {
+			fmt.Fprintf(w, "     error: %s\n", s.Err)
+		}
+	}
+}
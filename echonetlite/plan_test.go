@@ -0,0 +1,19 @@
+package echonetlite
+
+import (
+	"testing"
+)
+
+func Test_ParsePlan(t *testing.T) {
+	steps, err := ParsePlan("")
+	if err != nil || len(steps) != len(PlanSteps) {
+		t.Errorf("ParsePlan(\"\") => %v, %v", steps, err)
+	}
+	steps, err = ParsePlan(" Boundary Fuzz,OPC Fuzz ")
+	if err != nil || len(steps) != 2 || steps[0] != "Boundary Fuzz" || steps[1] != "OPC Fuzz" {
+		t.Errorf("ParsePlan => %v, %v", steps, err)
+	}
+	if _, err = ParsePlan("OPC Fuzz,Unknown"); err == nil {
+		t.Errorf("ParsePlan with unknown step => nil error")
+	}
+}
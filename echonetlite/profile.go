@@ -0,0 +1,280 @@
+package echonetlite
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+// profileInfWait is the time waiting packets from the device after an instance list notification
+const profileInfWait = 500 * time.Millisecond
+
+// Kinds of profile cases
+const (
+	profileSet = "set" // SetC with invalid value
+	profileGet = "get" // Get of EPC not in Get property map
+	profileInf = "inf" // malformed instance list notification
+)
+
+// profileCase is a frame sent to the node profile in Profile Fuzz
+type profileCase struct {
+	kind string
+	name string
+	epc  uint8
+	edt  []uint8
+}
+
+// isNodeProfile return whether code is the node profile object (0x0EF001 or 0x0EF002)
+func isNodeProfile(code [3]uint8) bool {
+	return code[0] == 0x0E && code[1] == 0xF0
+}
+
+// malformedLists return malformed lists of objects whose code is unit bytes,
+// like instance lists (0xD5, 0xD6) and class lists (0xD7)
+func malformedLists(unit int, r *rand.Rand) []boundaryCase {
+	entry := []uint8{0x01, 0x30, 0x01}[:unit]
+	entries := func(n int) []uint8 {
+		var retList []uint8
+		for i := 0; i < n; i++ {
+			retList = append(retList, entry...)
+		}
+		return retList
+	}
+	maxEntries := 252 / unit
+	retCases := []boundaryCase{
+		{"empty", []uint8{}},
+		{"count only", []uint8{0x01}},
+		{"count larger than list", append([]uint8{0x02}, entries(1)...)},
+		{"count smaller than list", append([]uint8{0x01}, entries(2)...)},
+		{"zero count with list", append([]uint8{0x00}, entries(1)...)},
+		{"partial entry", append([]uint8{0x01}, entry[:unit-1]...)},
+		{"count 0xFF", append([]uint8{0xFF}, entries(maxEntries)...)},
+		{fmt.Sprintf("%d entries", maxEntries), append([]uint8{uint8(maxEntries)}, entries(maxEntries)...)},
+	}
+	if unit == 3 {
+		retCases = append(retCases,
+			boundaryCase{"node profile listed", []uint8{0x01, 0x0E, 0xF0, 0x01}},
+			boundaryCase{"instance code 0x00", []uint8{0x01, 0x01, 0x30, 0x00}},
+		)
+	}
+	random := make([]uint8, 1+r.Intn(255))
+	r.Read(random)
+	retCases = append(retCases, boundaryCase{"random", random})
+	return retCases
+}
+
+// profileCases return cases of Profile Fuzz against the node profile inst in the order they are sent
+func profileCases(inst Instance, r *rand.Rand) []profileCase {
+	var retCases []profileCase
+
+	// Set of operating status and unique identifier data with invalid values
+	fallback := map[uint8][]boundaryCase{
+		0x80: {{"empty", []uint8{}}, {"0x00", []uint8{0x00}}, {"0xFF", []uint8{0xFF}}, {"size 2 (want 1)", []uint8{0x30, 0x30}}},
+		0xBF: {{"empty", []uint8{}}, {"size 1 (want 2)", []uint8{0x00}}, {"size 3 (want 2)", []uint8{0x00, 0x00, 0x00}}},
+	}
+	for _, epc := range []uint8{0x80, 0xBF} {
+		cases := fallback[epc]
+		for _, prop := range inst.Props {
+			if prop.EPC == epc && len(prop.Data) > 0 {
+				generated, err := propertyBoundaryValues(prop, r)
+				if err == nil && len(generated) > 0 {
+					cases = generated
+				}
+			}
+		}
+		for _, c := range cases {
+			retCases = append(retCases, profileCase{kind: profileSet, name: c.name, epc: epc, edt: c.edt})
+		}
+	}
+
+	// Get of EPCs not in Get property map
+	implemented := make(map[uint8]bool)
+	for _, prop := range inst.Props {
+		if prop.ImplementGet {
+			implemented[prop.EPC] = true
+		}
+	}
+	for epc := 0; epc <= 0xFF; epc++ {
+		if !implemented[uint8(epc)] {
+			retCases = append(retCases, profileCase{kind: profileGet, name: "not in Get property map", epc: uint8(epc)})
+		}
+	}
+
+	// malformed instance list and class list notifications
+	for _, list := range []struct {
+		epc  uint8
+		unit int
+	}{{0xD5, 3}, {0xD6, 3}, {0xD7, 2}} {
+		for _, c := range malformedLists(list.unit, r) {
+			retCases = append(retCases, profileCase{kind: profileInf, name: c.name, epc: list.epc, edt: c.edt})
+		}
+	}
+	return retCases
+}
+
+// frame return the frame of c sent from seoj to the node profile dstCode
+func (c profileCase) frame(tid uint16, seoj [3]uint8, dstCode [3]uint8) FrameFormat {
+	retFrame := FrameFormat{
+		EHD1:      0x10,
+		EHD2:      0x81,
+		TID:       tid,
+		SEOJ:      seoj,
+		DEOJ:      dstCode,
+		OPC:       0x01,
+		VarGroups: []VarByteGroup{{EPC: c.epc, PDC: uint8(len(c.edt)), EDT: c.edt}},
+	}
+	switch c.kind {
+	case profileSet:
+		retFrame.ESV = 0x61
+	case profileGet:
+		retFrame.ESV = 0x62
+	case profileInf:
+		retFrame.ESV = 0x73
+	}
+	return retFrame
+}
+
+// ProfileFuzz send invalid Sets of operating status (0x80) and unique identifier data (0xBF),
+// Gets of EPCs not in Get property map and malformed instance list notifications (0xD5, 0xD6, 0xD7)
+// to the node profile of the node designated by dstIP. Liveness of the node is checked after every case
+func (a *Auditor) ProfileFuzz(dstIP net.IP) error {
+	a.printf("---Start Profile fuzzy---\n")
+	var node Node
+	var inst Instance
+	found := false
+	for _, n := range a.DistNodes {
+		if !n.ip.Equal(dstIP) {
+			continue
+		}
+		for _, i := range n.Instances {
+			if isNodeProfile(i.ClassCode) {
+				node, inst, found = n, i, true
+				break
+			}
+		}
+	}
+	if !found {
+		a.logger.Error("Node profile not found", zap.String("IPaddr", dstIP.String()))
+		return xerrors.Errorf("Node profile of %s is not found", dstIP)
+	}
+	dstCode := inst.ClassCode
+	node.logger.Info("Start Profile fuzzy", zap.String("instance", eojString(dstCode)))
+
+	strategy := "Profile Fuzz"
+	campaign := a.beginCampaign(strategy, dstIP.String(), eojString(dstCode), a.campaignSeed(strategy, dstIP.String(), eojString(dstCode)))
+	stats := campaign.statsOf(strategy)
+	if a.Result != nil {
+		defer func() {
+			stats.Duration = time.Since(stats.Start)
+			a.Result.AddFuzz(stats)
+			a.Result.AddTiming(stats.Strategy, stats.Node, stats.Start)
+		}()
+	}
+	r := rand.New(rand.NewSource(stats.Seed))
+	timeout := node.replyTimeout()
+	live := newLiveness(&node, a.LivenessEvery, a.LivenessRecovery)
+	live.every = 1
+	minimized := make(minimizedCases)
+
+	for i, c := range profileCases(inst, r) {
+		index := i + 1
+		if index <= campaign.Next {
+			continue
+		}
+		if err := node.canceled(); err != nil {
+			return xerrors.Errorf("Profile fuzzy stopped: %w", err)
+		}
+		caseFindings := a.findingCount(&node)
+		sent := c.frame(node.client.nextTID(), node.client.SEOJ, dstCode)
+		node.logger.Info("sent packet", zap.String("EPC", fmt.Sprintf("0x%02X", c.epc)), zap.String("case", c.name), zap.String("payload", frameHex(&sent)))
+		fuzzCase := FuzzCase{
+			Strategy: stats.Strategy,
+			Node:     stats.Node,
+			Instance: stats.Instance,
+			Index:    stats.Sent + 1,
+			Sent:     frameHex(&sent),
+			SentAt:   time.Now(),
+		}
+		stats.Sent++
+
+		var recv FrameFormat
+		var err error
+		if c.kind == profileInf {
+			// no reply is expected. Requests the device sends after the notification are skipped
+			recv, err = node.exchange(sent, profileInfWait)
+		} else {
+			recv, err = node.exchange(sent, timeout)
+		}
+		fuzzCase.RTT = time.Since(fuzzCase.SentAt)
+		if err := node.canceled(); err != nil {
+			// the reply is not waited for
+			return xerrors.Errorf("Profile fuzzy stopped: %w", err)
+		}
+		if err != nil && !xerrors.Is(err, ErrTimeout) {
+			node.logger.Error("Receive packet Failed", zap.String("message", err.Error()))
+			// truncated replies are reported by exchange
+			if c.kind != profileInf && !isTruncated(err) {
+				node.report(Finding{RuleID: "FUZZ-MALFORMED-REPLY", Instance: eojString(dstCode), EPC: fmt.Sprintf("%02X", c.epc), Message: fmt.Sprintf("Reply to %s of %02X (%s) is not ECHONET Lite frame: %s", c.kind, c.epc, c.name, err), Sent: fuzzCase.Sent})
+			}
+		}
+		fuzzCase.Timeout = err != nil && xerrors.Is(err, ErrTimeout)
+		fuzzCase.Recv = frameHex(&recv)
+		a.recordCase(fuzzCase)
+		if err != nil && !fuzzCase.Timeout {
+			stats.Replied++
+		} else if fuzzCase.Timeout {
+			stats.Timeouts++
+		} else {
+			stats.Replied++
+		}
+
+		if c.kind != profileInf && (err == nil || fuzzCase.Timeout) {
+			node.checkProfileReply(inst, c, &sent, &recv)
+		}
+		err = live.after(fuzzCase, &stats)
+		if err != nil {
+			return xerrors.Errorf("Profile fuzzy stopped: %w", err)
+		}
+		a.minimizeFindings(&node, live, caseFindings, minimized)
+		stats.Findings += a.findingCount(&node) - caseFindings
+		a.progress(campaign, index, stats)
+	}
+	caseFindings := a.findingCount(&node)
+	err := live.flush(&stats)
+	if err != nil {
+		return xerrors.Errorf("Profile fuzzy stopped: %w", err)
+	}
+	a.minimizeFindings(&node, live, caseFindings, minimized)
+	stats.Findings += a.findingCount(&node) - caseFindings
+	a.endCampaign(campaign)
+	node.logger.Info("Finished Profile fuzzy", zap.String("instance", eojString(dstCode)))
+	return nil
+}
+
+// checkProfileReply check the reply to an invalid Set or a Get of EPC not in Get property map of the node profile
+func (node *Node) checkProfileReply(inst Instance, c profileCase, sent *FrameFormat, recv *FrameFormat) {
+	defer node.endCheck(node.beginCheck("ProfileFuzz", inst.ClassCode, frameHex(sent), frameHex(recv)))
+	epc := fmt.Sprintf("%02X", c.epc)
+
+	if recv.EHD1 == 0 {
+		node.report(Finding{RuleID: "RECV-NONE", EPC: epc, Message: fmt.Sprintf("No reply to %s of node profile %02X (%s)", c.kind, c.epc, c.name)})
+		return
+	}
+	node.CheckFlowValidation(*sent, *recv)
+	switch c.kind {
+	case profileSet:
+		if recv.ESV == 0x71 {
+			node.report(Finding{RuleID: "PROFILE-SET-ACCEPTED", EPC: epc, Message: fmt.Sprintf("Node profile accepted invalid value of %02X (%s, EDT:%X)", c.epc, c.name, c.edt)})
+		} else if recv.ESV != 0x51 {
+			node.report(Finding{RuleID: "PROFILE-SET-NOT-REJECTED", EPC: epc, Message: fmt.Sprintf("Node profile replied ESV %02X to invalid value of %02X (%s), want 51", recv.ESV, c.epc, c.name)})
+		}
+	case profileGet:
+		if recv.ESV != 0x52 {
+			node.report(Finding{RuleID: "PROFILE-GET-NOT-REJECTED", EPC: epc, Message: fmt.Sprintf("Node profile replied ESV %02X to Get of %02X not in Get property map, want 52", recv.ESV, c.epc)})
+		}
+	}
+}
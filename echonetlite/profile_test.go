@@ -0,0 +1,55 @@
+package echonetlite
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func Test_malformedLists(t *testing.T) {
+	for _, unit := range []int{2, 3} {
+		for _, c := range malformedLists(unit, rand.New(rand.NewSource(1))) {
+			if len(c.edt) > 0xFF {
+				t.Errorf("unit %d %s: EDT size %d is larger than PDC", unit, c.name, len(c.edt))
+			}
+			// only these have well-formed layouts with invalid contents
+			if c.name == "node profile listed" || c.name == "instance code 0x00" || c.name == "random" || c.name == fmt.Sprintf("%d entries", 252/unit) {
+				continue
+			}
+			if len(c.edt) > 0 && len(c.edt) == 1+int(c.edt[0])*unit {
+				t.Errorf("unit %d %s: %X is well-formed", unit, c.name, c.edt)
+			}
+		}
+	}
+}
+
+func Test_profileCases(t *testing.T) {
+	inst := Instance{
+		ClassCode: [3]uint8{0x0E, 0xF0, 0x01},
+		Props: []Property{
+			{EPC: 0x80, ImplementGet: true},
+			{EPC: 0xD6, ImplementGet: true},
+		},
+	}
+	counts := make(map[string]int)
+	for _, c := range profileCases(inst, rand.New(rand.NewSource(1))) {
+		counts[c.kind]++
+		if c.kind == profileGet && (c.epc == 0x80 || c.epc == 0xD6) {
+			t.Errorf("Get of %02X in Get property map", c.epc)
+		}
+	}
+	// fallback values of 0x80 and 0xBF
+	if counts[profileSet] != 7 {
+		t.Errorf("set cases => %d, want 7", counts[profileSet])
+	}
+	if counts[profileGet] != 254 {
+		t.Errorf("get cases => %d, want 254", counts[profileGet])
+	}
+	if counts[profileInf] == 0 {
+		t.Errorf("no notification cases")
+	}
+	sent := profileCases(inst, rand.New(rand.NewSource(1)))[0].frame(1, [3]uint8{0x0E, 0xF0, 0x01}, inst.ClassCode)
+	if sent.ESV != 0x61 || sent.DEOJ != inst.ClassCode || sent.VarGroups[0].EPC != 0x80 {
+		t.Errorf("frame => %+v", sent)
+	}
+}
@@ -0,0 +1,114 @@
+package echonetlite
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Prompter is the user of interactive flows like Communicate and the prompt.
+// Output for the user is written into it, and answers are read with NextLine
+type Prompter interface {
+	io.Writer
+	// NextLine return the next line answered by the user. io.EOF is returned if there are no more answers
+	NextLine() (string, error)
+}
+
+// linePrompter read answers line by line from a reader
+type linePrompter struct {
+	io.Writer
+	s *bufio.Scanner
+}
+
+// NewPrompter create Prompter reading answers from in and writing output into out.
+// A script of answers, one per line, is played back by giving it as in
+func NewPrompter(in io.Reader, out io.Writer) Prompter {
+	return &linePrompter{Writer: out, s: bufio.NewScanner(in)}
+}
+
+func (p *linePrompter) NextLine() (string, error) {
+	if !p.s.Scan() {
+		if err := p.s.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return p.s.Text(), nil
+}
+
+// recorder record answers of Prompter
+type recorder struct {
+	Prompter
+	script io.Writer
+}
+
+// Record return Prompter which writes answers of p into script, one per line, so that the session can be played back with NewPrompter
+func Record(p Prompter, script io.Writer) Prompter {
+	return &recorder{Prompter: p, script: script}
+}
+
+func (r *recorder) NextLine() (string, error) {
+	line, err := r.Prompter.NextLine()
+	if err == nil {
+		fmt.Fprintln(r.script, line)
+	}
+	return line, err
+}
+
+// recordCommand write a command of the prompt into the script if p records the session
+func recordCommand(p Prompter, in string) {
+	if r, ok := p.(*recorder); ok {
+		fmt.Fprintln(r.script, in)
+	}
+}
+
+// RunScript execute commands of the prompt read from Prompter one per line until there are no more lines or exit.
+// Answers asked by the commands are read from the following lines
+func (a *Auditor) RunScript() {
+	for !a.Quitting() {
+		in, err := answer(a.Prompter)
+		if err != nil {
+			return
+		}
+		a.printf("(Input)> %s\n", in)
+		a.execute(in)
+	}
+}
+
+// answer read a line answered to p. Empty if p is nil or has no more answers
+func answer(p Prompter) (string, error) {
+	if p == nil {
+		return "", io.EOF
+	}
+	return p.NextLine()
+}
+
+// printTo write output for the user into p. Nothing is written if p is nil
+func printTo(p Prompter, format string, args ...interface{}) {
+	if p == nil {
+		return
+	}
+	fmt.Fprintf(p, format, args...)
+}
+
+// nextLine read a line answered by the user
+func (a *Auditor) nextLine() string {
+	line, _ := answer(a.Prompter)
+	return line
+}
+
+// printf write output for the user
+func (a *Auditor) printf(format string, args ...interface{}) {
+	printTo(a.Prompter, format, args...)
+}
+
+// nextLine read a line answered by the user
+func (node *Node) nextLine() string {
+	line, _ := answer(node.prompter)
+	return line
+}
+
+// printf write output for the user
+func (node *Node) printf(format string, args ...interface{}) {
+	printTo(node.prompter, format, args...)
+}
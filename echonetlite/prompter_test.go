@@ -0,0 +1,77 @@
+package echonetlite
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func Test_PrintInfo(t *testing.T) {
+	a := newTestAuditor()
+	prop := a.DistNodes[0].Instances[1].Props[0]
+	prop.Data = append(prop.Data, Number{format: "uint8", minimum: 0, maximum: 100})
+
+	var out bytes.Buffer
+	node := newTestNode()
+	node.prompter = NewPrompter(strings.NewReader("1\n31\n"), &out)
+	edt, err := node.PrintInfo(prop, true)
+	if err != nil || len(edt) != 1 || edt[0] != 0x31 {
+		t.Errorf("PrintInfo => %X, %v", edt, err)
+	}
+	if !strings.Contains(out.String(), "0x31 means OFF") || !strings.Contains(out.String(), "Type 2") {
+		t.Errorf("PrintInfo output => %s", out.String())
+	}
+
+	node.prompter = NewPrompter(strings.NewReader("3\n"), &out)
+	if _, err := node.PrintInfo(prop, true); err == nil {
+		t.Errorf("PrintInfo of type out of range => no error")
+	}
+}
+
+func Test_Communicate(t *testing.T) {
+	c, _ := newFakeClient(t, func(req *FrameFormat) []byte {
+		res := FrameFormat{EHD1: 0x10, EHD2: 0x81, TID: req.TID, SEOJ: req.DEOJ, DEOJ: req.SEOJ, ESV: 0x72, OPC: 1,
+			VarGroups: []VarByteGroup{{EPC: 0x80, PDC: 1, EDT: []uint8{0x30}}}}
+		return echonetToByte(res)
+	})
+	node := newTestNode()
+	node.client = c
+	node.Instances = newTestAuditor().DistNodes[0].Instances
+
+	// Normal mode, Get 0x80 of the air conditioner, then exit. The answers are recorded and played back
+	var out, script bytes.Buffer
+	node.prompter = Record(NewPrompter(strings.NewReader("1\n2\n62\n1\n80\n0\n"), &out), &script)
+	if err := node.Communicate(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "> DEOJ:   013001") || !strings.Contains(out.String(), "> ESV:    72") {
+		t.Errorf("Communicate output => %s", out.String())
+	}
+	if script.String() != "1\n2\n62\n1\n80\n0\n" {
+		t.Errorf("Record => %q", script.String())
+	}
+
+	out.Reset()
+	node.prompter = NewPrompter(&script, &out)
+	if err := node.Communicate(); err != nil || !strings.Contains(out.String(), "> ESV:    72") {
+		t.Errorf("Communicate played back => %v, %s", err, out.String())
+	}
+
+	// invalid answers are asked again, and the session ends when answers run out
+	out.Reset()
+	node.prompter = NewPrompter(strings.NewReader("x\n1\n9\n2\n99\n"), &out)
+	if err := node.Communicate(); err != nil || strings.Count(out.String(), "(ECHONET Lite:Error)") != 3 {
+		t.Errorf("Communicate of invalid answers => %v, %s", err, out.String())
+	}
+}
+
+func Test_RunScript(t *testing.T) {
+	a := newTestAuditor()
+	a.Config.LogDir = t.TempDir()
+	var out bytes.Buffer
+	a.Prompter = NewPrompter(strings.NewReader("nodes\nunknown\n"), &out)
+	a.RunScript()
+	if !strings.Contains(out.String(), "013001 Home air conditioner") || !strings.Contains(out.String(), "Command not found") {
+		t.Errorf("RunScript output => %s", out.String())
+	}
+}
@@ -0,0 +1,62 @@
+package echonetlite
+
+import (
+	"net"
+	"sync"
+)
+
+// maxDatagram is the size of receive buffers. It is the maximum UDP payload, so replies are never truncated by the tester
+const maxDatagram = 65535
+
+// inboxSize is the number of packets queued per node. Packets beyond it are dropped
+const inboxSize = 64
+
+// receiver read the receive socket shared by nodes and dispatch packets to the inbox of their source node,
+// so that nodes can be tested in parallel
+type receiver struct {
+	conn    *net.UDPConn
+	mu      sync.Mutex
+	inboxes map[string]chan []byte
+}
+
+// newReceiver create receiver and start reading conn
+func newReceiver(conn *net.UDPConn) *receiver {
+	rc := &receiver{conn: conn, inboxes: make(map[string]chan []byte)}
+	go rc.run()
+	return rc
+}
+
+// inbox return the channel packets from ip are dispatched to
+func (rc *receiver) inbox(ip net.IP) chan []byte {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	key := ip.String()
+	if _, ok := rc.inboxes[key]; !ok {
+		rc.inboxes[key] = make(chan []byte, inboxSize)
+	}
+	return rc.inboxes[key]
+}
+
+func (rc *receiver) run() {
+	buffer := make([]byte, maxDatagram)
+	for {
+		length, addr, err := rc.conn.ReadFromUDP(buffer)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			return
+		}
+		rc.mu.Lock()
+		inbox, ok := rc.inboxes[addr.IP.String()]
+		rc.mu.Unlock()
+		if !ok {
+			continue
+		}
+		select {
+		// copy only the datagram, buffer is reused
+		case inbox <- append([]byte(nil), buffer[:length]...):
+		default:
+		}
+	}
+}
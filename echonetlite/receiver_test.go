@@ -0,0 +1,34 @@
+package echonetlite
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+func Test_receiver(t *testing.T) {
+	conn := listenLoopback(t)
+	rc := newReceiver(conn)
+	local := rc.inbox(net.IPv4(127, 0, 0, 1))
+	other := rc.inbox(net.ParseIP("192.0.2.1"))
+
+	device := listenLoopback(t)
+	device.WriteToUDP([]byte{0x10, 0x81, 0x00, 0x01}, conn.LocalAddr().(*net.UDPAddr))
+
+	c := NewClient(net.IPv4(127, 0, 0, 1), nil, nil)
+	c.inbox = local
+	c.Timeout = time.Second
+	data, err := c.receive(context.Background())
+	if err != nil || string(data) != string([]byte{0x10, 0x81, 0x00, 0x01}) {
+		t.Fatalf("receive => %X, %v", data, err)
+	}
+	c.inbox = other
+	c.Timeout = 50 * time.Millisecond
+	_, err = c.receive(context.Background())
+	if err == nil || !xerrors.Is(err, ErrTimeout) {
+		t.Errorf("receive of other node => %v, want timeout", err)
+	}
+}
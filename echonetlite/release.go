@@ -0,0 +1,179 @@
+package echonetlite
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/buger/jsonparser"
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+// releaseBases are the versions of ECHONET Lite specification Appendix releases are based on, {major, minor}
+var releaseBases = map[string][2]uint8{
+	"A": {1, 0}, "B": {1, 1}, "C": {1, 1}, "D": {1, 10}, "E": {1, 10}, "F": {1, 11}, "G": {1, 11},
+	"H": {1, 12}, "I": {1, 12}, "J": {1, 12}, "K": {1, 13}, "L": {1, 13}, "M": {1, 13},
+}
+
+// decodeRelease decode Version information (0x82) of a device object into the release letter and the revision
+func decodeRelease(edt []uint8) (string, uint8, error) {
+	if len(edt) != 4 {
+		return "", 0, xerrors.Errorf("Version information should be 4 bytes, but %d bytes", len(edt))
+	}
+	if edt[0] != 0x00 || edt[1] != 0x00 {
+		return "", 0, xerrors.Errorf("1st and 2nd bytes of version information should be 0x00, but %02X%02X", edt[0], edt[1])
+	}
+	if edt[2] < 'A' || edt[2] > 'Z' {
+		return "", 0, xerrors.Errorf("Release of version information should be 'A' ~ 'Z', but 0x%02X", edt[2])
+	}
+	return string(rune(edt[2])), edt[3], nil
+}
+
+// decodeLiteVersion decode Version information (0x82) of the node profile into the major and minor version of ECHONET Lite
+func decodeLiteVersion(edt []uint8) (uint8, uint8, error) {
+	if len(edt) != 4 {
+		return 0, 0, xerrors.Errorf("Version information should be 4 bytes, but %d bytes", len(edt))
+	}
+	return edt[0], edt[1], nil
+}
+
+// latestRelease return the latest release class definitions have
+func latestRelease(json []byte) string {
+	latest, err := jsonparser.GetString(json, "metaData", "release")
+	if err != nil {
+		return "M"
+	}
+	return latest
+}
+
+// definedRelease return whether class definitions have the class of objectCode in release
+func definedRelease(json []byte, objectCode [3]uint8, release string) bool {
+	latest := latestRelease(json)
+	if release > latest {
+		return false
+	}
+	classCode := fmt.Sprintf("0x%02X%02X", objectCode[0], objectCode[1])
+	covers := func(value []byte) bool {
+		from, err := jsonparser.GetString(value, "validRelease", "from")
+		if err != nil {
+			return true
+		}
+		to, _ := jsonparser.GetString(value, "validRelease", "to")
+		if to == "latest" {
+			to = latest
+		}
+		return from <= release && release <= to
+	}
+	class, _, _, err := jsonparser.Get(json, "devices", classCode)
+	if err != nil {
+		return false
+	}
+	defined := false
+	_, err = jsonparser.ArrayEach(class, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		if covers(value) {
+			defined = true
+		}
+	}, "oneOf")
+	if err != nil {
+		return covers(class)
+	}
+	return defined
+}
+
+// getProperty get the property epc of the object eoj and return EDT
+func (node *Node) getProperty(eoj [3]uint8, epc uint8) ([]uint8, error) {
+	get := FrameFormat{
+		EHD1:      0x10,
+		EHD2:      0x81,
+		TID:       node.client.nextTID(),
+		SEOJ:      node.client.SEOJ,
+		DEOJ:      eoj,
+		ESV:       0x62,
+		OPC:       0x01,
+		VarGroups: []VarByteGroup{{EPC: epc, PDC: 0x00}},
+	}
+	node.logger.Info("sent packet", zap.String("payload", frameHex(&get)))
+	recv, err := node.exchange(get, node.replyTimeout())
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to get %02X of %s: %w", epc, eojString(eoj), err)
+	}
+	node.logger.Info("received packet", zap.String("payload", frameHex(&recv)))
+	if recv.ESV != 0x72 {
+		return nil, xerrors.Errorf("Get %02X of %s is not accepted: %w", epc, eojString(eoj), &SNAError{Response: &Response{Frame: recv, Properties: recv.VarGroups}})
+	} else if len(recv.VarGroups) == 0 || recv.VarGroups[0].EPC != epc {
+		return nil, xerrors.Errorf("Reply to Get %02X of %s: %w", epc, eojString(eoj), &MalformedError{Data: echonetToByte(recv), Err: xerrors.Errorf("EPC %02X is not replied", epc)})
+	}
+	return recv.VarGroups[0].EDT, nil
+}
+
+// detectReleases get Version information (0x82) of objects in instList, whose first is the node profile,
+// and return the release each object is created with. A release which isn't detected is asked once,
+// a release class definitions don't have falls back to the latest one, and the node profile uses the release of the first device object
+func (node *Node) detectReleases(instList [][3]uint8, json []byte) []string {
+	retReleases := make([]string, len(instList))
+	asked := ""
+	nodeRelease := ""
+	for i := 1; i < len(instList); i++ {
+		eoj := instList[i]
+		edt, err := node.getProperty(eoj, 0x82)
+		if err == nil {
+			var revision uint8
+			retReleases[i], revision, err = decodeRelease(edt)
+			node.logger.Info("Detect release", zap.String("instance", eojString(eoj)), zap.String("release", retReleases[i]), zap.Uint8("revision", revision))
+		}
+		if err != nil {
+			node.logger.Error("Detect release Failed", zap.String("instance", eojString(eoj)), zap.String("message", err.Error()))
+			node.reportRelease(eoj, "RELEASE-INVALID", fmt.Sprintf("Couldn't detect release of %s: %s", eojString(eoj), err))
+			if asked == "" {
+				node.printf("(ECHONET Lite:Information)> Input ECHONET Lite Version that test device %s use ('A' ~ '%s')\n", node.ip, latestRelease(json))
+				node.printf("(Input)> ")
+				asked = strings.ToUpper(node.nextLine())
+			}
+			retReleases[i] = asked
+		}
+		if !definedRelease(json, eoj, retReleases[i]) {
+			latest := latestRelease(json)
+			node.reportRelease(eoj, "RELEASE-UNDEFINED", fmt.Sprintf("Class definitions don't have %s in release %s, release %s is used", eojString(eoj), retReleases[i], latest))
+			retReleases[i] = latest
+		}
+		if nodeRelease == "" {
+			nodeRelease = retReleases[i]
+		}
+	}
+	if nodeRelease == "" {
+		nodeRelease = latestRelease(json)
+	}
+	retReleases[0] = nodeRelease
+
+	edt, err := node.getProperty(instList[0], 0x82)
+	if err != nil {
+		node.logger.Error("Get ECHONET Lite version Failed", zap.String("message", err.Error()))
+		return retReleases
+	}
+	major, minor, err := decodeLiteVersion(edt)
+	if err != nil {
+		node.logger.Error("Get ECHONET Lite version Failed", zap.String("message", err.Error()))
+		return retReleases
+	}
+	for i := 1; i < len(instList); i++ {
+		node.checkReleaseVersion(instList[i], retReleases[i], major, minor)
+	}
+	return retReleases
+}
+
+// checkReleaseVersion check the release of the device object eoj is based on the ECHONET Lite version of the node profile or older
+func (node *Node) checkReleaseVersion(eoj [3]uint8, release string, major uint8, minor uint8) {
+	base, ok := releaseBases[release]
+	if !ok {
+		return
+	}
+	if major < base[0] || (major == base[0] && minor < base[1]) {
+		node.reportRelease(eoj, "RELEASE-VERSION-MISMATCH", fmt.Sprintf("Release %s of %s is based on ECHONET Lite %d.%02d, but the node profile declares %d.%02d", release, eojString(eoj), base[0], base[1], major, minor))
+	}
+}
+
+// reportRelease report a finding about the release of the object eoj
+func (node *Node) reportRelease(eoj [3]uint8, rule string, message string) {
+	defer node.endCheck(node.beginCheck("Release", eoj, "", ""))
+	node.report(Finding{RuleID: rule, EPC: "82", Message: message})
+}
@@ -0,0 +1,68 @@
+package echonetlite
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func Test_decodeRelease(t *testing.T) {
+	tests := []struct {
+		edt      []uint8
+		release  string
+		revision uint8
+		wantErr  bool
+	}{
+		{[]uint8{0x00, 0x00, 'M', 0x01}, "M", 0x01, false},
+		{[]uint8{0x00, 0x00, 'J', 0x00}, "J", 0x00, false},
+		{[]uint8{0x00, 0x00, 'J'}, "", 0, true},
+		{[]uint8{0x01, 0x0D, 0x01, 0x00}, "", 0, true},
+		{[]uint8{0x00, 0x00, 'm', 0x00}, "", 0, true},
+	}
+	for _, tt := range tests {
+		release, revision, err := decodeRelease(tt.edt)
+		if (err != nil) != tt.wantErr || release != tt.release || revision != tt.revision {
+			t.Errorf("decodeRelease(%X) => %q, %d, %v", tt.edt, release, revision, err)
+		}
+	}
+}
+
+func Test_definedRelease(t *testing.T) {
+	json, err := ioutil.ReadFile("class.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latestRelease(json) != "M" {
+		t.Errorf("latestRelease => %s, want M", latestRelease(json))
+	}
+	tests := []struct {
+		eoj     [3]uint8
+		release string
+		want    bool
+	}{
+		{[3]uint8{0x0E, 0xF0, 0x01}, "A", true},
+		{[3]uint8{0x02, 0x60, 0x01}, "C", true},
+		{[3]uint8{0x02, 0x60, 0x01}, "M", true},
+		{[3]uint8{0x0E, 0xF0, 0x01}, "N", false},
+		{[3]uint8{0x0F, 0xFF, 0x01}, "M", false},
+	}
+	for _, tt := range tests {
+		if got := definedRelease(json, tt.eoj, tt.release); got != tt.want {
+			t.Errorf("definedRelease(%X, %s) => %v, want %v", tt.eoj, tt.release, got, tt.want)
+		}
+	}
+}
+
+func Test_checkReleaseVersion(t *testing.T) {
+	node := newTestNode()
+	eoj := [3]uint8{0x01, 0x30, 0x01}
+	node.checkReleaseVersion(eoj, "M", 1, 13)
+	node.checkReleaseVersion(eoj, "J", 1, 14)
+	node.checkReleaseVersion(eoj, "Z", 1, 0)
+	if len(node.result.Findings) != 0 {
+		t.Fatalf("findings => %v, want none", node.result.Findings)
+	}
+	node.checkReleaseVersion(eoj, "M", 1, 12)
+	if len(node.result.Findings) != 1 || node.result.Findings[0].RuleID != "RELEASE-VERSION-MISMATCH" || node.result.Findings[0].Instance != "013001" {
+		t.Errorf("findings => %v, want RELEASE-VERSION-MISMATCH", node.result.Findings)
+	}
+}
@@ -0,0 +1,443 @@
+package echonetlite
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/c-bata/go-prompt"
+	"github.com/tttfrfr2/ECHONETTester/util"
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+// maxHistory is the number of commands loaded from the history file
+const maxHistory = 1000
+
+// replCommand is a one-line command of the prompt like "get 192.168.1.5 013001 80 b3"
+type replCommand struct {
+	usage       string
+	description string
+	esv         uint8 // service sent, or 0 if the command doesn't send a request
+	withEDT     bool  // properties are given as EPC=EDT
+	access      func(prop Property) bool
+}
+
+// replCommands are one-line commands of the prompt
+var replCommands = map[string]replCommand{
+	"get": {
+		usage:       "get IP EOJ EPC...",
+		description: "Get properties and print the values",
+		esv:         0x62,
+		access:      func(prop Property) bool { return prop.ImplementGet },
+	},
+	"set": {
+		usage:       "set IP EOJ EPC=EDT...",
+		description: "Set properties with SetC and print the reply",
+		esv:         0x61,
+		withEDT:     true,
+		access:      func(prop Property) bool { return prop.ImplementSet },
+	},
+	"seti": {
+		usage:       "seti IP EOJ EPC=EDT...",
+		description: "Set properties with SetI",
+		esv:         0x60,
+		withEDT:     true,
+		access:      func(prop Property) bool { return prop.ImplementSet },
+	},
+	"inf": {
+		usage:       "inf IP EOJ EPC...",
+		description: "Request notifications of properties with INF_REQ",
+		esv:         0x63,
+		access:      func(prop Property) bool { return prop.ImplementInf },
+	},
+	"watch": {
+		usage:       "watch IP EOJ EPC... [INTERVAL]",
+		description: "Get properties every interval (default 1s) and receive their notifications until Enter, then export the values as CSV",
+		access:      func(prop Property) bool { return prop.ImplementGet || prop.ImplementInf },
+	},
+	"props": {
+		usage:       "props IP EOJ",
+		description: "Print properties of an instance with their property maps and values accepted",
+	},
+	"nodes": {
+		usage:       "nodes",
+		description: "Print nodes and their instances",
+	},
+	"help": {
+		usage:       "help",
+		description: "Print one-line commands",
+	},
+}
+
+// replCommandNames return names of one-line commands in order
+func replCommandNames() []string {
+	var retNames []string
+	for name := range replCommands {
+		retNames = append(retNames, name)
+	}
+	sort.Strings(retNames)
+	return retNames
+}
+
+// historyPath return the path of the file commands of the prompt are stored
+func (a *Auditor) historyPath() string {
+	return a.logDir() + "/history"
+}
+
+// loadHistory return commands stored in the history file, the latest last
+func (a *Auditor) loadHistory() []string {
+	var retHistory []string
+	f, err := os.Open(a.historyPath())
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if line := strings.TrimSpace(s.Text()); line != "" {
+			retHistory = append(retHistory, line)
+		}
+	}
+	if len(retHistory) > maxHistory {
+		retHistory = retHistory[len(retHistory)-maxHistory:]
+	}
+	return retHistory
+}
+
+// appendHistory store in into the history file
+func (a *Auditor) appendHistory(in string) {
+	f, err := os.OpenFile(a.historyPath(), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+	if err != nil {
+		a.logger.Error("Write history Failed", zap.String("message", err.Error()))
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, in)
+}
+
+// replInstance return the node of ip and its instance designated by code like "013001"
+func (a *Auditor) replInstance(ip string, code string) (*Node, *Instance, error) {
+	dstIP := net.ParseIP(ip)
+	if dstIP == nil {
+		return nil, nil, xerrors.Errorf("Invalid IP address %s", ip)
+	}
+	for i := range a.DistNodes {
+		node := &a.DistNodes[i]
+		if !node.ip.Equal(dstIP) {
+			continue
+		}
+		eoj, err := parseEOJ(code)
+		if err != nil {
+			return node, nil, err
+		}
+		for j := range node.Instances {
+			if node.Instances[j].ClassCode == eoj {
+				return node, &node.Instances[j], nil
+			}
+		}
+		return node, nil, xerrors.Errorf("Instance %s is not found in %s", strings.ToUpper(code), ip)
+	}
+	return nil, nil, xerrors.Errorf("Node %s is not found", ip)
+}
+
+// findProp return the property of inst whose EPC is epc
+func findProp(inst *Instance, epc uint8) (Property, bool) {
+	for _, prop := range inst.Props {
+		if prop.EPC == epc {
+			return prop, true
+		}
+	}
+	return Property{}, false
+}
+
+// parseGroups change arguments like "80" or "80=30" into groups of a request
+func parseGroups(args []string, withEDT bool) ([]VarByteGroup, error) {
+	var retGroups []VarByteGroup
+	for _, arg := range args {
+		epcText, edtText := arg, ""
+		if withEDT {
+			i := strings.Index(arg, "=")
+			if i < 0 {
+				return nil, xerrors.Errorf("%s should be EPC=EDT like 80=30", arg)
+			}
+			epcText, edtText = arg[:i], arg[i+1:]
+		}
+		epc, err := strconv.ParseUint(strings.TrimPrefix(epcText, "0x"), 16, 8)
+		if err != nil {
+			return nil, xerrors.Errorf("Invalid EPC %s", epcText)
+		}
+		edt, err := hex.DecodeString(strings.TrimPrefix(edtText, "0x"))
+		if err != nil || len(edt) > 0xFF {
+			return nil, xerrors.Errorf("Invalid EDT %s of EPC %02X", edtText, epc)
+		}
+		retGroups = append(retGroups, VarByteGroup{EPC: uint8(epc), PDC: uint8(len(edt)), EDT: edt})
+	}
+	if len(retGroups) == 0 {
+		return nil, xerrors.Errorf("No EPC")
+	}
+	return retGroups, nil
+}
+
+// executeCommand run a one-line command. Return false if in is not a one-line command
+func (a *Auditor) executeCommand(in string) bool {
+	fields := strings.Fields(in)
+	if len(fields) == 0 {
+		return false
+	}
+	name := strings.ToLower(fields[0])
+	command, ok := replCommands[name]
+	if !ok {
+		return false
+	}
+	err := a.runCommand(name, command, fields[1:])
+	if err != nil {
+		a.printf("(ECHONET Lite:Error) > %s\n", err)
+		a.printf("(ECHONET Lite:Information)> Usage: %s\n", command.usage)
+	}
+	return true
+}
+
+func (a *Auditor) runCommand(name string, command replCommand, args []string) error {
+	switch name {
+	case "help":
+		for _, n := range replCommandNames() {
+			a.printf("   %-24s %s\n", replCommands[n].usage, replCommands[n].description)
+		}
+		return nil
+	case "nodes":
+		for _, node := range a.DistNodes {
+			a.printf("   %s\n", node.ip)
+			for _, inst := range node.Instances {
+				a.printf("     %s %s (release %s)\n", eojString(inst.ClassCode), inst.ClassName, inst.release)
+			}
+		}
+		return nil
+	}
+	if len(args) < 2 {
+		return xerrors.Errorf("IP and EOJ are required")
+	}
+	node, inst, err := a.replInstance(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	if name == "props" {
+		for _, prop := range inst.Props {
+			a.printf("   %02X %-40s %-3s %s\n", prop.EPC, prop.PropertyName, accessString(prop), propertyHint(prop))
+		}
+		return nil
+	}
+	if name == "watch" {
+		return a.runWatch(node, inst, args[2:])
+	}
+	groups, err := parseGroups(args[2:], command.withEDT)
+	if err != nil {
+		return err
+	}
+
+	if command.esv == 0x63 {
+		for _, group := range groups {
+			edt, err := node.requestInf(inst.ClassCode, group.EPC)
+			if err != nil {
+				a.printf("   %02X no notification: %s\n", group.EPC, err)
+				continue
+			}
+			printGroup(a.Prompter, inst, VarByteGroup{EPC: group.EPC, PDC: uint8(len(edt)), EDT: edt})
+		}
+		return nil
+	}
+	recv, err := a.Request(node.ip, inst.ClassCode, command.esv, groups)
+	if err != nil {
+		return err
+	}
+	if recv.EHD1 == 0 {
+		a.printf("   no reply\n")
+		return nil
+	}
+	a.printf("   ESV:%02X\n", recv.ESV)
+	for _, group := range recv.VarGroups {
+		printGroup(a.Prompter, inst, group)
+	}
+	return nil
+}
+
+// runWatch watch properties of inst designated by args like "80 B3 5s" until Enter is input, and export the values as CSV
+func (a *Auditor) runWatch(node *Node, inst *Instance, args []string) error {
+	interval := time.Second
+	if len(args) > 0 {
+		if d, err := time.ParseDuration(args[len(args)-1]); err == nil {
+			if d <= 0 {
+				return xerrors.Errorf("Invalid interval %s", args[len(args)-1])
+			}
+			interval, args = d, args[:len(args)-1]
+		}
+	}
+	groups, err := parseGroups(args, false)
+	if err != nil {
+		return err
+	}
+	var epcs []uint8
+	for _, group := range groups {
+		epcs = append(epcs, group.EPC)
+	}
+
+	// the line is read here, not in the goroutine, so no reader is left when watch ends by itself
+	stop := make(chan struct{})
+	done := make(chan []WatchSample, 1)
+	go func() {
+		samples, err := a.Watch(node.ip, inst.ClassCode, epcs, interval, a.Prompter, true, stop)
+		if err != nil {
+			a.printf("(ECHONET Lite:Error) > %s\n", err)
+		}
+		if err != nil || node.canceled() != nil {
+			a.printf("(ECHONET Lite:Information)> Watch stopped. Press Enter\n")
+		}
+		done <- samples
+	}()
+	a.nextLine()
+	close(stop)
+	samples := <-done
+	if len(samples) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	err = WriteWatchCSV(&buf, samples)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(a.resultDir(), fmt.Sprintf("%s-watch-%s-%s.csv", a.Result.RunID, node.ip, eojString(inst.ClassCode)))
+	err = util.WriteByteFile(path, buf.Bytes(), false)
+	if err != nil {
+		return xerrors.Errorf("Failed to export watch: %w", err)
+	}
+	a.printf("(ECHONET Lite:Information)> %d values are exported to %s\n", len(samples), path)
+	return nil
+}
+
+// accessString return access rules implemented by prop like "GS-"
+func accessString(prop Property) string {
+	access := []byte("---")
+	if prop.ImplementGet {
+		access[0] = 'G'
+	}
+	if prop.ImplementSet {
+		access[1] = 'S'
+	}
+	if prop.ImplementInf {
+		access[2] = 'I'
+	}
+	return string(access)
+}
+
+// printGroup print a property in a reply with its name
+func printGroup(w io.Writer, inst *Instance, group VarByteGroup) {
+	name := ""
+	if prop, ok := findProp(inst, group.EPC); ok {
+		name = prop.PropertyName
+	}
+	fmt.Fprintf(w, "   %02X %-40s PDC:%d EDT:%X\n", group.EPC, name, group.PDC, group.EDT)
+}
+
+// commandSuggests return suggestions of names of one-line commands
+func commandSuggests() []prompt.Suggest {
+	var retSuggests []prompt.Suggest
+	for _, name := range replCommandNames() {
+		retSuggests = append(retSuggests, prompt.Suggest{Text: name, Description: replCommands[name].description})
+	}
+	return retSuggests
+}
+
+// completeCommand return suggestions for arguments of a one-line command being input.
+// ok is false if the input is not arguments of a one-line command
+func (a *Auditor) completeCommand(d prompt.Document) ([]prompt.Suggest, bool) {
+	text := d.TextBeforeCursor()
+	fields := strings.Fields(text)
+	index := len(fields) // the argument being input
+	if index > 0 && !strings.HasSuffix(text, " ") {
+		index--
+	}
+	word := d.GetWordBeforeCursor()
+	if index == 0 {
+		return nil, false
+	}
+	name := strings.ToLower(fields[0])
+	command, ok := replCommands[name]
+	if !ok {
+		return nil, false
+	}
+	if name == "nodes" || name == "help" {
+		return nil, true
+	}
+
+	var s []prompt.Suggest
+	switch {
+	case index == 1:
+		for _, node := range a.DistNodes {
+			s = append(s, prompt.Suggest{Text: node.ip.String(), Description: fmt.Sprintf("%d instances", len(node.Instances))})
+		}
+	case index == 2:
+		for _, node := range a.DistNodes {
+			if node.ip.String() != fields[1] {
+				continue
+			}
+			for _, inst := range node.Instances {
+				s = append(s, prompt.Suggest{Text: eojString(inst.ClassCode), Description: inst.ClassName})
+			}
+		}
+	case command.access != nil:
+		_, inst, err := a.replInstance(fields[1], fields[2])
+		if err != nil {
+			return nil, true
+		}
+		if i := strings.Index(word, "="); command.withEDT && i >= 0 {
+			// value of the property
+			epc, err := strconv.ParseUint(word[:i], 16, 8)
+			if prop, ok := findProp(inst, uint8(epc)); err == nil && ok {
+				s = valueSuggests(word[:i], prop)
+			}
+			break
+		}
+		for _, prop := range inst.Props {
+			if !command.access(prop) {
+				continue
+			}
+			text := fmt.Sprintf("%02X", prop.EPC)
+			if command.withEDT {
+				text += "="
+			}
+			s = append(s, prompt.Suggest{Text: text, Description: prop.PropertyName})
+		}
+	}
+	return prompt.FilterHasPrefix(s, word, true), true
+}
+
+// valueSuggests return suggestions of EPC=EDT for prop. States are suggested one by one, and others with the hint
+func valueSuggests(epc string, prop Property) []prompt.Suggest {
+	var retSuggests []prompt.Suggest
+	for _, data := range prop.Data {
+		state, ok := data.(State)
+		if !ok {
+			continue
+		}
+		size := int(state.size)
+		if size == 0 {
+			size = 1
+		}
+		for _, e := range state.enum {
+			retSuggests = append(retSuggests, prompt.Suggest{Text: fmt.Sprintf("%s=%0*X", epc, size*2, e.edt), Description: e.state})
+		}
+	}
+	if len(retSuggests) == 0 {
+		retSuggests = append(retSuggests, prompt.Suggest{Text: epc + "=", Description: propertyHint(prop)})
+	}
+	return retSuggests
+}
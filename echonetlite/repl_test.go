@@ -0,0 +1,125 @@
+package echonetlite
+
+import (
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/c-bata/go-prompt"
+)
+
+func newTestAuditor() *Auditor {
+	node := newTestNode()
+	node.Instances = []Instance{
+		{ClassCode: [3]uint8{0x0E, 0xF0, 0x01}, ClassName: "Node profile"},
+		{ClassCode: [3]uint8{0x01, 0x30, 0x01}, ClassName: "Home air conditioner", Props: []Property{
+			{EPC: 0x80, PropertyName: "Operation status", ImplementGet: true, ImplementSet: true, ImplementInf: true,
+				Data: []interface{}{State{size: 1, enum: []enumber{{edt: 0x30, state: "ON"}, {edt: 0x31, state: "OFF"}}}}},
+			{EPC: 0xB3, PropertyName: "Temperature setting", ImplementGet: true, ImplementSet: true,
+				Data: []interface{}{Number{format: "uint8", minimum: 0, maximum: 50, unit: "Celsius"}}},
+			{EPC: 0xBB, PropertyName: "Measured room temperature", ImplementGet: true},
+		}},
+	}
+	return &Auditor{DistNodes: []Node{*node}}
+}
+
+func complete(a *Auditor, text string) []string {
+	b := prompt.NewBuffer()
+	b.InsertText(text, false, true)
+	s, _ := a.completeCommand(*b.Document())
+	var retTexts []string
+	for _, suggest := range s {
+		retTexts = append(retTexts, suggest.Text)
+	}
+	return retTexts
+}
+
+func Test_completeCommand(t *testing.T) {
+	a := newTestAuditor()
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"get ", []string{"192.0.2.1"}},
+		{"get 192.0.2.1 01", []string{"013001"}},
+		{"get 192.0.2.1 013001 ", []string{"80", "B3", "BB"}},
+		{"set 192.0.2.1 013001 ", []string{"80=", "B3="}},
+		{"inf 192.0.2.1 013001 ", []string{"80"}},
+		{"set 192.0.2.1 013001 80=", []string{"80=30", "80=31"}},
+		{"set 192.0.2.1 013001 b3=", []string{"b3="}},
+	}
+	for _, tt := range tests {
+		got := complete(a, tt.text)
+		if len(got) != len(tt.want) {
+			t.Errorf("complete(%q) => %v, want %v", tt.text, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("complete(%q) => %v, want %v", tt.text, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func Test_parseGroups(t *testing.T) {
+	groups, err := parseGroups([]string{"80=30", "0xB3=0x1A"}, true)
+	if err != nil || len(groups) != 2 || groups[1].EPC != 0xB3 || groups[1].PDC != 1 || groups[1].EDT[0] != 0x1A {
+		t.Errorf("parseGroups => %+v, %v", groups, err)
+	}
+	for _, args := range [][]string{{"80"}, {"80=3"}, {"GG=30"}, {}} {
+		if _, err := parseGroups(args, true); err == nil {
+			t.Errorf("parseGroups(%v) => no error", args)
+		}
+	}
+	if groups, err := parseGroups([]string{"80", "b3"}, false); err != nil || len(groups) != 2 || groups[0].PDC != 0 {
+		t.Errorf("parseGroups of Get => %+v, %v", groups, err)
+	}
+}
+
+func Test_dataHint(t *testing.T) {
+	a := newTestAuditor()
+	props := a.DistNodes[0].Instances[1].Props
+	if got := propertyHint(props[0]); got != "30=ON 31=OFF" {
+		t.Errorf("propertyHint of State => %q", got)
+	}
+	if got := propertyHint(props[1]); got != "uint8 0..50 Celsius" {
+		t.Errorf("propertyHint of Number => %q", got)
+	}
+}
+
+func Test_runWatch_interrupted(t *testing.T) {
+	a := newTestAuditor()
+	a.Result = NewResult("test")
+	a.ResultDir = t.TempDir()
+	node := &a.DistNodes[0]
+	node.op = a.operation()
+	node.client, _ = newFakeClient(t, func(req *FrameFormat) []byte {
+		return echonetToByte(FrameFormat{EHD1: 0x10, EHD2: 0x81, TID: req.TID, SEOJ: req.DEOJ, DEOJ: req.SEOJ, ESV: 0x72, OPC: 1,
+			VarGroups: []VarByteGroup{{EPC: 0x80, PDC: 1, EDT: []uint8{0x30}}}})
+	})
+	in, lines := io.Pipe()
+	defer lines.Close()
+	a.Prompter = NewPrompter(in, ioutil.Discard)
+
+	done := make(chan error, 1)
+	go func() { done <- a.runWatch(node, &node.Instances[1], []string{"80", "20ms"}) }()
+	time.Sleep(100 * time.Millisecond)
+	a.Interrupt()
+	io.WriteString(lines, "\n")
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("runWatch => %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("runWatch doesn't return after Enter")
+	}
+	// no reader of the Prompter is left by watch
+	go io.WriteString(lines, "next\n")
+	if line := a.nextLine(); line != "next" {
+		t.Errorf("the line after watch => %q, want next", line)
+	}
+}
@@ -0,0 +1,173 @@
+package echonetlite
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tttfrfr2/ECHONETTester/util"
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+// campaignSeed return the seed of a fuzzing campaign derived from the seed of the run.
+// The same run seed gives the same campaign seed regardless of the order campaigns are executed
+func (a *Auditor) campaignSeed(strategy string, node string, instance string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(strategy + "/" + node + "/" + instance))
+	return a.Seed ^ int64(h.Sum64())
+}
+
+// recordCase store c in a.Result and append it to the corpus of the campaign
+func (a *Auditor) recordCase(c FuzzCase) {
+	if a.Result == nil {
+		return
+	}
+	a.Result.AddFuzzCase(c)
+	err := util.WriteByteFile(corpusPath(a.resultDir(), a.Result.RunID, c), []byte(c.Sent+"\n"), true)
+	if err != nil && a.logger != nil {
+		a.logger.Error("Write corpus Failed", zap.String("message", err.Error()))
+	}
+}
+
+// corpusPath return the path of corpus file under the result directory dir of the campaign c belongs to.
+// One case is written in HEX per line in the order they were sent
+func corpusPath(dir string, runID string, c FuzzCase) string {
+	name := strings.NewReplacer("/", "-", " ", "").Replace(c.Strategy) + "_" + c.Node + "_" + c.Instance + ".hex"
+	return filepath.Join(dir, "corpus", runID, name)
+}
+
+// LoadCorpus read cases from corpus file written during fuzzing.
+// Index of cases is the line number
+func LoadCorpus(path string) ([]FuzzCase, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to open corpus %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var retCases []FuzzCase
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if _, err := hex.DecodeString(text); err != nil {
+			return nil, xerrors.Errorf("Invalid HEX at line %d of corpus %s: %w", line, path, err)
+		}
+		retCases = append(retCases, FuzzCase{Strategy: "corpus", Index: line, Sent: text})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, xerrors.Errorf("Failed to read corpus %s: %w", path, err)
+	}
+	return retCases, nil
+}
+
+// ReplayResult is the reply to a case sent again and the comparison with the reply observed originally
+type ReplayResult struct {
+	Case    FuzzCase // case sent originally
+	Recv    string   // reply to the case sent again in HEX
+	Timeout bool
+	RTT     time.Duration
+
+	// Match is true if the reply is the same as the original one.
+	// If the original reply is unknown, Match is always false
+	Match bool
+	// SameESV is true if ESV of the reply is the same as the original one, or both timed out
+	SameESV bool
+}
+
+// Replay send cases to the node designated by dstIP in order and compare the replies with the original ones
+func (a *Auditor) Replay(dstIP net.IP, cases []FuzzCase) ([]ReplayResult, error) {
+	var node *Node
+	for i := range a.DistNodes {
+		if a.DistNodes[i].ip.Equal(dstIP) {
+			node = &a.DistNodes[i]
+			break
+		}
+	}
+	if node == nil {
+		return nil, xerrors.Errorf("Node %s is not found", dstIP)
+	}
+	node.logger.Info("Start replay", zap.Int("cases", len(cases)))
+
+	var retResults []ReplayResult
+	for _, c := range cases {
+		if err := node.canceled(); err != nil {
+			return retResults, xerrors.Errorf("Replay stopped: %w", err)
+		}
+		data, err := hex.DecodeString(c.Sent)
+		if err != nil {
+			return retResults, xerrors.Errorf("Invalid HEX of case #%d: %w", c.Index, err)
+		}
+		node.logger.Info("sent packet", zap.String("strategy", c.Strategy), zap.Int("index", c.Index), zap.String("payload", c.Sent))
+		result := ReplayResult{Case: c}
+		start := time.Now()
+		err = SendRaw(data, node.client.conn)
+		if err != nil {
+			return retResults, xerrors.Errorf("Failed to send case #%d at replay: %w", c.Index, err)
+		}
+		reply, err := node.recvReplyTo(data, node.replyTimeout())
+		result.RTT = time.Since(start)
+		if err != nil {
+			if !xerrors.Is(err, ErrTimeout) {
+				return retResults, xerrors.Errorf("Failed to recieve reply of case #%d at replay: %w", c.Index, err)
+			}
+			result.Timeout = true
+		} else {
+			result.Recv = hex.EncodeToString(reply)
+		}
+		result.compare()
+		retResults = append(retResults, result)
+	}
+	node.logger.Info("Finished replay")
+	return retResults, nil
+}
+
+// compare the reply with the original one
+func (r *ReplayResult) compare() {
+	original, _ := hex.DecodeString(r.Case.Recv)
+	reply, _ := hex.DecodeString(r.Recv)
+	if r.Case.Recv != "" || r.Case.Timeout {
+		r.Match = bytes.Equal(original, reply) && r.Timeout == r.Case.Timeout
+	}
+	if len(original) > offsetESV && len(reply) > offsetESV {
+		r.SameESV = original[offsetESV] == reply[offsetESV]
+	} else {
+		r.SameESV = r.Timeout && r.Case.Timeout
+	}
+}
+
+// WriteReplay write results of Replay as text into w
+func WriteReplay(w io.Writer, results []ReplayResult) {
+	for _, r := range results {
+		verdict := "DIFF"
+		if r.Match {
+			verdict = "SAME"
+		} else if r.Case.Recv == "" && !r.Case.Timeout {
+			verdict = "----"
+		} else if r.SameESV {
+			verdict = "ESV "
+		}
+		recv := r.Recv
+		if r.Timeout {
+			recv = "timeout"
+		}
+		original := r.Case.Recv
+		if r.Case.Timeout {
+			original = "timeout"
+		}
+		fmt.Fprintf(w, "%s %s #%d sent:%s\n", verdict, r.Case.Strategy, r.Case.Index, r.Case.Sent)
+		fmt.Fprintf(w, "     original:%s\n     replayed:%s (%s)\n", original, recv, r.RTT)
+	}
+}
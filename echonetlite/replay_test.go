@@ -0,0 +1,92 @@
+package echonetlite
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_campaignSeed(t *testing.T) {
+	a := &Auditor{Seed: 12345}
+	seed := a.campaignSeed("OPC Fuzz", "192.0.2.1", "013001")
+	if seed != a.campaignSeed("OPC Fuzz", "192.0.2.1", "013001") {
+		t.Errorf("campaignSeed is not stable")
+	}
+	if seed == a.campaignSeed("OPC Fuzz", "192.0.2.1", "013002") {
+		t.Errorf("campaignSeed of different instances are the same")
+	}
+
+	prop := Property{EPC: 0xB3, Data: []interface{}{Raw{minSize: 1, maxSize: 16}}}
+	first, err := RandProp(prop, rand.New(rand.NewSource(seed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := RandProp(prop, rand.New(rand.NewSource(seed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(first) != string(second) {
+		t.Errorf("RandProp with the same seed => %X, %X", first, second)
+	}
+}
+
+func Test_LoadCorpus(t *testing.T) {
+	dir, err := ioutil.TempDir("", "corpus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "OPCFuzz_192.0.2.1_013001.hex")
+	err = ioutil.WriteFile(path, []byte("1081000105ff010130016201800\n\n1081000205ff0101300162018000\n"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCorpus(path); err == nil {
+		t.Errorf("LoadCorpus accepted odd length HEX")
+	}
+
+	err = ioutil.WriteFile(path, []byte("1081000105ff0101300162018000\n\n1081000205ff0101300162018000\n"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases, err := LoadCorpus(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cases) != 2 || cases[0].Index != 1 || cases[1].Index != 3 {
+		t.Errorf("cases => %+v, want index 1 and 3", cases)
+	}
+}
+
+func Test_ReplayResult_compare(t *testing.T) {
+	reply := "108100010130010ef0017201800130"
+	sna := "108100010130010ef0015201800130"
+	cases := []struct {
+		name    string
+		r       ReplayResult
+		match   bool
+		sameESV bool
+	}{
+		{"same", ReplayResult{Case: FuzzCase{Recv: reply}, Recv: reply}, true, true},
+		{"different ESV", ReplayResult{Case: FuzzCase{Recv: reply}, Recv: sna}, false, false},
+		{"both timeout", ReplayResult{Case: FuzzCase{Timeout: true}, Timeout: true}, true, true},
+		{"timeout now", ReplayResult{Case: FuzzCase{Recv: reply}, Timeout: true}, false, false},
+		{"unknown original", ReplayResult{Case: FuzzCase{}, Recv: reply}, false, false},
+	}
+	for _, c := range cases {
+		c.r.compare()
+		if c.r.Match != c.match || c.r.SameESV != c.sameESV {
+			t.Errorf("%s: match, sameESV => %v, %v, want %v, %v", c.name, c.r.Match, c.r.SameESV, c.match, c.sameESV)
+		}
+	}
+}
+
+func Test_corpusPath(t *testing.T) {
+	c := FuzzCase{Strategy: "Frame Fuzz/EHD", Node: "192.0.2.1", Instance: "013001"}
+	want := filepath.Join("out", "corpus", "run", "FrameFuzz-EHD_192.0.2.1_013001.hex")
+	if path := corpusPath("out", "run", c); path != want {
+		t.Errorf("corpusPath => %s, want %s", path, want)
+	}
+}
@@ -0,0 +1,187 @@
+package echonetlite
+
+import (
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+)
+
+// frameField is a field of decoded frame shown in HTML report
+type frameField struct {
+	Name  string
+	Value string
+}
+
+// decodeFrame decode HEX string of frame into fields
+func decodeFrame(hexFrame string) []frameField {
+	if hexFrame == "" {
+		return nil
+	}
+	data, err := hex.DecodeString(hexFrame)
+	if err != nil {
+		return []frameField{{Name: "Error", Value: err.Error()}}
+	}
+	frame, err := parser(data)
+	if err != nil {
+		return []frameField{{Name: "Malformed", Value: err.Error()}}
+	}
+	fields := []frameField{
+		{"EHD1", fmt.Sprintf("%02X", frame.EHD1)},
+		{"EHD2", fmt.Sprintf("%02X", frame.EHD2)},
+		{"TID", fmt.Sprintf("%04X", frame.TID)},
+		{"SEOJ", eojString(frame.SEOJ)},
+		{"DEOJ", eojString(frame.DEOJ)},
+		{"ESV", fmt.Sprintf("%02X", frame.ESV)},
+		{"OPC", fmt.Sprintf("%02X", frame.OPC)},
+	}
+	for i, group := range frame.VarGroups {
+		fields = append(fields, frameField{fmt.Sprintf("EPC%d", i+1), fmt.Sprintf("%02X (PDC:%02X) EDT:%X", group.EPC, group.PDC, group.EDT)})
+	}
+	if frame.ESV&0x0F == 0x0E {
+		fields = append(fields, frameField{"OPCGet", fmt.Sprintf("%02X", frame.OPCG)})
+		for i, group := range frame.VarGroupsG {
+			fields = append(fields, frameField{fmt.Sprintf("EPC%d", i+1), fmt.Sprintf("%02X (PDC:%02X) EDT:%X", group.EPC, group.PDC, group.EDT)})
+		}
+	}
+	return fields
+}
+
+// htmlCheck is a check with its findings shown in HTML report
+type htmlCheck struct {
+	CheckRecord
+	Findings []Finding
+}
+
+// htmlReport is the data rendered by reportTemplate
+type htmlReport struct {
+	Generated time.Time
+	Nodes     []NodeRecord
+	Checks    []htmlCheck
+	Unscoped  []Finding // findings reported outside any check
+	Fuzz      []FuzzStats
+	Passed    int
+	Failed    int
+	Severity  map[Severity]int
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"decode": decodeFrame,
+	"ms": func(d time.Duration) string {
+		return fmt.Sprintf("%.1f ms", float64(d)/float64(time.Millisecond))
+	},
+	"mapClass": func(rule string, implemented bool) string {
+		if rule == "required" && !implemented {
+			return "missing"
+		} else if rule == "notApplicable" && implemented {
+			return "forbidden"
+		} else if implemented {
+			return "implemented"
+		}
+		return ""
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>ECHONET Lite Test Report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #999; padding: 2px 6px; font-size: 90%; }
+th { background: #ddd; }
+.pass { color: #070; font-weight: bold; }
+.fail { color: #b00; font-weight: bold; }
+.missing { background: #fbb; }
+.forbidden { background: #fd9; }
+.implemented { background: #cfc; }
+.high { background: #f99; }
+.medium { background: #fc9; }
+.low { background: #ffc; }
+code { font-size: 85%; }
+</style>
+</head>
+<body>
+<h1>ECHONET Lite Test Report</h1>
+<p>Generated at {{.Generated.Format "2006-01-02 15:04:05"}}</p>
+
+<h2>Summary</h2>
+<table>
+<tr><th>Checks passed</th><td class="pass">{{.Passed}}</td></tr>
+<tr><th>Checks failed</th><td class="fail">{{.Failed}}</td></tr>
+{{range $sev, $n := .Severity}}<tr><th>Findings ({{$sev}})</th><td class="{{$sev}}">{{$n}}</td></tr>
+{{end}}</table>
+
+<h2>Nodes</h2>
+{{range .Nodes}}
+<h3>{{.IP}}</h3>
+{{range .Instances}}
+<details>
+<summary>{{.Code}} {{.ClassName}} (Release {{.Release}})</summary>
+<table>
+<tr><th>EPC</th><th>Property</th><th>Get</th><th>Set</th><th>Inf</th></tr>
+{{range .Props}}<tr><td>{{.EPC}}</td><td>{{.Name}}</td><td class="{{mapClass .Get .ImplementGet}}">{{.Get}}</td><td class="{{mapClass .Set .ImplementSet}}">{{.Set}}</td><td class="{{mapClass .Inf .ImplementInf}}">{{.Inf}}</td></tr>
+{{end}}</table>
+</details>
+{{end}}
+{{end}}
+
+<h2>Fuzzing</h2>
+<table>
+<tr><th>Strategy</th><th>Node</th><th>Instance</th><th>Start</th><th>Duration</th><th>Sent</th><th>Replied</th><th>Timeouts</th><th>Hangs</th><th>Reboots</th><th>Findings</th></tr>
+{{range .Fuzz}}<tr><td>{{.Strategy}}</td><td>{{.Node}}</td><td>{{.Instance}}</td><td>{{.Start.Format "15:04:05"}}</td><td>{{ms .Duration}}</td><td>{{.Sent}}</td><td>{{.Replied}}</td><td>{{.Timeouts}}</td><td>{{.Hangs}}</td><td>{{.Reboots}}</td><td>{{.Findings}}</td></tr>
+{{end}}</table>
+
+<h2>Checks</h2>
+{{range .Checks}}
+<details>
+<summary>#{{.ID}} {{.Name}} {{.Node}}/{{.Instance}} {{.Start.Format "15:04:05.000"}} ({{ms .Duration}}) {{if .Passed}}<span class="pass">PASS</span>{{else}}<span class="fail">FAIL</span>{{end}}</summary>
+{{if .Findings}}<table>
+<tr><th>Rule</th><th>Severity</th><th>EPC</th><th>Message</th><th>Specification</th></tr>
+{{range .Findings}}<tr class="{{.Severity}}"><td>{{.RuleID}}</td><td>{{.Severity}}</td><td>{{.EPC}}</td><td>{{.Message}}{{if .Minimized}}<br>Minimized: <code>{{.Minimized}}</code>{{end}}</td><td>{{.SpecRef}}</td></tr>
+{{end}}</table>{{end}}
+<table>
+<tr><th>Sent <code>{{.Sent}}</code></th><th>Received <code>{{.Recv}}</code></th></tr>
+<tr><td>{{range decode .Sent}}{{.Name}}: {{.Value}}<br>{{end}}</td><td>{{range decode .Recv}}{{.Name}}: {{.Value}}<br>{{end}}</td></tr>
+</table>
+</details>
+{{end}}
+{{if .Unscoped}}
+<h2>Findings outside checks</h2>
+<table>
+<tr><th>Rule</th><th>Severity</th><th>Node</th><th>Instance</th><th>EPC</th><th>Message</th><th>Specification</th><th>Sent</th><th>Received</th></tr>
+{{range .Unscoped}}<tr class="{{.Severity}}"><td>{{.RuleID}}</td><td>{{.Severity}}</td><td>{{.Node}}</td><td>{{.Instance}}</td><td>{{.EPC}}</td><td>{{.Message}}</td><td>{{.SpecRef}}</td><td><code>{{.Sent}}</code></td><td><code>{{.Recv}}</code></td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
+
+// WriteHTML write Result as a self-contained HTML report into w
+func (r *Result) WriteHTML(w io.Writer) error {
+	r.mu.Lock()
+	report := htmlReport{
+		Generated: time.Now(),
+		Nodes:     append([]NodeRecord(nil), r.Nodes...),
+		Fuzz:      append([]FuzzStats(nil), r.Fuzz...),
+		Severity:  make(map[Severity]int),
+	}
+	findingsOf := make(map[int][]Finding)
+	for _, f := range r.Findings {
+		findingsOf[f.CheckID] = append(findingsOf[f.CheckID], f)
+		report.Severity[f.Severity]++
+	}
+	for _, check := range r.Checks {
+		report.Checks = append(report.Checks, htmlCheck{CheckRecord: check, Findings: findingsOf[check.ID]})
+		if check.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+	}
+	report.Unscoped = findingsOf[0]
+	r.mu.Unlock()
+
+	return reportTemplate.Execute(w, report)
+}
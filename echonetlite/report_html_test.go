@@ -0,0 +1,28 @@
+package echonetlite
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func Test_WriteHTML(t *testing.T) {
+	node := newTestNode()
+	sent := FrameFormat{EHD1: 0x10, EHD2: 0x81, TID: 0x0001, DEOJ: [3]uint8{0x01, 0x30, 0x01}, ESV: 0x62, OPC: 0x01, VarGroups: []VarByteGroup{{EPC: 0x80}}}
+	node.Instances = []Instance{{ClassCode: sent.DEOJ, ClassName: "Home air conditioner", Props: []Property{{EPC: 0x80, PropertyName: "Operation status", Get: "required", ImplementGet: false}}}}
+	node.result.AddNode(node, "M")
+	node.Check(&sent, &FrameFormat{})
+	node.report(Finding{RuleID: "RECV-TRUNCATED", Message: "unscoped"})
+
+	var buf bytes.Buffer
+	err := node.result.WriteHTML(&buf)
+	if err != nil {
+		t.Fatalf("WriteHTML => %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"Home air conditioner", `<td class="missing">required</td>`, "RECV-NONE", "DEOJ: 013001", `<span class="fail">FAIL</span>`, "Findings outside checks", "unscoped"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("HTML report doesn't contain %q", want)
+		}
+	}
+}
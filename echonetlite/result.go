@@ -0,0 +1,496 @@
+package echonetlite
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tttfrfr2/ECHONETTester/util"
+	"golang.org/x/xerrors"
+)
+
+// ResultSchemaVersion is the version of ResultDocument layout.
+// Increment it when fields are changed incompatibly
+const ResultSchemaVersion = 1
+
+// ToolVersion is the version of this tool recorded in results
+const ToolVersion = "0.2.0"
+
+// CheckRecord is the verdict of a check executed
+type CheckRecord struct {
+	ID       int           `json:"id"`
+	Name     string        `json:"name"`
+	Node     string        `json:"node"`
+	Instance string        `json:"instance,omitempty"`
+	Sent     string        `json:"sent,omitempty"`
+	Recv     string        `json:"recv,omitempty"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+	Passed   bool          `json:"passed"`
+
+	running bool
+}
+
+// NodeRecord is the snapshot of a node discovered
+type NodeRecord struct {
+	IP        string           `json:"ip"`
+	Release   string           `json:"release"`
+	Instances []InstanceRecord `json:"instances"`
+}
+
+// InstanceRecord is the snapshot of an instance and its property maps
+type InstanceRecord struct {
+	Code      string           `json:"code"`
+	ClassName string           `json:"className"`
+	Release   string           `json:"release"`
+	Props     []PropertyRecord `json:"properties"`
+}
+
+// PropertyRecord is access rules of the class requirements and the implementation in property maps
+type PropertyRecord struct {
+	EPC          string `json:"epc"`
+	Name         string `json:"name"`
+	Get          string `json:"get"`
+	Set          string `json:"set"`
+	Inf          string `json:"inf"`
+	ImplementGet bool   `json:"implementGet"`
+	ImplementSet bool   `json:"implementSet"`
+	ImplementInf bool   `json:"implementInf"`
+}
+
+// FuzzStats is the statistics of a fuzzing strategy against an instance
+type FuzzStats struct {
+	Strategy string        `json:"strategy"`
+	Node     string        `json:"node"`
+	Instance string        `json:"instance"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+	Sent     int           `json:"sent"`
+	Replied  int           `json:"replied"`
+	Seed     int64         `json:"seed"` // seed of random values, the same seed sends the same cases
+	Timeouts int           `json:"timeouts"`
+	Findings int           `json:"findings"`
+	Hangs    int           `json:"hangs"`   // heartbeats failed
+	Reboots  int           `json:"reboots"` // reboots detected
+}
+
+// FuzzCase is a case sent during fuzzing and the reply
+type FuzzCase struct {
+	Strategy string        `json:"strategy"`
+	Node     string        `json:"node"`
+	Instance string        `json:"instance"`
+	Index    int           `json:"index"`
+	Sent     string        `json:"sent"`
+	Recv     string        `json:"recv,omitempty"`
+	SentAt   time.Time     `json:"sentAt"`
+	RTT      time.Duration `json:"rtt"`
+	Timeout  bool          `json:"timeout"`
+
+	// Suspect is true if the device hung or rebooted after this case before the next heartbeat
+	Suspect bool `json:"suspect,omitempty"`
+}
+
+// Observation is the range of values of a property observed in replies.
+// Min and Max are EDT in HEX compared as unsigned big endian number
+type Observation struct {
+	Node     string `json:"node"`
+	Instance string `json:"instance"`
+	EPC      string `json:"epc"`
+	Min      string `json:"min"`
+	Max      string `json:"max"`
+	Count    int    `json:"count"`
+	Signed   bool   `json:"signed,omitempty"` // EDT is a signed number
+}
+
+// Timing is how long an operation took
+type Timing struct {
+	Name     string        `json:"name"`
+	Node     string        `json:"node,omitempty"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Result stores checks and findings of one run.
+// Reports and exit code are built from Result
+type Result struct {
+	mu sync.Mutex
+	ResultDocument
+}
+
+// ResultDocument is the JSON document of Result.
+// The layout is versioned by SchemaVersion and described in README
+type ResultDocument struct {
+	SchemaVersion int       `json:"schemaVersion"`
+	ToolVersion   string    `json:"toolVersion"`
+	RunID         string    `json:"runId"`
+	Seed          int64     `json:"seed"` // seed of the run, seeds of fuzzing are derived from it
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+
+	// Config is the configuration the run used
+	Config util.Config `json:"config"`
+
+	Nodes     []NodeRecord  `json:"nodes"`
+	Checks    []CheckRecord `json:"checks"`
+	Findings  []Finding     `json:"findings"`
+	Fuzz      []FuzzStats   `json:"fuzz"`
+	FuzzCases []FuzzCase    `json:"fuzzCases"`
+	Timings   []Timing      `json:"timings"`
+
+	// Observations are the ranges of property values the devices replied
+	Observations []Observation `json:"observations"`
+}
+
+// NewResult create empty Result of the run whose ID is runID
+func NewResult(runID string) *Result {
+	r := &Result{}
+	r.SchemaVersion = ResultSchemaVersion
+	r.ToolVersion = ToolVersion
+	r.RunID = runID
+	r.Start = time.Now()
+	return r
+}
+
+// LoadResult read the result JSON file written by SaveTo
+func LoadResult(path string) (*Result, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to read result %s: %w", path, err)
+	}
+	r := &Result{}
+	err = json.Unmarshal(data, &r.ResultDocument)
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to parse result %s: %w", path, err)
+	}
+	if r.SchemaVersion > ResultSchemaVersion {
+		return nil, xerrors.Errorf("Unsupported schema version %d of result %s", r.SchemaVersion, path)
+	}
+	return r, nil
+}
+
+// UnmarshalJSON read JSON document into Result
+func (r *Result) UnmarshalJSON(data []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return json.Unmarshal(data, &r.ResultDocument)
+}
+
+// MarshalJSON change Result into JSON document
+func (r *Result) MarshalJSON() ([]byte, error) {
+	r.mu.Lock()
+	doc := r.document()
+	doc.Checks = append([]CheckRecord(nil), r.Checks...)
+	doc.FuzzCases = append([]FuzzCase(nil), r.FuzzCases...)
+	r.mu.Unlock()
+	return json.Marshal(doc)
+}
+
+// document return the copy of ResultDocument without checks and fuzzing cases. r.mu must be locked
+func (r *Result) document() ResultDocument {
+	doc := r.ResultDocument
+	// elements of slices are updated by checks running
+	doc.Nodes = append([]NodeRecord(nil), r.Nodes...)
+	doc.Checks = nil
+	doc.Findings = append([]Finding(nil), r.Findings...)
+	doc.Fuzz = append([]FuzzStats(nil), r.Fuzz...)
+	doc.FuzzCases = nil
+	doc.Timings = append([]Timing(nil), r.Timings...)
+	doc.Observations = append([]Observation(nil), r.Observations...)
+	return doc
+}
+
+// SaveTo output Result as JSON into dir.
+// File name is stable per run, (RunID)-result.json
+func (r *Result) SaveTo(dir string) error {
+	r.mu.Lock()
+	r.End = time.Now()
+	r.mu.Unlock()
+	return util.OutJson(r, filepath.Join(dir, r.RunID+"-result"))
+}
+
+// AddNode store the snapshot of node whose Appendix release is release
+func (r *Result) AddNode(node *Node, release string) {
+	record := NodeRecord{IP: node.ip.String(), Release: release}
+	for _, inst := range node.Instances {
+		instRecord := InstanceRecord{
+			Code:      eojString(inst.ClassCode),
+			ClassName: inst.ClassName,
+			Release:   inst.release,
+		}
+		for _, prop := range inst.Props {
+			instRecord.Props = append(instRecord.Props, PropertyRecord{
+				EPC:          fmt.Sprintf("%02X", prop.EPC),
+				Name:         prop.PropertyName,
+				Get:          prop.Get,
+				Set:          prop.Set,
+				Inf:          prop.Inf,
+				ImplementGet: prop.ImplementGet,
+				ImplementSet: prop.ImplementSet,
+				ImplementInf: prop.ImplementInf,
+			})
+		}
+		sort.Slice(instRecord.Props, func(i, j int) bool {
+			return instRecord.Props[i].EPC < instRecord.Props[j].EPC
+		})
+		record.Instances = append(record.Instances, instRecord)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Nodes = append(r.Nodes, record)
+}
+
+// AddFuzz store the statistics of fuzzing.
+// The statistics of a campaign resumed replace the ones stored before
+func (r *Result) AddFuzz(stats FuzzStats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, s := range r.Fuzz {
+		if s.Strategy == stats.Strategy && s.Node == stats.Node && s.Instance == stats.Instance && s.Start.Equal(stats.Start) {
+			r.Fuzz[i] = stats
+			return
+		}
+	}
+	r.Fuzz = append(r.Fuzz, stats)
+}
+
+// beginCheck record a check started and return its ID
+func (r *Result) beginCheck(name string, node string, instance string, sent string, recv string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record := CheckRecord{
+		ID:       len(r.Checks) + 1,
+		Name:     name,
+		Node:     node,
+		Instance: instance,
+		Sent:     sent,
+		Recv:     recv,
+		Start:    time.Now(),
+		Passed:   true,
+		running:  true,
+	}
+	r.Checks = append(r.Checks, record)
+	return record.ID
+}
+
+// endCheck record a check finished
+func (r *Result) endCheck(id int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id < 1 || id > len(r.Checks) {
+		return
+	}
+	r.Checks[id-1].Duration = time.Since(r.Checks[id-1].Start)
+	r.Checks[id-1].running = false
+}
+
+// Add store a Finding. If the finding belongs to a check, the check fails
+func (r *Result) Add(f Finding) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if f.CheckID > 0 && f.CheckID <= len(r.Checks) {
+		r.Checks[f.CheckID-1].Passed = false
+	}
+	r.Findings = append(r.Findings, f)
+}
+
+// findingIndexesFrom return indexes of findings of node stored since the from-th finding of node.
+// Findings of other nodes fuzzed in parallel are skipped
+func (r *Result) findingIndexesFrom(node string, from int) []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var retIndexes []int
+	n := 0
+	for i, f := range r.Findings {
+		if f.Node != node {
+			continue
+		}
+		if n >= from {
+			retIndexes = append(retIndexes, i)
+		}
+		n++
+	}
+	return retIndexes
+}
+
+// countNode return the number of findings of node
+func (r *Result) countNode(node string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, f := range r.Findings {
+		if f.Node == node {
+			n++
+		}
+	}
+	return n
+}
+
+// finding return the finding at index i
+func (r *Result) finding(i int) Finding {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Findings[i]
+}
+
+// setMinimized store the minimized frame of the finding at index i
+func (r *Result) setMinimized(i int, minimized string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Findings[i].Minimized = minimized
+}
+
+// FindingsOf return findings reported by the check whose ID is checkID
+func (r *Result) FindingsOf(checkID int) []Finding {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var retFindings []Finding
+	for _, f := range r.Findings {
+		if f.CheckID == checkID {
+			retFindings = append(retFindings, f)
+		}
+	}
+	return retFindings
+}
+
+// Filter return findings whose severity is min or more serious
+func (r *Result) Filter(min Severity) []Finding {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var retFindings []Finding
+	for _, f := range r.Findings {
+		if f.Severity.Rank() >= min.Rank() {
+			retFindings = append(retFindings, f)
+		}
+	}
+	return retFindings
+}
+
+// Count return the number of findings whose severity is min or more serious
+func (r *Result) Count(min Severity) int {
+	return len(r.Filter(min))
+}
+
+// ExitCode return 1 if there are findings whose severity is fail or more serious, otherwise 0
+func (r *Result) ExitCode(fail Severity) int {
+	if r.Count(fail) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// AddFuzzCase store a case sent during fuzzing
+func (r *Result) AddFuzzCase(c FuzzCase) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.FuzzCases = append(r.FuzzCases, c)
+}
+
+// countCases return the number of fuzzing cases sent to node
+func (r *Result) countCases(node string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, c := range r.FuzzCases {
+		if c.Node == node {
+			n++
+		}
+	}
+	return n
+}
+
+// MarkSuspects mark cases stored as the suspects of hang or reboot
+func (r *Result) MarkSuspects(cases []FuzzCase) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range cases {
+		for i := len(r.FuzzCases) - 1; i >= 0; i-- {
+			stored := r.FuzzCases[i]
+			if stored.Strategy == c.Strategy && stored.Node == c.Node && stored.Instance == c.Instance && stored.Index == c.Index && stored.SentAt.Equal(c.SentAt) {
+				r.FuzzCases[i].Suspect = true
+				break
+			}
+		}
+	}
+}
+
+// Observe store the value of property replied by a device. If signed, EDT is compared as a signed number
+func (r *Result) Observe(node string, instance string, epc uint8, edt []uint8, signed bool) {
+	value := hex.EncodeToString(edt)
+	epcStr := fmt.Sprintf("%02X", epc)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, o := range r.Observations {
+		if o.Node == node && o.Instance == instance && o.EPC == epcStr {
+			if compareEDT(value, o.Min, o.Signed) < 0 {
+				r.Observations[i].Min = value
+			}
+			if compareEDT(value, o.Max, o.Signed) > 0 {
+				r.Observations[i].Max = value
+			}
+			r.Observations[i].Count++
+			return
+		}
+	}
+	r.Observations = append(r.Observations, Observation{Node: node, Instance: instance, EPC: epcStr, Min: value, Max: value, Count: 1, Signed: signed})
+}
+
+// signedProperty return whether the value of prop is a signed number
+func signedProperty(prop Property) bool {
+	if len(prop.Data) != 1 {
+		return false
+	}
+	number, ok := prop.Data[0].(Number)
+	return ok && strings.HasPrefix(number.format, "int")
+}
+
+// compareEDT compare EDT in HEX as big endian number, signed if signed is true.
+// Return -1 if a < b, 0 if a == b and 1 if a > b
+func compareEDT(a string, b string, signed bool) int {
+	if signed {
+		x, okA := signedEDT(a)
+		y, okB := signedEDT(b)
+		if okA && okB {
+			if x < y {
+				return -1
+			} else if x > y {
+				return 1
+			}
+			return 0
+		}
+	}
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// signedEDT decode EDT in HEX as signed big endian number of up to 8 bytes
+func signedEDT(s string) (int64, bool) {
+	data, err := hex.DecodeString(s)
+	if err != nil || len(data) == 0 || len(data) > 8 {
+		return 0, false
+	}
+	value := int64(int8(data[0]))
+	for _, b := range data[1:] {
+		value = value<<8 | int64(b)
+	}
+	return value, true
+}
+
+// AddTiming store how long the operation named name took since start
+func (r *Result) AddTiming(name string, node string, start time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Timings = append(r.Timings, Timing{Name: name, Node: node, Start: start, Duration: time.Since(start)})
+}
@@ -0,0 +1,103 @@
+package echonetlite
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_ResultJSON(t *testing.T) {
+	node := newTestNode()
+	sent := FrameFormat{EHD1: 0x10, EHD2: 0x81, TID: 0x0001, DEOJ: [3]uint8{0x01, 0x30, 0x01}, ESV: 0x62, OPC: 0x01, VarGroups: []VarByteGroup{{EPC: 0x80}}}
+	node.Instances = []Instance{{ClassCode: sent.DEOJ, ClassName: "Home air conditioner"}}
+	node.result.AddNode(node, "M")
+	node.Check(&sent, &FrameFormat{})
+	node.result.AddFuzzCase(FuzzCase{Strategy: "OPC Fuzz", Node: "192.0.2.1", Instance: "013001", Index: 1, Sent: frameHex(&sent), Timeout: true})
+
+	data, err := json.Marshal(node.result)
+	if err != nil {
+		t.Fatalf("json.Marshal(Result) => %v", err)
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("json.Unmarshal => %v", err)
+	}
+	for _, key := range []string{"schemaVersion", "toolVersion", "runId", "config", "nodes", "checks", "findings", "fuzz", "fuzzCases", "timings"} {
+		if _, ok := raw[key]; !ok {
+			t.Errorf("result JSON doesn't have key %q", key)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "echonet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "result.json")
+	if err := ioutil.WriteFile(path, data, 0666); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadResult(path)
+	if err != nil {
+		t.Fatalf("LoadResult => %v", err)
+	}
+	if loaded.SchemaVersion != ResultSchemaVersion || loaded.RunID != "test" {
+		t.Errorf("LoadResult => version %d, run %q", loaded.SchemaVersion, loaded.RunID)
+	}
+	if len(loaded.Findings) != 1 || loaded.Findings[0].RuleID != "RECV-NONE" {
+		t.Errorf("LoadResult findings => %+v", loaded.Findings)
+	}
+	if len(loaded.Nodes) != 1 || loaded.Nodes[0].Release != "M" || len(loaded.FuzzCases) != 1 {
+		t.Errorf("LoadResult nodes => %+v, fuzz cases => %+v", loaded.Nodes, loaded.FuzzCases)
+	}
+}
+
+func Test_Observe(t *testing.T) {
+	r := NewResult("test")
+	for _, edt := range [][]uint8{{0x00, 0x10}, {0xFF, 0xF6}, {0x00, 0x05}} {
+		r.Observe("192.0.2.1", "001101", 0xE0, edt, true)
+		r.Observe("192.0.2.1", "013001", 0xB3, edt, false)
+	}
+	// -10 to 16 as signed, 5 to 65526 as unsigned
+	if o := r.Observations[0]; o.Min != "fff6" || o.Max != "0010" || !o.Signed {
+		t.Errorf("signed observation => %+v", o)
+	}
+	if o := r.Observations[1]; o.Min != "0005" || o.Max != "fff6" || o.Signed {
+		t.Errorf("unsigned observation => %+v", o)
+	}
+}
+
+func Test_ResultJSON_concurrent(t *testing.T) {
+	r := NewResult("test")
+	r.Observe("192.0.2.1", "013001", 0xB3, []uint8{0x10}, false)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			r.Observe("192.0.2.1", "013001", 0xB3, []uint8{uint8(i)}, false)
+			r.Add(Finding{RuleID: "RECV-NONE"})
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if _, err := json.Marshal(r); err != nil {
+			t.Fatalf("json.Marshal(Result) => %v", err)
+		}
+	}
+	<-done
+}
+
+func Test_findingIndexesFrom(t *testing.T) {
+	r := NewResult("test")
+	for _, node := range []string{"192.0.2.1", "192.0.2.2", "192.0.2.1", "192.0.2.2", "192.0.2.1"} {
+		r.Add(Finding{RuleID: "TEST", Node: node})
+	}
+	if n := r.countNode("192.0.2.1"); n != 3 {
+		t.Errorf("countNode => %d, want 3", n)
+	}
+	got := r.findingIndexesFrom("192.0.2.1", 1)
+	if len(got) != 2 || got[0] != 2 || got[1] != 4 {
+		t.Errorf("findingIndexesFrom => %v, want [2 4]", got)
+	}
+}
@@ -0,0 +1,136 @@
+package echonetlite
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// dataHint return a short description of values data accepts like "30=ON 31=OFF" or "uint8 0..100 %"
+func dataHint(data interface{}) string {
+	switch value := data.(type) {
+	case []interface{}:
+		var hints []string
+		for _, d := range value {
+			hints = append(hints, dataHint(d))
+		}
+		return strings.Join(hints, " | ")
+	case Number:
+		if len(value.enum) > 0 {
+			var enums []string
+			for _, e := range value.enum {
+				enums = append(enums, strconv.FormatInt(e, 10))
+			}
+			return fmt.Sprintf("%s one of %s", value.format, strings.Join(enums, ","))
+		}
+		hint := fmt.Sprintf("%s %d..%d", value.format, value.minimum, value.maximum)
+		if value.multipleOf != 0 && value.multipleOf != 1 {
+			hint += fmt.Sprintf(" x%g", value.multipleOf)
+		}
+		if value.unit != "" {
+			hint += " " + value.unit
+		}
+		return hint
+	case State:
+		size := int(value.size)
+		if size == 0 {
+			size = 1
+		}
+		var states []string
+		for _, e := range value.enum {
+			states = append(states, fmt.Sprintf("%0*X=%s", size*2, e.edt, e.state))
+		}
+		return strings.Join(states, " ")
+	case Level:
+		base, _ := strconv.ParseUint(value.base, 0, 64)
+		return fmt.Sprintf("level %X..%X", base, base+value.maximum)
+	case Raw:
+		return fmt.Sprintf("raw %d..%d bytes", value.minSize, value.maxSize)
+	case Bitmap:
+		return fmt.Sprintf("bitmap %d bytes", value.size)
+	case NumericValues:
+		size := int(value.size)
+		if size == 0 {
+			size = 1
+		}
+		var values []string
+		for _, e := range value.enum {
+			values = append(values, fmt.Sprintf("%0*X=%g", size*2, e.edt, e.value))
+		}
+		return strings.Join(values, " ")
+	case DateTime:
+		return fmt.Sprintf("date time %d bytes", value.size)
+	case Object:
+		var names []string
+		for _, e := range value.element {
+			names = append(names, e.name)
+		}
+		return fmt.Sprintf("object {%s}", strings.Join(names, ", "))
+	case Array:
+		return fmt.Sprintf("array of %d..%d items of %d bytes", value.minItems, value.maxItems, value.itemSize)
+	}
+	return ""
+}
+
+// propertyHint return a short description of values prop accepts
+func propertyHint(prop Property) string {
+	var hints []string
+	for _, data := range prop.Data {
+		if hint := dataHint(data); hint != "" {
+			hints = append(hints, hint)
+		}
+	}
+	return strings.Join(hints, " | ")
+}
+
+// decodeValue change edt into a readable value like "ON" or "25 Celsius" by data.
+// If edt isn't decoded, it is returned in HEX
+func decodeValue(data interface{}, edt []uint8) string {
+	switch value := data.(type) {
+	case []interface{}:
+		for _, d := range value {
+			if ok, _ := elementCorrectRange(d, edt); ok {
+				return decodeValue(d, edt)
+			}
+		}
+	case Number:
+		n := int64(uintOf(edt))
+		if !strings.HasPrefix(value.format, "uint") && len(edt) > 0 && len(edt) < 8 && edt[0]&0x80 != 0 {
+			n -= 1 << (uint(len(edt)) * 8)
+		}
+		text := strconv.FormatInt(n, 10)
+		if value.multipleOf != 0 && value.multipleOf != 1 {
+			text = strconv.FormatFloat(float64(n)*value.multipleOf, 'f', -1, 64)
+		}
+		if value.unit != "" {
+			text += " " + value.unit
+		}
+		return text
+	case State:
+		for _, e := range value.enum {
+			if int64(uintOf(edt)) == e.edt {
+				return e.state
+			}
+		}
+	case Level:
+		base, _ := strconv.ParseUint(value.base, 0, 64)
+		return fmt.Sprintf("level %d", uint64(uintOf(edt))-base+1)
+	case NumericValues:
+		for _, e := range value.enum {
+			if int64(uintOf(edt)) == e.edt {
+				return strconv.FormatFloat(e.value, 'f', -1, 64)
+			}
+		}
+	}
+	return fmt.Sprintf("%X", edt)
+}
+
+// decodeProperty change edt into a readable value by the first data type of prop edt is in the range of
+func decodeProperty(prop Property, edt []uint8) string {
+	for _, data := range prop.Data {
+		if ok, _ := elementCorrectRange(data, edt); ok {
+			return decodeValue(data, edt)
+		}
+	}
+	return fmt.Sprintf("%X", edt)
+}
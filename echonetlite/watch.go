@@ -0,0 +1,177 @@
+package echonetlite
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+// Sources of values observed during watch
+const (
+	watchGet = "Get"
+	watchInf = "INF"
+)
+
+// WatchSample is a value of a property observed during watch
+type WatchSample struct {
+	Time   time.Time
+	EPC    uint8
+	Source string // Get or INF
+	EDT    []uint8
+	Value  string // decoded EDT
+}
+
+// watchRow is the latest value of a watched property
+type watchRow struct {
+	prop    Property
+	edt     []uint8
+	value   string
+	source  string
+	updated time.Time
+	changes int
+	changed bool // changed at the latest poll or notification
+}
+
+// watch is the state of properties of an instance being watched
+type watch struct {
+	rows    []*watchRow
+	samples []WatchSample
+}
+
+func newWatch(inst Instance, epcs []uint8) *watch {
+	w := &watch{}
+	for _, epc := range epcs {
+		prop, ok := findProp(&inst, epc)
+		if !ok {
+			prop = Property{EPC: epc}
+		}
+		w.rows = append(w.rows, &watchRow{prop: prop})
+	}
+	return w
+}
+
+// observe record the value of group notified or replied from source at t.
+// Return whether the value of a watched property changed
+func (w *watch) observe(t time.Time, source string, group VarByteGroup) bool {
+	for _, row := range w.rows {
+		if row.prop.EPC != group.EPC || group.PDC == 0 {
+			continue
+		}
+		value := decodeProperty(row.prop, group.EDT)
+		w.samples = append(w.samples, WatchSample{Time: t, EPC: group.EPC, Source: source, EDT: group.EDT, Value: value})
+		row.changed = row.edt != nil && !bytes.Equal(row.edt, group.EDT)
+		if row.changed {
+			row.changes++
+		}
+		row.edt, row.value, row.source, row.updated = group.EDT, value, source, t
+		return row.changed
+	}
+	return false
+}
+
+// writeWatchTable write the latest values as a text table into out.
+// If ansi is true, the screen is cleared and changed values are highlighted
+func writeWatchTable(out io.Writer, node string, inst Instance, w *watch, ansi bool) {
+	if ansi {
+		fmt.Fprint(out, "\x1b[H\x1b[2J")
+	}
+	fmt.Fprintf(out, "   %s %s %s (press Enter to stop)\n", node, eojString(inst.ClassCode), inst.ClassName)
+	fmt.Fprintf(out, "   %-3s %-36s %-24s %-16s %-6s %-8s %s\n", "EPC", "NAME", "VALUE", "EDT", "SOURCE", "UPDATED", "CHANGES")
+	for _, row := range w.rows {
+		updated := "-"
+		if !row.updated.IsZero() {
+			updated = row.updated.Format("15:04:05")
+		}
+		line := fmt.Sprintf("   %02X  %-36s %-24s %-16X %-6s %-8s %d", row.prop.EPC, row.prop.PropertyName, row.value, row.edt, row.source, updated, row.changes)
+		if row.changed && ansi {
+			line = "\x1b[1;33m" + line + "\x1b[0m"
+		} else if row.changed {
+			line += " *"
+		}
+		fmt.Fprintln(out, line)
+	}
+}
+
+// WriteWatchCSV write samples as CSV into w
+func WriteWatchCSV(w io.Writer, samples []WatchSample) error {
+	c := csv.NewWriter(w)
+	err := c.Write([]string{"time", "epc", "source", "edt", "value"})
+	if err != nil {
+		return xerrors.Errorf("Failed to write CSV: %w", err)
+	}
+	for _, s := range samples {
+		err = c.Write([]string{s.Time.Format(time.RFC3339Nano), fmt.Sprintf("%02X", s.EPC), s.Source, fmt.Sprintf("%X", s.EDT), s.Value})
+		if err != nil {
+			return xerrors.Errorf("Failed to write CSV: %w", err)
+		}
+	}
+	c.Flush()
+	return c.Error()
+}
+
+// Watch get properties epcs of the instance designated by dstIP and dstCode every interval
+// and receive notifications of them meanwhile, until stop is closed or the operation is interrupted.
+// The table of the latest values is written into out every poll and notification, and all values observed are returned
+func (a *Auditor) Watch(dstIP net.IP, dstCode [3]uint8, epcs []uint8, interval time.Duration, out io.Writer, ansi bool, stop <-chan struct{}) ([]WatchSample, error) {
+	node, instIndex, err := a.searchInstane(dstIP, dstCode)
+	if err != nil {
+		return nil, err
+	}
+	inst := node.Instances[instIndex]
+	w := newWatch(inst, epcs)
+	node.logger.Info("Start watch", zap.String("instance", eojString(dstCode)), zap.Duration("interval", interval))
+	defer node.logger.Info("Finished watch", zap.String("instance", eojString(dstCode)))
+
+	ctx, cancel := context.WithCancel(node.context())
+	defer cancel()
+	notifications := node.client.Subscribe(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, row := range w.rows {
+			row.changed = false
+		}
+		recv, err := node.GetProp(dstCode, node.client.SEOJ, epcs...)
+		if ctx.Err() != nil {
+			return w.samples, nil
+		} else if err != nil && !xerrors.Is(err, ErrTimeout) && !isTruncated(err) {
+			return w.samples, xerrors.Errorf("Failed to get properties at watch: %w", err)
+		} else if err != nil {
+			node.logger.Error("No reply at watch", zap.String("message", err.Error()))
+		}
+		if recv.ESV == 0x72 || recv.ESV == 0x52 {
+			for _, group := range recv.VarGroups {
+				w.observe(time.Now(), watchGet, group)
+			}
+		}
+		writeWatchTable(out, node.ip.String(), inst, w, ansi)
+
+		// notifications until the next poll
+	wait:
+		for {
+			select {
+			case <-stop:
+				return w.samples, nil
+			case <-ctx.Done():
+				return w.samples, nil
+			case <-ticker.C:
+				break wait
+			case n := <-notifications:
+				if n.SEOJ != dstCode {
+					continue
+				}
+				for _, group := range n.Properties {
+					w.observe(n.Time, watchInf, group)
+				}
+				writeWatchTable(out, node.ip.String(), inst, w, ansi)
+			}
+		}
+	}
+}
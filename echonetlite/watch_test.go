@@ -0,0 +1,65 @@
+package echonetlite
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_watchObserve(t *testing.T) {
+	a := newTestAuditor()
+	inst := a.DistNodes[0].Instances[1]
+	w := newWatch(inst, []uint8{0x80, 0xB3})
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if w.observe(now, watchGet, VarByteGroup{EPC: 0x80, PDC: 1, EDT: []uint8{0x30}}) {
+		t.Errorf("first value => changed")
+	}
+	if w.observe(now, watchGet, VarByteGroup{EPC: 0x80, PDC: 1, EDT: []uint8{0x30}}) {
+		t.Errorf("same value => changed")
+	}
+	if !w.observe(now.Add(time.Second), watchInf, VarByteGroup{EPC: 0x80, PDC: 1, EDT: []uint8{0x31}}) {
+		t.Errorf("new value => not changed")
+	}
+	if w.observe(now, watchGet, VarByteGroup{EPC: 0xBB, PDC: 1, EDT: []uint8{0x19}}) {
+		t.Errorf("property not watched => changed")
+	}
+	w.observe(now, watchGet, VarByteGroup{EPC: 0xB3, PDC: 1, EDT: []uint8{0x19}})
+	if len(w.samples) != 4 || w.rows[0].value != "OFF" || w.rows[0].changes != 1 || w.rows[1].value != "25 Celsius" {
+		t.Fatalf("watch => rows %+v %+v, %d samples", *w.rows[0], *w.rows[1], len(w.samples))
+	}
+
+	var out bytes.Buffer
+	writeWatchTable(&out, "192.0.2.1", inst, w, false)
+	if !strings.Contains(out.String(), "OFF") || !strings.Contains(out.String(), " *\n") {
+		t.Errorf("writeWatchTable => %s", out.String())
+	}
+
+	out.Reset()
+	if err := WriteWatchCSV(&out, w.samples); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 5 || lines[3] != "2020-01-01T00:00:01Z,80,INF,31,OFF" {
+		t.Errorf("WriteWatchCSV => %q", lines)
+	}
+}
+
+func Test_decodeValue(t *testing.T) {
+	tests := []struct {
+		data interface{}
+		edt  []uint8
+		want string
+	}{
+		{Number{format: "int8", minimum: -127, maximum: 125, unit: "Celsius"}, []uint8{0xFE}, "-2 Celsius"},
+		{Number{format: "uint16", minimum: 0, maximum: 65533, multipleOf: 0.1}, []uint8{0x00, 0x0F}, "1.5"},
+		{State{size: 1, enum: []enumber{{edt: 0x41, state: "Auto"}}}, []uint8{0x41}, "Auto"},
+		{State{size: 1, enum: []enumber{{edt: 0x41, state: "Auto"}}}, []uint8{0x42}, "42"},
+	}
+	for _, tt := range tests {
+		if got := decodeValue(tt.data, tt.edt); got != tt.want {
+			t.Errorf("decodeValue(%+v, %X) => %q, want %q", tt.data, tt.edt, got, tt.want)
+		}
+	}
+}
@@ -1,42 +1,109 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"net"
-	"time"
+	"os"
+	"sort"
+	"strings"
 
 	"github.com/tttfrfr2/ECHONETTester/echonetlite"
-	"github.com/tttfrfr2/ECHONETTester/util"
 )
 
-var auditor echonetlite.Auditor
-var echonetConf util.EchonetLiteConf
+// commands are subcommands. Without subcommand, the interactive prompt is launched
+var commands = map[string]func(args []string) int{
+	"prompt":      runPrompt,
+	"discover":    runDiscover,
+	"get":         runGet,
+	"set":         runSet,
+	"plan":        runTestPlan,
+	"sweep":       runSweep,
+	"conformance": runConformance,
+	"fuzz":        runFuzz,
+	"report":      runReport,
+	"diff":        runDiff,
+	"replay":      runReplay,
+	"resume":      runResume,
+}
 
 func main() {
-	t := time.Now()
-	echonetlite.TimeStr = fmt.Sprint(t.Year()) + "-" + fmt.Sprint(int(t.Month())) + "-" + fmt.Sprint(t.Day()) + "-" + fmt.Sprint(t.Minute()) + "-" + fmt.Sprint(t.Second())
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			os.Exit(command(os.Args[2:]))
+		}
+		if !strings.HasPrefix(os.Args[1], "-") {
+			fmt.Fprintf(os.Stderr, "Unknown subcommand %s\n", os.Args[1])
+			usage()
+			os.Exit(exitError)
+		}
+	}
+	os.Exit(runPrompt(os.Args[1:]))
+}
 
-	var config *util.Config
-	config = util.ReadConfig("config.tml")
-	if config == nil {
-		return
+// usage print subcommands
+func usage() {
+	var names []string
+	for name := range commands {
+		names = append(names, name)
 	}
-	echonetConf = util.DistributeConf(*config)
-	fmt.Println("---Tool Start---")
+	sort.Strings(names)
+	fmt.Fprintf(os.Stderr, "Usage: %s [SUBCOMMAND] [flags]\nSubcommands: %s\nWithout subcommand, the interactive prompt is launched\n", os.Args[0], strings.Join(names, ", "))
+}
 
-	var echonetTargets []net.IP
-	for _, ip := range echonetConf.IP {
-		echonetTargets = append(echonetTargets, net.ParseIP(ip))
+// runPrompt discover nodes and launch the interactive prompt
+func runPrompt(args []string) int {
+	fs := flag.NewFlagSet("prompt", flag.ContinueOnError)
+	opts := addOptions(fs, withTarget|withOut|withSeed)
+	script := fs.String("script", "", "Play back commands and answers of this file, one per line, instead of the interactive prompt")
+	record := fs.String("record", "", "Record commands and answers of the session into this file, which is played back with -script")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s [prompt] [-script FILE] [-record FILE] [flags]\n", os.Args[0])
+		fs.PrintDefaults()
 	}
-	err := auditor.NewAuditor(echonetTargets)
-	if err != nil {
-		fmt.Printf("ECHONET Lite testing ERROR: %+v\n", err)
+	if err := fs.Parse(args); err != nil {
+		return exitError
 	}
-
-	if len(auditor.DistNodes) < 1 {
-		fmt.Printf("There are no ECHONET Lite node\nEXIT\n")
-		return
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "Unexpected arguments %s\n", strings.Join(fs.Args(), " "))
+		fs.Usage()
+		return exitError
 	}
-	auditor.RunEchonetPrompt()
-
+	opts.prompter = echonetlite.NewPrompter(os.Stdin, os.Stdout)
+	if *script != "" {
+		f, err := os.Open(*script)
+		if err != nil {
+			fmt.Printf("ECHONET Lite ERROR: %s\n", err)
+			return 2
+		}
+		defer f.Close()
+		opts.prompter = echonetlite.NewPrompter(f, os.Stdout)
+	}
+	if *record != "" {
+		f, err := os.Create(*record)
+		if err != nil {
+			fmt.Printf("ECHONET Lite ERROR: %s\n", err)
+			return 2
+		}
+		defer f.Close()
+		opts.prompter = echonetlite.Record(opts.prompter, f)
+	}
+	a, code := opts.start()
+	if a == nil {
+		return code
+	}
+	// the prompt handles Ctrl-C by itself
+	opts.stop()
+	defer a.Close()
+	if *script != "" {
+		stop := a.HandleInterrupt()
+		defer stop()
+		a.RunScript()
+	} else {
+		a.RunEchonetPrompt()
+	}
+	// quit by Ctrl-C twice rather than exit
+	if a.Quitting() && a.Interrupted() {
+		return echonetlite.ExitInterrupted
+	}
+	return exitPass
 }
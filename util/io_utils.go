@@ -21,7 +21,7 @@ func WriteByteFile(path string, data []byte, isAppend bool) (err error) {
 			return xerrors.Errorf("Failed to open file: %v, path: %s", err, path)
 		}
 	} else {
-		file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0666)
+		file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
 		if err != nil {
 			return xerrors.Errorf("Failed to open file: %v, path: %s", err, path)
 		}
@@ -3,32 +3,68 @@ package util
 import (
 	"encoding/json"
 	"os"
-	"strconv"
-	"time"
+	"path/filepath"
 
 	"golang.org/x/xerrors"
 )
 
-func OutJson(v interface{}, testType string) error {
+// OutJson output v as JSON into (path).json like OutJsonDir, path is like "result/(name)".
+// The file is overwritten if it exists
+func OutJson(v interface{}, path string) error {
+	return OutJsonDir(v, filepath.Dir(path), filepath.Base(path))
+}
+
+// OutJsonDir output v as JSON into (dir)/(name).json.
+// The file is overwritten if it exists
+func OutJsonDir(v interface{}, dir string, name string) error {
 	// confirm if directory exists
-	if _, err := os.Stat("result"); os.IsNotExist(err) {
-		os.Mkdir("result", 0777)
+	err := Check_dir(dir + "/")
+	if err != nil {
+		return xerrors.Errorf("Failed to create directory %s: %w", dir, err)
 	}
 
-	outputJson, err := json.Marshal(&v)
+	outputJson, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
 		return xerrors.Errorf("Failed to change result into JSON: %w", err)
 	}
 
-	t := time.Now()
-	filename := "result/" + strconv.Itoa(t.Year()) + strconv.Itoa(int(t.Month())) + strconv.Itoa(t.Day()) + strconv.Itoa(t.Hour()) + strconv.Itoa(t.Minute()) + strconv.Itoa(t.Second()) + "_" + testType + ".json"
-	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE, 0666)
+	filename := dir + "/" + name + ".json"
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return xerrors.Errorf("Failed to create file pointer: %w", err)
+	}
 	defer f.Close()
 
+	_, err = f.Write(outputJson)
+	if err != nil {
+		return xerrors.Errorf("Failed to write JSON: %w", err)
+	}
+	return nil
+}
+
+// AppendJsonLines output values as JSON lines into path.
+// If truncate, the file is overwritten, otherwise values are appended
+func AppendJsonLines(path string, truncate bool, values ...interface{}) error {
+	err := Check_dir(filepath.Dir(path) + "/")
+	if err != nil {
+		return xerrors.Errorf("Failed to create directory %s: %w", filepath.Dir(path), err)
+	}
+	flag := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	if truncate {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flag, 0666)
 	if err != nil {
 		return xerrors.Errorf("Failed to create file pointer: %w", err)
 	}
+	defer f.Close()
 
-	f.Write(outputJson)
+	encoder := json.NewEncoder(f)
+	for _, v := range values {
+		err = encoder.Encode(v)
+		if err != nil {
+			return xerrors.Errorf("Failed to write JSON: %w", err)
+		}
+	}
 	return nil
 }
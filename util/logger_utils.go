@@ -9,8 +9,14 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
+// InitLogger create a logger writing into console and log/(filePath)
 func InitLogger(filePath string) *zap.Logger {
-	dirLogFile, fileLogFile := filepath.Split("log/" + filePath)
+	return InitLoggerIn("log", filePath)
+}
+
+// InitLoggerIn create a logger writing into console and (dir)/(filePath)
+func InitLoggerIn(dir string, filePath string) *zap.Logger {
+	dirLogFile, fileLogFile := filepath.Split(dir + "/" + filePath)
 	logFilePath := dirLogFile + fileLogFile
 	consoleConfig := zapcore.EncoderConfig{
 		TimeKey:        "Time",
@@ -1,28 +1,171 @@
 package util
 
 import (
+	"encoding/hex"
 	"fmt"
+	"net"
+	"os"
+	"strings"
+
 	"github.com/BurntSushi/toml"
+	"golang.org/x/xerrors"
 )
 
+// Config is the configuration of the tool read from a TOML file
 type Config struct {
-	Title       string
-	EchonetLite EchonetLiteConf
+	Title       string          `toml:"title" json:"title"`
+	EchonetLite EchonetLiteConf `toml:"echonetLite" json:"echonetLite"`
 }
+
+// EchonetLiteConf is the configuration of ECHONET Lite testing.
+// Settings of a node section override the global ones for the node
 type EchonetLiteConf struct {
-	IP []string
+	IP          []string   `toml:"ip" json:"ip"`                             // Target nodes without node section
+	Release     string     `toml:"release" json:"release,omitempty"`         // Appendix release of nodes like "M". If empty, it is detected
+	Timeout     int        `toml:"timeout" json:"timeout,omitempty"`         // Seconds waiting a reply. If 0, 3
+	Plan        []string   `toml:"plan" json:"plan,omitempty"`               // Steps of the test plan. If empty, all steps
+	Definitions string     `toml:"definitions" json:"definitions,omitempty"` // Path of class definitions. If empty, echonetlite/class.json
+	LogDir      string     `toml:"logDir" json:"logDir,omitempty"`           // Directory logs are output. If empty, log
+	ResultDir   string     `toml:"resultDir" json:"resultDir,omitempty"`     // Directory reports are output. If empty, result
+	Nodes       []NodeConf `toml:"node" json:"node,omitempty"`
 }
 
-func ReadConfig(filePath string) *Config {
+// NodeConf is the configuration of a target node
+type NodeConf struct {
+	IP           string   `toml:"ip" json:"ip"`
+	Release      string   `toml:"release" json:"release,omitempty"`
+	Manufacturer string   `toml:"manufacturer" json:"manufacturer,omitempty"` // Expected manufacturer code (0x8A) in HEX like "000077"
+	Skip         []string `toml:"skip" json:"skip,omitempty"`                 // Instances not tested like "013002"
+	Timeout      int      `toml:"timeout" json:"timeout,omitempty"`
+}
+
+// LoadConfig read and validate the TOML file of filePath.
+// Unknown keys are errors to catch misspelled settings
+func LoadConfig(filePath string) (*Config, error) {
 	var config Config
-	_, err := toml.DecodeFile(filePath, &config)
+	md, err := toml.DecodeFile(filePath, &config)
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to read config %s: %w", filePath, err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		var keys []string
+		for _, key := range undecoded {
+			keys = append(keys, key.String())
+		}
+		return nil, xerrors.Errorf("Unknown keys in config %s: %s", filePath, strings.Join(keys, ", "))
+	}
+	err = config.Validate()
+	if err != nil {
+		return nil, xerrors.Errorf("Invalid config %s: %w", filePath, err)
+	}
+	return &config, nil
+}
+
+// ReadConfig read the TOML file of filePath. If it is invalid, the error is printed and nil is returned
+func ReadConfig(filePath string) *Config {
+	config, err := LoadConfig(filePath)
 	if err != nil {
 		fmt.Printf("Read TOML Error: %s\n", err)
 		return nil
 	}
-	return &config
+	return config
 }
 
 func DistributeConf(config Config) EchonetLiteConf {
 	return config.EchonetLite
 }
+
+// Validate check values of config and return all problems found
+func (c *Config) Validate() error {
+	var problems []string
+	conf := &c.EchonetLite
+	seen := make(map[string]bool)
+	checkIP := func(where string, s string) {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			problems = append(problems, fmt.Sprintf("%s: invalid IP address %q", where, s))
+			return
+		}
+		if seen[ip.String()] {
+			problems = append(problems, fmt.Sprintf("%s: IP address %s is duplicated", where, s))
+		}
+		seen[ip.String()] = true
+	}
+	checkRelease := func(where string, s string) {
+		if s != "" && (len(s) != 1 || s[0] < 'A' || s[0] > 'Z') {
+			problems = append(problems, fmt.Sprintf("%s: invalid release %q, it should be a capital letter like \"M\"", where, s))
+		}
+	}
+	checkTimeout := func(where string, t int) {
+		if t < 0 {
+			problems = append(problems, fmt.Sprintf("%s: timeout %d should not be negative", where, t))
+		}
+	}
+
+	for _, s := range conf.IP {
+		checkIP("ip", s)
+	}
+	checkRelease("release", conf.Release)
+	checkTimeout("timeout", conf.Timeout)
+	if conf.Definitions != "" {
+		if _, err := os.Stat(conf.Definitions); err != nil {
+			problems = append(problems, fmt.Sprintf("definitions: %s", err))
+		}
+	}
+	for i, node := range conf.Nodes {
+		where := fmt.Sprintf("node[%d]", i)
+		if node.IP == "" {
+			problems = append(problems, fmt.Sprintf("%s: ip is required", where))
+		} else {
+			where = fmt.Sprintf("node[%d] (%s)", i, node.IP)
+			checkIP(where, node.IP)
+		}
+		checkRelease(where, node.Release)
+		checkTimeout(where, node.Timeout)
+		if node.Manufacturer != "" && !isHexCode(node.Manufacturer) {
+			problems = append(problems, fmt.Sprintf("%s: invalid manufacturer code %q, it should be 6 HEX digits like \"000077\"", where, node.Manufacturer))
+		}
+		for _, eoj := range node.Skip {
+			if !isHexCode(eoj) {
+				problems = append(problems, fmt.Sprintf("%s: invalid instance %q in skip, it should be 6 HEX digits like \"013001\"", where, eoj))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return xerrors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Targets return IP addresses of all target nodes, ip and nodes in sections
+func (c *EchonetLiteConf) Targets() []string {
+	retIPs := append([]string{}, c.IP...)
+	for _, node := range c.Nodes {
+		retIPs = append(retIPs, node.IP)
+	}
+	return retIPs
+}
+
+// Node return the settings of the node designated by ip merged with global ones
+func (c *EchonetLiteConf) Node(ip net.IP) NodeConf {
+	retNode := NodeConf{IP: ip.String()}
+	for _, node := range c.Nodes {
+		if ip.Equal(net.ParseIP(node.IP)) {
+			retNode = node
+			break
+		}
+	}
+	if retNode.Release == "" {
+		retNode.Release = c.Release
+	}
+	if retNode.Timeout == 0 {
+		retNode.Timeout = c.Timeout
+	}
+	return retNode
+}
+
+// isHexCode return whether s is 3 bytes in HEX like "013001"
+func isHexCode(s string) bool {
+	code, err := hex.DecodeString(s)
+	return err == nil && len(code) == 3
+}